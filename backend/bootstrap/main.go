@@ -1,14 +1,21 @@
 package bootstrap
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"fmt"
 	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/segment"
+	"github.com/diggerhq/digger/libs/tracing"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/diggerhq/digger/backend/controllers"
@@ -42,6 +49,10 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 		log.Printf("Sentry initialization failed: %v\n", err)
 	}
 
+	if err := tracing.Init(context.Background(), "digger-backend"); err != nil {
+		log.Printf("Tracing initialization failed: %v\n", err)
+	}
+
 	//database migrations
 	models.ConnectDatabase()
 
@@ -53,6 +64,8 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 
 	r.Use(sentrygin.New(sentrygin.Options{Repanic: true}))
 
+	r.Use(middleware.Tracing())
+
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"build_date":  cfg.GetString("build_date"),
@@ -62,6 +75,9 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 		})
 	})
 
+	metrics.RegisterQueueCollector()
+	go metrics.Serve(cfg.GetString("metrics_address"))
+
 	r.SetFuncMap(template.FuncMap{
 		"formatAsDate": func(msec int64) time.Time {
 			return time.UnixMilli(msec)
@@ -93,6 +109,11 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	githubGroup.GET("/setup", controllers.GithubAppSetup)
 	githubGroup.GET("/exchange-code", diggerController.GithubSetupExchangeCode)
 
+	jobsGroup := r.Group("/jobs")
+	jobsGroup.Use(middleware.GetWebMiddleware())
+	jobsGroup.GET("/:jobId/logs", controllers.JobLogsPage)
+	jobsGroup.GET("/:jobId/logs/stream", controllers.StreamJobLogs)
+
 	authorized := r.Group("/")
 	authorized.Use(middleware.GetApiMiddleware(), middleware.AccessLevel(models.CliJobAccessType, models.AccessPolicyType, models.AdminPolicyType))
 
@@ -111,10 +132,21 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	authorized.GET("/repos/:repo/projects/:projectName/drift-policy", controllers.FindDriftPolicy)
 	authorized.GET("/orgs/:organisation/drift-policy", controllers.FindDriftPolicyForOrg)
 
+	authorized.GET("/repos/:repo/projects/:projectName/policies/:policyType/versions", controllers.ListPolicyVersionsForRepoAndProject)
+	authorized.GET("/repos/:repo/projects/:projectName/policies/:policyType/versions/:version/diff", controllers.DiffPolicyVersionsForRepoAndProject)
+	authorized.GET("/orgs/:organisation/policies/:policyType/versions", controllers.ListPolicyVersionsForOrg)
+	authorized.GET("/orgs/:organisation/policies/:policyType/versions/:version/diff", controllers.DiffPolicyVersionsForOrg)
+
+	authorized.POST("/repos/:repo/projects/:projectName/policies/:policyType/decisions", controllers.RecordPolicyDecision)
+	authorized.GET("/repos/:repo/projects/:projectName/policies/:policyType/decisions", controllers.ListPolicyDecisionsForRepoAndProject)
+	authorized.GET("/orgs/:organisation/policies/:policyType/decisions", controllers.ListPolicyDecisionsForOrg)
+
 	authorized.GET("/repos/:repo/projects/:projectName/runs", controllers.RunHistoryForProject)
 	authorized.POST("/repos/:repo/projects/:projectName/runs", controllers.CreateRunForProject)
 
 	authorized.POST("/repos/:repo/projects/:projectName/jobs/:jobId/set-status", diggerController.SetJobStatusForProject)
+	authorized.POST("/repos/:repo/projects/:projectName/jobs/:jobId/heartbeat", controllers.HeartbeatJob)
+	authorized.POST("/repos/:repo/projects/:projectName/jobs/:jobId/logs", controllers.ReportJobLogs)
 
 	authorized.GET("/repos/:repo/projects", controllers.FindProjectsForRepo)
 	authorized.POST("/repos/:repo/report-projects", controllers.ReportProjectsForRepo)
@@ -130,6 +162,12 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	admin.PUT("/repos/:repo/projects/:projectName/drift-policy", controllers.UpsertDriftPolicyForRepoAndProject)
 	admin.PUT("/orgs/:organisation/drift-policy", controllers.UpsertDriftPolicyForOrg)
 
+	admin.POST("/repos/:repo/projects/:projectName/policies/:policyType/versions/:version/rollback", controllers.RollbackPolicyForRepoAndProject)
+	admin.POST("/orgs/:organisation/policies/:policyType/versions/:version/rollback", controllers.RollbackPolicyForOrg)
+
+	admin.GET("/orgs/:organisation/audit-events", controllers.ListAuditEventsForOrg)
+	admin.GET("/orgs/:organisation/audit-events/export", controllers.ExportAuditEventsForOrg)
+
 	admin.POST("/tokens/issue-access-token", controllers.IssueAccessTokenForOrg)
 
 	r.Use(middleware.CORSMiddleware())
@@ -153,6 +191,35 @@ func Bootstrap(templates embed.FS, diggerController controllers.DiggerController
 	return r
 }
 
+// how long requests in flight get to complete when the backend stops
+const shutdownTimeout = 10 * time.Second
+
+// Run serves r on port until the backend is interrupted or terminated, then lets the requests in flight complete
+// and flushes the spans not exported yet
+func Run(r *gin.Engine, port int) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: r}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("could not serve on port %v: %v", port, err)
+		}
+	}()
+	<-ctx.Done()
+	log.Println("Stopping the backend")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("could not stop the server gracefully: %v", err)
+	}
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		log.Printf("could not flush traces: %v", err)
+	}
+}
+
 func initLogging() {
 	log.SetOutput(os.Stdout)
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
@@ -7,6 +7,12 @@ import (
 
 type CiBackend interface {
 	TriggerWorkflow(spec spec.Spec, runName string, vcsToken string) error
+	// CancelWorkflow cancels the CI run of the job of spec if it is still running. workflowRunUrl is the url
+	// of the run reported when the job started, "#" if it didn't start yet.
+	CancelWorkflow(spec spec.Spec, workflowRunUrl string) error
+	// IsWorkflowRunning returns whether the CI run of the job of spec is queued or running, a run that can't
+	// be found is not running
+	IsWorkflowRunning(spec spec.Spec, workflowRunUrl string) (bool, error)
 }
 
 type JenkinsCi struct{}
@@ -3,10 +3,15 @@ package ci_backends
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/diggerhq/digger/backend/utils"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/spec"
 	"github.com/google/go-github/v61/github"
 	"log"
+	"net/http"
+	"path"
+	"strconv"
 )
 
 type GithubActionCi struct {
@@ -30,3 +35,54 @@ func (g GithubActionCi) TriggerWorkflow(spec spec.Spec, runName string, vcsToken
 
 	return err
 }
+
+// workflowRunIdFromUrl returns the id of a workflow run from its url, e.g. https://github.com/owner/repo/actions/runs/123
+func workflowRunIdFromUrl(workflowRunUrl string) (int64, bool) {
+	if workflowRunUrl == "" || workflowRunUrl == "#" {
+		return 0, false
+	}
+	runId, err := strconv.ParseInt(path.Base(workflowRunUrl), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return runId, true
+}
+
+func (g GithubActionCi) CancelWorkflow(spec spec.Spec, workflowRunUrl string) error {
+	runId, found := workflowRunIdFromUrl(workflowRunUrl)
+	if !found {
+		// the job didn't report its run yet, look it up among the recent runs
+		var err error
+		runId, _, err = utils.GetWorkflowIdAndUrlFromDiggerJobId(g.Client, spec.VCS.RepoOwner, spec.VCS.RepoName, spec.JobId)
+		if err != nil {
+			return fmt.Errorf("could not find workflow run of job %v: %v", spec.JobId, err)
+		}
+	}
+	log.Printf("CancelGithubWorkflow: repoOwner: %v, repoName: %v, runId: %v", spec.VCS.RepoOwner, spec.VCS.RepoName, runId)
+	resp, err := g.Client.Actions.CancelWorkflowRunByID(context.Background(), spec.VCS.RepoOwner, spec.VCS.RepoName, runId)
+	if resp != nil && resp.StatusCode == http.StatusConflict {
+		// the run already completed
+		return nil
+	}
+	return err
+}
+
+func (g GithubActionCi) IsWorkflowRunning(spec spec.Spec, workflowRunUrl string) (bool, error) {
+	runId, found := workflowRunIdFromUrl(workflowRunUrl)
+	if !found {
+		var err error
+		runId, _, err = utils.GetWorkflowIdAndUrlFromDiggerJobId(g.Client, spec.VCS.RepoOwner, spec.VCS.RepoName, spec.JobId)
+		if err != nil {
+			log.Printf("could not find workflow run of job %v: %v", spec.JobId, err)
+			return false, nil
+		}
+	}
+	run, resp, err := g.Client.Actions.GetWorkflowRunByID(context.Background(), spec.VCS.RepoOwner, spec.VCS.RepoName, runId)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not get workflow run %v: %v", runId, err)
+	}
+	return run.GetStatus() != "completed", nil
+}
@@ -24,6 +24,24 @@ func New() *Config {
 	v.SetDefault("build_date", "null")
 	v.SetDefault("deployed_at", time.Now().UTC().Format(time.RFC3339))
 	v.SetDefault("max_concurrency_per_batch", "0")
+	// maximum number of jobs running at the same time on a CI backend, 0 for no limit
+	v.SetDefault("max_concurrency_github_actions", "0")
+	v.SetDefault("max_concurrency_gitlab_ci", "0")
+	// how long a run can stay in a stage before it is failed
+	v.SetDefault("run_planning_timeout", "2h")
+	v.SetDefault("run_approval_timeout", "168h")
+	v.SetDefault("run_applying_timeout", "2h")
+	// the scheduler reacts to notifications and polls as a fallback, claims of workers that died expire after the lease
+	v.SetDefault("scheduler_poll_interval", "30s")
+	v.SetDefault("scheduler_claim_lease", "10m")
+	// a triggered job whose CI run didn't start after this long is retried or failed
+	v.SetDefault("job_start_timeout", "30m")
+	// a started job that didn't send a heartbeat for this long is reaped unless its CI run is still running
+	v.SetDefault("job_plan_heartbeat_timeout", "10m")
+	v.SetDefault("job_apply_heartbeat_timeout", "30m")
+	// addresses on which the backend and the scheduler worker serve their prometheus metrics, empty to disable them
+	v.SetDefault("metrics_address", ":9090")
+	v.SetDefault("worker_metrics_address", ":9091")
 	v.BindEnv()
 	return v
 }
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultAuditEventsLimit = 100
+const maxAuditEventsLimit = 1000
+
+// recordJobCompletedAuditEvent adds the result of a job reported by the cli to the audit log
+func recordJobCompletedAuditEvent(orgId uint, job *models.DiggerJob, outcome string) {
+	event := models.AuditEvent{
+		OrganisationID: orgId,
+		Type:           models.AuditJobCompleted,
+		JobId:          job.DiggerJobID,
+		Outcome:        outcome,
+	}
+	if job.FailureClass != "" {
+		event.Details = map[string]string{"failure_class": job.FailureClass}
+	}
+	if job.BatchID != nil {
+		event.BatchId = *job.BatchID
+	}
+	if job.Batch != nil {
+		event.Repo = job.Batch.RepoFullName
+		event.PrNumber = job.Batch.PrNumber
+		event.Command = string(job.Batch.BatchType)
+	}
+	var jobSpec orchestrator_scheduler.JobJson
+	err := json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+	if err != nil {
+		log.Printf("could not read the spec of job %v: %v", job.DiggerJobID, err)
+	} else {
+		event.ProjectName = jobSpec.ProjectName
+		event.Actor = jobSpec.RequestedBy
+		event.CommitSha = jobSpec.Commit
+	}
+	models.DB.RecordAuditEvent(event)
+}
+
+// auditEventsOrganisation returns the id of the organisation of the request if it is the one in the path
+func auditEventsOrganisation(c *gin.Context) (uint, bool) {
+	organisation := c.Param("organisation")
+	loggedInOrganisation := c.GetUint(middleware.ORGANISATION_ID_KEY)
+
+	org := models.Organisation{}
+	orgResult := models.DB.GormDB.Where("name = ?", organisation).Take(&org)
+	if orgResult.RowsAffected == 0 {
+		c.String(http.StatusNotFound, "Could not find organisation: "+organisation)
+		return 0, false
+	}
+	if org.ID != loggedInOrganisation {
+		log.Printf("Organisation ID %v does not match logged in organisation ID %v", org.ID, loggedInOrganisation)
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return 0, false
+	}
+	return org.ID, true
+}
+
+// parseAuditEventFilter reads the filter of audit events from the query parameters type, repo, project, actor,
+// pr, since, until (RFC3339 timestamps) and before (id of the event to page from)
+func parseAuditEventFilter(c *gin.Context) (models.AuditEventFilter, error) {
+	filter := models.AuditEventFilter{
+		Type:        c.Query("type"),
+		Repo:        c.Query("repo"),
+		ProjectName: c.Query("project"),
+		Actor:       c.Query("actor"),
+	}
+	if pr := c.Query("pr"); pr != "" {
+		prNumber, err := strconv.Atoi(pr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid pr: %v", pr)
+		}
+		filter.PrNumber = prNumber
+	}
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %v, expected an RFC3339 timestamp: %v", param, value)
+		}
+		*target = &t
+	}
+	if before := c.Query("before"); before != "" {
+		beforeId, err := strconv.ParseUint(before, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid before: %v", before)
+		}
+		filter.BeforeId = uint(beforeId)
+	}
+	return filter, nil
+}
+
+// ListAuditEventsForOrg returns the latest events of the audit log of the organisation matching the filter of
+// the query, ?limit= controls how many are returned. The next page is fetched by passing next_before as ?before=
+func ListAuditEventsForOrg(c *gin.Context) {
+	orgId, ok := auditEventsOrganisation(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseAuditEventFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultAuditEventsLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 || parsed > maxAuditEventsLimit {
+			c.String(http.StatusBadRequest, fmt.Sprintf("Invalid limit: %v", limitParam))
+			return
+		}
+		limit = parsed
+	}
+
+	events, err := models.DB.GetAuditEvents(orgId, filter, limit)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		return
+	}
+
+	response := make([]interface{}, 0, len(events))
+	for _, e := range events {
+		response = append(response, e.MapToJsonStruct())
+	}
+	var nextBefore *uint
+	if len(events) == limit {
+		nextBefore = &events[len(events)-1].ID
+	}
+	c.JSON(http.StatusOK, gin.H{"events": response, "next_before": nextBefore})
+}
+
+// ExportAuditEventsForOrg streams every event of the audit log of the organisation matching the filter of the
+// query as JSON lines, latest first
+func ExportAuditEventsForOrg(c *gin.Context) {
+	orgId, ok := auditEventsOrganisation(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseAuditEventFilter(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", "attachment; filename=audit-events.jsonl")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for {
+		events, err := models.DB.GetAuditEvents(orgId, filter, maxAuditEventsLimit)
+		if err != nil {
+			// the response has started already, the export ends truncated
+			log.Printf("Error exporting audit events of org %v: %v", orgId, err)
+			return
+		}
+		for _, e := range events {
+			err = encoder.Encode(e.MapToJsonStruct())
+			if err != nil {
+				log.Printf("Error writing audit events of org %v: %v", orgId, err)
+				return
+			}
+		}
+		c.Writer.Flush()
+		if len(events) < maxAuditEventsLimit {
+			return
+		}
+		filter.BeforeId = events[len(events)-1].ID
+	}
+}
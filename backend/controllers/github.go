@@ -11,13 +11,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/diggerhq/digger/backend/ci_backends"
 	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/segment"
 	"github.com/diggerhq/digger/backend/services"
 	"github.com/diggerhq/digger/libs/ci"
@@ -25,7 +25,10 @@ import (
 	comment_updater "github.com/diggerhq/digger/libs/comment_utils/reporting"
 	dg_locking "github.com/diggerhq/digger/libs/locking"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/diggerhq/digger/libs/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 
 	"github.com/diggerhq/digger/backend/middleware"
@@ -61,6 +64,7 @@ func (d DiggerController) GithubAppWebHook(c *gin.Context) {
 	}
 
 	webhookType := github.WebHookType(c.Request)
+	metrics.WebhookReceived("github", webhookType)
 	event, err := github.ParseWebHook(webhookType, payload)
 	if err != nil {
 		log.Printf("Failed to parse Github Event. :%v\n", err)
@@ -87,7 +91,7 @@ func (d DiggerController) GithubAppWebHook(c *gin.Context) {
 			c.String(http.StatusOK, "OK")
 			return
 		}
-		err := handleIssueCommentEvent(gh, event, d.CiBackendProvider)
+		err := handleIssueCommentEvent(c.Request.Context(), gh, event, d.CiBackendProvider)
 		if err != nil {
 			log.Printf("handleIssueCommentEvent error: %v", err)
 			c.String(http.StatusInternalServerError, err.Error())
@@ -105,7 +109,7 @@ func (d DiggerController) GithubAppWebHook(c *gin.Context) {
 		}
 	case *github.PullRequestEvent:
 		log.Printf("Got pull request event for %d", *event.PullRequest.ID)
-		err := handlePullRequestEvent(gh, event, d.CiBackendProvider)
+		err := handlePullRequestEvent(c.Request.Context(), gh, event, d.CiBackendProvider)
 		if err != nil {
 			log.Printf("handlePullRequestEvent error: %v", err)
 			c.String(http.StatusInternalServerError, err.Error())
@@ -387,7 +391,7 @@ func handlePushEvent(gh utils.GithubClientProvider, payload *github.PushEvent) e
 	return nil
 }
 
-func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullRequestEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+func handlePullRequestEvent(ctx context.Context, gh utils.GithubClientProvider, payload *github.PullRequestEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
 	installationId := *payload.Installation.ID
 	appId := *payload.Installation.AppID
 	repoName := *payload.Repo.Name
@@ -449,6 +453,44 @@ func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullR
 		return fmt.Errorf("error processing event")
 	}
 
+	if action == "opened" || action == "reopened" || action == "synchronize" || action == "ready_for_review" {
+		owners := make([]string, 0)
+		for _, project := range impactedProjects {
+			owners = append(owners, project.Owners...)
+		}
+		err = ci.RequestOwnerReviews(ghService, prNumber, owners, payload.PullRequest.GetUser().GetLogin())
+		if err != nil {
+			// not requesting reviews should never block plans
+			log.Printf("could not request reviews from project owners: %v", err)
+		}
+	}
+
+	ciBackend, err := ciBackendProvider.GetCiBackend(
+		ci_backends.CiBackendOptions{
+			GithubClientProvider: gh,
+			GithubInstallationId: installationId,
+			GithubAppId:          appId,
+			RepoName:             repoName,
+			RepoOwner:            repoOwner,
+			RepoFullName:         repoFullName,
+		},
+	)
+	if err != nil {
+		log.Printf("GetCiBackend error: %v", err)
+		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
+		return fmt.Errorf("error fetching ci backed %v", err)
+	}
+
+	if action == "synchronize" {
+		// plans of the previous commit are stale, apply batches are left running so that an apply isn't interrupted
+		cancelled, err := services.CancelActiveBatchesForPr(ciBackend, models.DiggerVCSGithub, repoFullName, prNumber, orchestrator_scheduler.BatchJobSuperseded, true)
+		if err != nil {
+			log.Printf("could not supersede batches of PR %v: %v", prNumber, err)
+		} else if cancelled > 0 {
+			log.Printf("superseded %v jobs of PR %v", cancelled, prNumber)
+		}
+	}
+
 	jobsForImpactedProjects, _, err := dg_github.ConvertGithubPullRequestEventToJobs(payload, impactedProjects, nil, *config, false)
 	if err != nil {
 		log.Printf("Error converting event to jobsForImpactedProjects: %v", err)
@@ -477,6 +519,17 @@ func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullR
 		return nil
 	}
 
+	models.DB.RecordAuditEvent(models.AuditEvent{
+		OrganisationID: organisationId,
+		Type:           models.AuditCommandRequested,
+		Actor:          payload.Sender.GetLogin(),
+		Repo:           repoFullName,
+		PrNumber:       prNumber,
+		CommitSha:      commitSha,
+		Command:        string(*diggerCommand),
+		Details:        map[string]string{"pull_request_action": action},
+	})
+
 	// perform locking/unlocking in backend
 	if config.PrLocks {
 		for _, project := range impactedProjects {
@@ -492,6 +545,7 @@ func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullR
 			}
 			err = dg_locking.PerformLockingActionFromCommand(prLock, *diggerCommand)
 			if err != nil {
+				metrics.LockFailed(prLock.LockId(), prNumber)
 				utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
 				return fmt.Errorf("failed to perform lock action on project: %v, %v", project.Name, err)
 			}
@@ -581,23 +635,7 @@ func handlePullRequestEvent(gh utils.GithubClientProvider, payload *github.PullR
 
 	segment.Track(strconv.Itoa(int(organisationId)), "backend_trigger_job")
 
-	ciBackend, err := ciBackendProvider.GetCiBackend(
-		ci_backends.CiBackendOptions{
-			GithubClientProvider: gh,
-			GithubInstallationId: installationId,
-			GithubAppId:          appId,
-			RepoName:             repoName,
-			RepoOwner:            repoOwner,
-			RepoFullName:         repoFullName,
-		},
-	)
-	if err != nil {
-		log.Printf("GetCiBackend error: %v", err)
-		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
-		return fmt.Errorf("error fetching ci backed %v", err)
-	}
-
-	err = TriggerDiggerJobs(ciBackend, repoFullName, repoOwner, repoName, batchId, prNumber, ghService, gh)
+	err = TriggerDiggerJobs(ctx, ciBackend, repoFullName, repoOwner, repoName, batchId, prNumber, ghService, gh)
 	if err != nil {
 		log.Printf("TriggerDiggerJobs error: %v", err)
 		utils.InitCommentReporter(ghService, prNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
@@ -619,13 +657,18 @@ func GetDiggerConfigForBranch(gh utils.GithubClientProvider, installationId int6
 	var dependencyGraph graph.Graph[string, dg_configuration.Project]
 
 	err = utils.CloneGitRepoAndDoAction(cloneUrl, branch, *token, func(dir string) error {
-		diggerYmlBytes, err := os.ReadFile(path.Join(dir, "digger.yml"))
-		diggerYmlStr = string(diggerYmlBytes)
+		var err error
 		config, _, dependencyGraph, err = dg_configuration.LoadDiggerConfig(dir, true, changedFiles)
 		if err != nil {
 			log.Printf("Error loading digger config: %v", err)
 			return err
 		}
+		// the stored config is loaded later without the repository, so includes and project files are merged in
+		diggerYmlStr, err = dg_configuration.LoadMergedDiggerConfigString(dir)
+		if err != nil {
+			log.Printf("Error merging digger config: %v", err)
+			return err
+		}
 		return nil
 	})
 	if err != nil {
@@ -696,7 +739,7 @@ func getBatchType(jobs []orchestrator_scheduler.Job) orchestrator_scheduler.Digg
 	}
 }
 
-func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.IssueCommentEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
+func handleIssueCommentEvent(ctx context.Context, gh utils.GithubClientProvider, payload *github.IssueCommentEvent, ciBackendProvider ci_backends.CiBackendProvider) error {
 	installationId := *payload.Installation.ID
 	appId := *payload.Installation.AppID
 	repoName := *payload.Repo.Name
@@ -769,6 +812,56 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 		return fmt.Errorf("unkown digger command in comment %v", err)
 	}
 
+	models.DB.RecordAuditEvent(models.AuditEvent{
+		OrganisationID: orgId,
+		Type:           models.AuditCommandRequested,
+		Actor:          actor,
+		Repo:           repoFullName,
+		PrNumber:       issueNumber,
+		CommitSha:      *commitSha,
+		CommentUrl:     payload.Comment.GetHTMLURL(),
+		Command:        string(*diggerCommand),
+		Details:        map[string]string{"comment": commentBody},
+	})
+
+	if *diggerCommand == orchestrator_scheduler.DiggerCommandCancel {
+		ciBackend, err := ciBackendProvider.GetCiBackend(
+			ci_backends.CiBackendOptions{
+				GithubClientProvider: gh,
+				GithubInstallationId: installationId,
+				GithubAppId:          appId,
+				RepoName:             repoName,
+				RepoOwner:            repoOwner,
+				RepoFullName:         repoFullName,
+			},
+		)
+		if err != nil {
+			log.Printf("GetCiBackend error: %v", err)
+			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
+			return fmt.Errorf("error fetching ci backed %v", err)
+		}
+		// cancelling is subject to the access policy of the projects like plan and apply
+		deniedProjects, err := services.CancelDeniedProjects(orgId, ghService, ghService, models.DiggerVCSGithub, repoFullName, repoOwner, repoName, issueNumber, actor)
+		if err != nil {
+			log.Printf("CancelDeniedProjects error: %v", err)
+			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Could not check access policy: %v", err))
+			return fmt.Errorf("error checking access policy: %v", err)
+		}
+		if len(deniedProjects) > 0 {
+			log.Printf("%v is not allowed to cancel jobs of projects %v", actor, deniedProjects)
+			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: %v is not allowed to perform action: cancel on projects: %v. Check your policies :x:", actor, strings.Join(deniedProjects, ", ")))
+			return nil
+		}
+		cancelled, err := services.CancelActiveBatchesForPr(ciBackend, models.DiggerVCSGithub, repoFullName, issueNumber, orchestrator_scheduler.BatchJobCancelled, false)
+		if err != nil {
+			log.Printf("CancelActiveBatchesForPr error: %v", err)
+			utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Could not cancel jobs: %v", err))
+			return fmt.Errorf("error cancelling jobs: %v", err)
+		}
+		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":white_check_mark: Cancelled %v jobs", cancelled))
+		return nil
+	}
+
 	prBranchName, _, err := ghService.GetBranchName(issueNumber)
 	if err != nil {
 		log.Printf("GetBranchName error: %v", err)
@@ -776,7 +869,7 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 		return fmt.Errorf("error while fetching branch name")
 	}
 
-	impactedProjects, impactedProjectsSourceMapping, requestedProject, _, err := generic.ProcessIssueCommentEvent(issueNumber, *payload.Comment.Body, config, projectsGraph, ghService)
+	impactedProjects, impactedProjectsSourceMapping, requestedProjects, _, err := generic.ProcessIssueCommentEvent(issueNumber, *payload.Comment.Body, config, projectsGraph, ghService)
 	if err != nil {
 		log.Printf("Error processing event: %v", err)
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Error processing event: %v", err))
@@ -786,7 +879,8 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 
 	// perform unlocking in backend
 	if config.PrLocks {
-		for _, project := range impactedProjects {
+		lockProjects, _ := orchestrator_scheduler.ProjectsToRun(impactedProjects, requestedProjects)
+		for _, project := range lockProjects {
 			prLock := dg_locking.PullRequestLock{
 				InternalLock: locking.BackendDBLock{
 					OrgId: orgId,
@@ -799,6 +893,7 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 			}
 			err = dg_locking.PerformLockingActionFromCommand(prLock, *diggerCommand)
 			if err != nil {
+				metrics.LockFailed(prLock.LockId(), issueNumber)
 				utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
 				return fmt.Errorf("failed perform lock action on project: %v %v", project.Name, err)
 			}
@@ -812,7 +907,7 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 		return nil
 	}
 
-	jobs, _, err := generic.ConvertIssueCommentEventToJobs(repoFullName, actor, issueNumber, commentBody, impactedProjects, requestedProject, config.Workflows, prBranchName, defaultBranch)
+	jobs, _, err := generic.ConvertIssueCommentEventToJobs(repoFullName, actor, issueNumber, commentBody, impactedProjects, requestedProjects, config.Workflows, prBranchName, defaultBranch)
 	if err != nil {
 		log.Printf("Error converting event to jobs: %v", err)
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Error converting event to jobs: %v", err))
@@ -841,10 +936,8 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 		fmt.Errorf("error setting status for PR: %v", err)
 	}
 
-	impactedProjectsMap := make(map[string]dg_configuration.Project)
-	for _, p := range impactedProjects {
-		impactedProjectsMap[p.Name] = p
-	}
+	// the jobs are created for the projects selected by the comment, which may not all be impacted
+	impactedProjectsMap := utils.ProjectsToRunMap(impactedProjects, requestedProjects)
 
 	impactedProjectsJobMap := make(map[string]orchestrator_scheduler.Job)
 	for _, j := range jobs {
@@ -911,7 +1004,7 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
 		return fmt.Errorf("error fetching ci backed %v", err)
 	}
-	err = TriggerDiggerJobs(ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, ghService, gh)
+	err = TriggerDiggerJobs(ctx, ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, ghService, gh)
 	if err != nil {
 		log.Printf("TriggerDiggerJobs error: %v", err)
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
@@ -920,12 +1013,25 @@ func handleIssueCommentEvent(gh utils.GithubClientProvider, payload *github.Issu
 	return nil
 }
 
-func TriggerDiggerJobs(ciBackend ci_backends.CiBackend, repoFullName string, repoOwner string, repoName string, batchId *uuid.UUID, prNumber int, prService ci.PullRequestService, gh utils.GithubClientProvider) error {
-	_, err := models.DB.GetDiggerBatch(batchId)
+// TriggerDiggerJobs schedules the pending jobs of the batch, their spans join the trace of ctx
+func TriggerDiggerJobs(ctx context.Context, ciBackend ci_backends.CiBackend, repoFullName string, repoOwner string, repoName string, batchId *uuid.UUID, prNumber int, prService ci.PullRequestService, gh utils.GithubClientProvider) (err error) {
+	ctx, span := tracing.Start(ctx, "trigger digger jobs", trace.WithAttributes(
+		attribute.String("digger.repo", repoFullName),
+		attribute.Int("digger.pr_number", prNumber),
+		attribute.String("digger.batch_id", batchId.String()),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	batch, err := models.DB.GetDiggerBatch(batchId)
 	if err != nil {
 		log.Printf("failed to get digger batch, %v\n", err)
 		return fmt.Errorf("failed to get digger batch, %v\n", err)
 	}
+	// the jobs are triggered without the trace context when it can't be saved, their spans start new traces
+	err = models.DB.UpdateDiggerBatchTraceContext(batch, tracing.Inject(ctx))
+	if err != nil {
+		log.Printf("failed to save the trace context of batch %v, %v\n", batchId, err)
+	}
 	diggerJobs, err := models.DB.GetPendingParentDiggerJobs(batchId)
 
 	if err != nil {
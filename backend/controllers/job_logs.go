@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/redact"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// interval at which new chunks of logs are looked up for streaming
+const jobLogsPollInterval = time.Second
+
+// maximum number of chunks of logs sent at once
+const jobLogsPageSize = 100
+
+type ReportJobLogsRequest struct {
+	Content string `json:"content"`
+}
+
+// ReportJobLogs appends a chunk of output streamed by the cli to the logs of a job
+func ReportJobLogs(c *gin.Context) {
+	job, ok := getJobForOrganisation(c)
+	if !ok {
+		return
+	}
+
+	var request ReportJobLogsRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error binding JSON"})
+		return
+	}
+
+	_, err = models.DB.CreateDiggerJobLogChunk(job.DiggerJobID, redact.String(request.Content))
+	if err != nil {
+		log.Printf("Error saving logs of job %v: %v", job.DiggerJobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving logs"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// getJobForOrganisation returns the job of the request if the organisation of the request can access it
+func getJobForOrganisation(c *gin.Context) (*models.DiggerJob, bool) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
+	}
+
+	job, err := models.DB.GetDiggerJob(c.Param("jobId"))
+	if err != nil {
+		log.Printf("Error fetching job: %v", err)
+		c.String(http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+	if job.ID == 0 {
+		c.String(http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+
+	// jobs whose organisation can't be resolved are not shown to anyone
+	jobOrgId, err := models.DB.GetOrganisationIdOfJob(job)
+	if err != nil {
+		log.Printf("Error resolving organisation of job %v: %v", job.DiggerJobID, err)
+		c.String(http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+	if jobOrgId == 0 {
+		log.Printf("organisation of job %v is unknown", job.DiggerJobID)
+		c.String(http.StatusNotFound, "Job not found")
+		return nil, false
+	}
+	if jobOrgId != orgId {
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
+	}
+	return job, true
+}
+
+func jobFinished(status orchestrator_scheduler.DiggerJobStatus) bool {
+	return status == orchestrator_scheduler.DiggerJobSucceeded || status == orchestrator_scheduler.DiggerJobFailed || status == orchestrator_scheduler.DiggerJobCancelled
+}
+
+// JobLogsPage renders a page following the logs of a job live
+func JobLogsPage(c *gin.Context) {
+	job, ok := getJobForOrganisation(c)
+	if !ok {
+		return
+	}
+	serializedJob, err := job.MapToJsonStruct()
+	if err != nil {
+		log.Printf("Error serializing job: %v", err)
+	}
+	c.HTML(http.StatusOK, "job_logs.tmpl", gin.H{
+		"JobId":       job.DiggerJobID,
+		"ProjectName": serializedJob.ProjectName,
+		"Status":      job.Status.ToString(),
+	})
+}
+
+// StreamJobLogs streams the logs of a job as server-sent events. Every chunk is sent as a "log" event whose
+// id can be passed back in the Last-Event-ID header to resume the stream, and an "end" event with the final
+// status of the job closes the stream.
+func StreamJobLogs(c *gin.Context) {
+	job, ok := getJobForOrganisation(c)
+	if !ok {
+		return
+	}
+
+	var lastId uint
+	if lastEventId := c.GetHeader("Last-Event-ID"); lastEventId != "" {
+		id, err := strconv.ParseUint(lastEventId, 10, 64)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		lastId = uint(id)
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	// proxies must not buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	ticker := time.NewTicker(jobLogsPollInterval)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		// the status is read before the logs so that no chunk sent before the job finished is missed
+		job, err := models.DB.GetDiggerJob(job.DiggerJobID)
+		if err != nil {
+			log.Printf("Error fetching job: %v", err)
+			return false
+		}
+		chunks, err := models.DB.GetDiggerJobLogChunks(job.DiggerJobID, lastId, jobLogsPageSize)
+		if err != nil {
+			log.Printf("Error fetching logs of job %v: %v", job.DiggerJobID, err)
+			return false
+		}
+		for _, chunk := range chunks {
+			c.Render(-1, sse.Event{Id: strconv.FormatUint(uint64(chunk.ID), 10), Event: "log", Data: chunk.Content})
+			lastId = chunk.ID
+		}
+		if len(chunks) == jobLogsPageSize {
+			return true
+		}
+		if jobFinished(job.Status) {
+			c.Render(-1, sse.Event{Event: "end", Data: job.Status.ToString()})
+			return false
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/diggerhq/digger/backend/middleware"
 	"github.com/diggerhq/digger/backend/models"
 	dg_configuration "github.com/diggerhq/digger/libs/digger_config"
+	dg_policy "github.com/diggerhq/digger/libs/policy"
 	"github.com/dominikbraun/graph"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,6 +14,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 type CreatePolicyInput struct {
@@ -62,8 +64,7 @@ func findPolicy(c *gin.Context, policyType string) {
 		return
 	}
 
-	c.Header("Content-Type", "text/plain; charset=utf-8")
-	c.String(http.StatusOK, policy.Policy)
+	writePolicyVersion(c, &policy)
 }
 
 func FindAccessPolicyForOrg(c *gin.Context) {
@@ -103,8 +104,54 @@ func findPolicyForOrg(c *gin.Context, policyType string) {
 		return
 	}
 
+	writePolicyVersion(c, &policy)
+}
+
+// writePolicyVersion responds with the current policy or the version requested with ?version=N,
+// bundles are returned as tar.gz and the checksum is used as ETag so that clients can cache policies
+func writePolicyVersion(c *gin.Context, policy *models.Policy) {
+	content := policy.Policy
+	bundle := policy.Bundle
+	checksum := policy.Checksum
+
+	versionParam := c.Query("version")
+	if versionParam != "" {
+		version, err := strconv.ParseUint(versionParam, 10, 32)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid policy version: "+versionParam)
+			return
+		}
+		policyVersion, err := models.DB.GetPolicyVersion(policy.ID, uint(version))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.String(http.StatusNotFound, fmt.Sprintf("Could not find version %v of policy", version))
+			} else {
+				c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+			}
+			return
+		}
+		content = policyVersion.Content
+		bundle = policyVersion.Bundle
+		checksum = policyVersion.Checksum
+	}
+
+	c.Header(dg_policy.EnforcementHeader, policy.EnforcementMode())
+
+	if checksum != "" {
+		etag := fmt.Sprintf("\"%v\"", checksum)
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	if len(bundle) > 0 {
+		c.Data(http.StatusOK, "application/gzip", bundle)
+		return
+	}
 	c.Header("Content-Type", "text/plain; charset=utf-8")
-	c.String(http.StatusOK, policy.Policy)
+	c.String(http.StatusOK, content)
 }
 
 func JoinedOrganisationRepoProjectQuery() *gorm.DB {
@@ -128,10 +175,9 @@ func UpsertDriftPolicyForOrg(c *gin.Context) {
 
 func upsertPolicyForOrg(c *gin.Context, policyType string) {
 	// Validate input
-	policyData, err := io.ReadAll(c.Request.Body)
+	content, bundle, err := readPolicyUpload(c)
 	if err != nil {
-		// Handle the error
-		c.String(http.StatusInternalServerError, "Error reading request body")
+		c.String(http.StatusBadRequest, fmt.Sprintf("Invalid policy: %v", err))
 		return
 	}
 	organisation := c.Param("organisation")
@@ -156,27 +202,23 @@ func upsertPolicyForOrg(c *gin.Context, policyType string) {
 	policyResult := models.DB.GormDB.Where("organisation_id = ? AND (repo_id IS NULL AND project_id IS NULL) AND type = ?", org.ID, policyType).Take(&policy)
 
 	if policyResult.RowsAffected == 0 {
-		err := models.DB.GormDB.Create(&models.Policy{
+		policy = models.Policy{
 			OrganisationID: org.ID,
 			Type:           policyType,
-			Policy:         string(policyData),
-		}).Error
+		}
+		err := models.DB.GormDB.Create(&policy).Error
 
 		if err != nil {
 			log.Printf("Error creating policy: %v", err)
 			c.String(http.StatusInternalServerError, "Error creating policy")
 			return
 		}
-	} else {
-		err := policyResult.Update("policy", string(policyData)).Error
-		if err != nil {
-			log.Printf("Error updating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error updating policy")
-			return
-		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	if !applyPolicyEnforcement(c, &policy) {
+		return
+	}
+	savePolicyVersion(c, &policy, content, bundle)
 }
 
 func UpsertAccessPolicyForRepoAndProject(c *gin.Context) {
@@ -202,10 +244,9 @@ func upsertPolicyForRepoAndProject(c *gin.Context, policyType string) {
 	orgID = orgID.(uint)
 
 	// Validate input
-	policyData, err := io.ReadAll(c.Request.Body)
+	content, bundle, err := readPolicyUpload(c)
 	if err != nil {
-		// Handle the error
-		c.String(http.StatusInternalServerError, "Error reading request body")
+		c.String(http.StatusBadRequest, fmt.Sprintf("Invalid policy: %v", err))
 		return
 	}
 	repo := c.Param("repo")
@@ -246,28 +287,83 @@ func upsertPolicyForRepoAndProject(c *gin.Context, policyType string) {
 	policyResult := models.DB.GormDB.Where("organisation_id = ? AND repo_id = ? AND project_id = ? AND type = ?", orgID, repoModel.ID, projectModel.ID, policyType).Take(&policy)
 
 	if policyResult.RowsAffected == 0 {
-		err := models.DB.GormDB.Create(&models.Policy{
+		policy = models.Policy{
 			OrganisationID: orgID.(uint),
 			RepoID:         &repoModel.ID,
 			ProjectID:      &projectModel.ID,
 			Type:           policyType,
-			Policy:         string(policyData),
-		}).Error
+		}
+		err := models.DB.GormDB.Create(&policy).Error
 		if err != nil {
 			log.Printf("Error creating policy: %v", err)
 			c.String(http.StatusInternalServerError, "Error creating policy")
 			return
 		}
-	} else {
-		err := policyResult.Update("policy", string(policyData)).Error
-		if err != nil {
-			log.Printf("Error updating policy: %v", err)
-			c.String(http.StatusInternalServerError, "Error updating policy")
-			return
+	}
+
+	if !applyPolicyEnforcement(c, &policy) {
+		return
+	}
+	savePolicyVersion(c, &policy, content, bundle)
+}
+
+// readPolicyUpload reads either a plain rego module or an OPA bundle (tar.gz) from the request body
+func readPolicyUpload(c *gin.Context) (string, []byte, error) {
+	policyData, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading request body: %v", err)
+	}
+	if dg_policy.IsBundle(string(policyData)) {
+		if _, err := dg_policy.LoadBundle(policyData); err != nil {
+			return "", nil, err
 		}
+		return "", policyData, nil
+	}
+	return string(policyData), nil, nil
+}
+
+// policyAuthor returns who is making the change, as authenticated by the auth middleware
+func policyAuthor(c *gin.Context) string {
+	author := c.GetString(middleware.IDENTITY_KEY)
+	if author == "" {
+		return "unknown"
+	}
+	return author
+}
+
+// applyPolicyEnforcement updates the enforcement mode of the policy if the X-Digger-Policy-Enforcement header is set
+func applyPolicyEnforcement(c *gin.Context, policy *models.Policy) bool {
+	enforcement := c.GetHeader(dg_policy.EnforcementHeader)
+	if enforcement == "" || enforcement == policy.EnforcementMode() {
+		return true
+	}
+	if !models.IsValidPolicyEnforcement(enforcement) {
+		c.String(http.StatusBadRequest, fmt.Sprintf("Invalid policy enforcement: %v", enforcement))
+		return false
+	}
+	err := models.DB.UpdatePolicyEnforcement(policy, enforcement)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error updating policy enforcement")
+		return false
+	}
+	return true
+}
+
+func savePolicyVersion(c *gin.Context, policy *models.Policy, content string, bundle []byte) {
+	if policy.Version > 0 && policy.Checksum == models.PolicyChecksum(content, bundle) {
+		log.Printf("Policy %v is unchanged, not creating a new version", policy.ID)
+		c.JSON(http.StatusOK, gin.H{"success": true, "version": policy.Version, "enforcement": policy.EnforcementMode()})
+		return
+	}
+
+	policyVersion, err := models.DB.CreatePolicyVersion(policy, content, bundle, policyAuthor(c), c.GetHeader("X-Digger-Policy-Message"))
+	if err != nil {
+		log.Printf("Error updating policy: %v", err)
+		c.String(http.StatusInternalServerError, "Error updating policy")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"success": true, "version": policyVersion.Version, "enforcement": policy.EnforcementMode()})
 }
 
 func IssueAccessTokenForOrg(c *gin.Context) {
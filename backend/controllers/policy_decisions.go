@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/metrics"
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	dg_policy "github.com/diggerhq/digger/libs/policy"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPolicyDecisionsLimit = 100
+
+// RecordPolicyDecision stores a decision of a policy running in audit mode as reported by the cli. The cli
+// reports the decisions of policies in enforce mode as well, only their evaluation time is kept for the metrics.
+func RecordPolicyDecision(c *gin.Context) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+
+	policyType := c.Param("policyType")
+	if !isValidPolicyType(policyType) {
+		c.String(http.StatusBadRequest, "Invalid policy type: "+policyType)
+		return
+	}
+
+	var request dg_policy.Decision
+	err := c.BindJSON(&request)
+	if err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.String(http.StatusBadRequest, "Invalid policy decision")
+		return
+	}
+
+	if request.EvaluationSeconds > 0 {
+		metrics.PolicyEvaluated(policyType, request.Allowed, time.Duration(request.EvaluationSeconds*float64(time.Second)))
+	}
+
+	enforcement := request.Enforcement
+	if enforcement == "" {
+		enforcement = models.POLICY_ENFORCEMENT_AUDIT
+	}
+	if enforcement == models.POLICY_ENFORCEMENT_ENFORCE {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	decision := models.PolicyDecision{
+		OrganisationID: orgId.(uint),
+		PolicyType:     policyType,
+		Enforcement:    enforcement,
+		Repo:           c.Param("repo"),
+		ProjectName:    c.Param("projectName"),
+		Command:        request.Command,
+		RequestedBy:    request.RequestedBy,
+		Allowed:        request.Allowed,
+		Violations:     strings.Join(request.Violations, "\n"),
+	}
+	err = models.DB.CreatePolicyDecision(&decision)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Error recording policy decision")
+		return
+	}
+	repoFullName := decision.Repo
+	if repo, err := models.DB.GetRepo(decision.OrganisationID, decision.Repo); err == nil && repo != nil && repo.RepoFullName != "" {
+		repoFullName = repo.RepoFullName
+	}
+	outcome := "allowed"
+	if !decision.Allowed {
+		outcome = "denied"
+	}
+	models.DB.RecordAuditEvent(models.AuditEvent{
+		OrganisationID: decision.OrganisationID,
+		Type:           models.AuditPolicyDecision,
+		Actor:          decision.RequestedBy,
+		Repo:           repoFullName,
+		ProjectName:    decision.ProjectName,
+		Command:        decision.Command,
+		Outcome:        outcome,
+		Details: map[string]string{
+			"policy_type": decision.PolicyType,
+			"enforcement": decision.Enforcement,
+			"violations":  decision.Violations,
+		},
+	})
+
+	c.JSON(http.StatusOK, decision.MapToJsonStruct())
+}
+
+func ListPolicyDecisionsForRepoAndProject(c *gin.Context) {
+	listPolicyDecisions(c, c.Param("repo"), c.Param("projectName"))
+}
+
+func ListPolicyDecisionsForOrg(c *gin.Context) {
+	organisation := c.Param("organisation")
+	loggedInOrganisation := c.GetUint(middleware.ORGANISATION_ID_KEY)
+
+	org := models.Organisation{}
+	orgResult := models.DB.GormDB.Where("name = ?", organisation).Take(&org)
+	if orgResult.RowsAffected == 0 {
+		c.String(http.StatusNotFound, "Could not find organisation: "+organisation)
+		return
+	}
+	if org.ID != loggedInOrganisation {
+		log.Printf("Organisation ID %v does not match logged in organisation ID %v", org.ID, loggedInOrganisation)
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+
+	listPolicyDecisions(c, "", "")
+}
+
+// listPolicyDecisions returns the latest audit decisions, ?limit= controls how many are returned
+func listPolicyDecisions(c *gin.Context, repo string, projectName string) {
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+
+	policyType := c.Param("policyType")
+	if !isValidPolicyType(policyType) {
+		c.String(http.StatusBadRequest, "Invalid policy type: "+policyType)
+		return
+	}
+
+	limit := defaultPolicyDecisionsLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			c.String(http.StatusBadRequest, fmt.Sprintf("Invalid limit: %v", limitParam))
+			return
+		}
+		limit = parsed
+	}
+
+	decisions, err := models.DB.GetPolicyDecisions(orgId.(uint), repo, projectName, policyType, limit)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		return
+	}
+
+	response := make([]interface{}, 0)
+	for _, d := range decisions {
+		response = append(response, d.MapToJsonStruct())
+	}
+	c.JSON(http.StatusOK, gin.H{"decisions": response})
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diggerhq/digger/backend/middleware"
+	"github.com/diggerhq/digger/backend/models"
+	dg_policy "github.com/diggerhq/digger/libs/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+type policyLookup func(c *gin.Context, policyType string) (*models.Policy, bool)
+
+func ListPolicyVersionsForOrg(c *gin.Context) {
+	listPolicyVersions(c, lookupPolicyForOrg)
+}
+
+func ListPolicyVersionsForRepoAndProject(c *gin.Context) {
+	listPolicyVersions(c, lookupPolicyForRepoAndProject)
+}
+
+func DiffPolicyVersionsForOrg(c *gin.Context) {
+	diffPolicyVersions(c, lookupPolicyForOrg)
+}
+
+func DiffPolicyVersionsForRepoAndProject(c *gin.Context) {
+	diffPolicyVersions(c, lookupPolicyForRepoAndProject)
+}
+
+func RollbackPolicyForOrg(c *gin.Context) {
+	rollbackPolicy(c, lookupPolicyForOrg)
+}
+
+func RollbackPolicyForRepoAndProject(c *gin.Context) {
+	rollbackPolicy(c, lookupPolicyForRepoAndProject)
+}
+
+func isValidPolicyType(policyType string) bool {
+	return policyType == models.POLICY_TYPE_ACCESS || policyType == models.POLICY_TYPE_PLAN || policyType == models.POLICY_TYPE_DRIFT
+}
+
+func lookupPolicyForOrg(c *gin.Context, policyType string) (*models.Policy, bool) {
+	organisation := c.Param("organisation")
+	loggedInOrganisation := c.GetUint(middleware.ORGANISATION_ID_KEY)
+
+	var policy models.Policy
+	err := JoinedOrganisationRepoProjectQuery().
+		Where("organisations.name = ? AND (repos.id IS NULL AND projects.id IS NULL) AND policies.type = ? ", organisation, policyType).
+		First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, "Could not find policy for organisation: "+organisation)
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return nil, false
+	}
+
+	if policy.OrganisationID != loggedInOrganisation {
+		log.Printf("Organisation ID %v does not match logged in organisation ID %v", policy.OrganisationID, loggedInOrganisation)
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
+	}
+	return &policy, true
+}
+
+func lookupPolicyForRepoAndProject(c *gin.Context, policyType string) (*models.Policy, bool) {
+	repo := c.Param("repo")
+	projectName := c.Param("projectName")
+	orgId, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		log.Printf("Organisation ID not found in context")
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return nil, false
+	}
+
+	var policy models.Policy
+	err := JoinedOrganisationRepoProjectQuery().
+		Where("repos.name = ? AND projects.name = ? AND policies.organisation_id = ? AND policies.type = ?", repo, projectName, orgId, policyType).
+		First(&policy).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, fmt.Sprintf("Could not find policy for repo %v and project name %v", repo, projectName))
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return nil, false
+	}
+	return &policy, true
+}
+
+func policyFromRequest(c *gin.Context, lookup policyLookup) (*models.Policy, bool) {
+	policyType := c.Param("policyType")
+	if !isValidPolicyType(policyType) {
+		c.String(http.StatusBadRequest, "Unknown policy type: "+policyType)
+		return nil, false
+	}
+	return lookup(c, policyType)
+}
+
+func policyVersionFromParam(c *gin.Context, policy *models.Policy, param string) (*models.PolicyVersion, bool) {
+	version, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid policy version: "+param)
+		return nil, false
+	}
+	policyVersion, err := models.DB.GetPolicyVersion(policy.ID, uint(version))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.String(http.StatusNotFound, fmt.Sprintf("Could not find version %v of policy", version))
+		} else {
+			c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		}
+		return nil, false
+	}
+	return policyVersion, true
+}
+
+func listPolicyVersions(c *gin.Context, lookup policyLookup) {
+	policy, ok := policyFromRequest(c, lookup)
+	if !ok {
+		return
+	}
+
+	versions, err := models.DB.GetPolicyVersions(policy.ID)
+	if err != nil {
+		c.String(http.StatusInternalServerError, "Unknown error occurred while fetching database")
+		return
+	}
+
+	response := make([]interface{}, 0)
+	for _, v := range versions {
+		response = append(response, v.MapToJsonStruct())
+	}
+	c.JSON(http.StatusOK, gin.H{"current_version": policy.Version, "versions": response})
+}
+
+// policyVersionText returns the text used to diff policy versions, bundles are rendered module by module
+func policyVersionText(v *models.PolicyVersion) (string, error) {
+	if len(v.Bundle) == 0 {
+		return v.Content, nil
+	}
+	bundle, err := dg_policy.LoadBundle(v.Bundle)
+	if err != nil {
+		return "", err
+	}
+	return dg_policy.RenderBundle(bundle), nil
+}
+
+// diffPolicyVersions returns a unified diff between :version and ?base (defaults to the previous version)
+func diffPolicyVersions(c *gin.Context, lookup policyLookup) {
+	policy, ok := policyFromRequest(c, lookup)
+	if !ok {
+		return
+	}
+
+	target, ok := policyVersionFromParam(c, policy, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	baseText := ""
+	baseName := "empty"
+	baseParam := c.Query("base")
+	if baseParam == "" && target.Version > 1 {
+		baseParam = strconv.Itoa(int(target.Version - 1))
+	}
+	if baseParam != "" {
+		base, ok := policyVersionFromParam(c, policy, baseParam)
+		if !ok {
+			return
+		}
+		text, err := policyVersionText(base)
+		if err != nil {
+			log.Printf("could not render policy version %v: %v", base.Version, err)
+			c.String(http.StatusInternalServerError, "Could not render policy version")
+			return
+		}
+		baseText = text
+		baseName = fmt.Sprintf("version %v", base.Version)
+	}
+
+	targetText, err := policyVersionText(target)
+	if err != nil {
+		log.Printf("could not render policy version %v: %v", target.Version, err)
+		c.String(http.StatusInternalServerError, "Could not render policy version")
+		return
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(baseText),
+		B:        difflib.SplitLines(targetText),
+		FromFile: baseName,
+		ToFile:   fmt.Sprintf("version %v", target.Version),
+		Context:  3,
+	})
+	if err != nil {
+		log.Printf("could not diff policy versions: %v", err)
+		c.String(http.StatusInternalServerError, "Could not diff policy versions")
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.String(http.StatusOK, diff)
+}
+
+// rollbackPolicy restores the content of :version as a new version, history is never rewritten
+func rollbackPolicy(c *gin.Context, lookup policyLookup) {
+	policy, ok := policyFromRequest(c, lookup)
+	if !ok {
+		return
+	}
+
+	target, ok := policyVersionFromParam(c, policy, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	message := fmt.Sprintf("rollback to version %v", target.Version)
+	policyVersion, err := models.DB.CreatePolicyVersion(policy, target.Content, target.Bundle, policyAuthor(c), message)
+	if err != nil {
+		log.Printf("Error rolling back policy: %v", err)
+		c.String(http.StatusInternalServerError, "Error rolling back policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "version": policyVersion.Version})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/middleware"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/services"
@@ -11,9 +12,12 @@ import (
 	"github.com/diggerhq/digger/libs/ci"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/redact"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/terraform_utils"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 	"log"
 	"net/http"
@@ -323,6 +327,7 @@ type SetJobStatusRequest struct {
 	Footprint       *terraform_utils.TerraformPlanFootprint `json:"job_plan_footprint"`
 	PrCommentUrl    string                                  `json:"pr_comment_url"`
 	TerraformOutput string                                  `json:"terraform_output""`
+	ProjectOutputs  map[string]string                       `json:"project_outputs"`
 }
 
 func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
@@ -344,6 +349,11 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error binding JSON"})
 		return
 	}
+	// the cli sends the trace context of the job, the request span is part of its trace
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(
+		attribute.String("digger.job_id", jobId),
+		attribute.String("digger.job_status", request.Status),
+	)
 
 	job, err := models.DB.GetDiggerJob(jobId)
 
@@ -353,6 +363,24 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		return
 	}
 
+	if job.Status == orchestrator_scheduler.DiggerJobCancelled {
+		// the job was cancelled after its workflow run started, it must not count as running or finished
+		log.Printf("job %v was cancelled, ignoring its %v report", jobId, request.Status)
+		res, err := job.Batch.MapToJsonStruct(nil)
+		if err != nil {
+			log.Printf("Error getting batch details: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting batch details"})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	var startedAt *time.Time
+	if job.Status == orchestrator_scheduler.DiggerJobStarted {
+		startedAt = &job.StatusUpdatedAt
+	}
+
 	switch request.Status {
 	case "started":
 		job.Status = orchestrator_scheduler.DiggerJobStarted
@@ -380,7 +408,8 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		}
 	case "succeeded":
 		job.Status = orchestrator_scheduler.DiggerJobSucceeded
-		job.TerraformOutput = request.TerraformOutput
+		job.TerraformOutput = redact.String(request.TerraformOutput)
+		job.Outputs = request.ProjectOutputs
 		if request.Footprint != nil {
 			job.PlanFootprint, err = json.Marshal(request.Footprint)
 			if err != nil {
@@ -449,7 +478,8 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 
 	case "failed":
 		job.Status = orchestrator_scheduler.DiggerJobFailed
-		job.TerraformOutput = request.TerraformOutput
+		job.TerraformOutput = redact.String(request.TerraformOutput)
+		job.FailureClass = digger_config.ClassifyFailure(request.TerraformOutput)
 		err := models.DB.UpdateDiggerJob(job)
 		if err != nil {
 			log.Printf("Error updating job status: %v", request.Status)
@@ -457,6 +487,13 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 			return
 		}
 
+		retried, err := services.RetryJobIfRetryable(job, job.FailureClass)
+		if err != nil {
+			log.Printf("Error retrying job: %v", err)
+		} else if retried {
+			log.Printf("job %v queued for attempt %v", jobId, job.Attempt)
+		}
+
 	default:
 		log.Printf("Unexpected status %v", request.Status)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving job"})
@@ -469,6 +506,15 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving job"})
 		return
 	}
+	if request.Status == "succeeded" || request.Status == "failed" {
+		recordJobCompletedAuditEvent(c.GetUint(middleware.ORGANISATION_ID_KEY), job, request.Status)
+		// a failed job may already be queued for a retry
+		finalStatus := orchestrator_scheduler.DiggerJobSucceeded
+		if request.Status == "failed" {
+			finalStatus = orchestrator_scheduler.DiggerJobFailed
+		}
+		metrics.JobFinished(job, finalStatus, startedAt)
+	}
 
 	// get batch ID
 	// check if all jobs have succeeded at this point
@@ -480,6 +526,8 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating batch status"})
 		return
 	}
+	// a finished job frees a concurrency slot and a batch status change moves runs forward
+	models.DB.NotifyScheduler()
 
 	err = AutomergePRforBatchIfEnabled(d.GithubClientProvider, batch)
 	if err != nil {
@@ -488,7 +536,12 @@ func (d DiggerController) SetJobStatusForProject(c *gin.Context) {
 	}
 
 	// return batch summary to client
-	res, err := batch.MapToJsonStruct()
+	queuePositions, err := models.DB.GetQueuePositions(batch.ID)
+	if err != nil {
+		// the summary is still useful without the positions of queued jobs
+		log.Printf("Error getting queue positions of batch: %v", err)
+	}
+	res, err := batch.MapToJsonStruct(queuePositions)
 	if err != nil {
 		log.Printf("Error getting batch details: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting batch details"})
@@ -508,6 +561,39 @@ type CreateProjectRunRequest struct {
 	Output    string    `json:"output"`
 }
 
+// HeartbeatJob records that the cli running a job is alive, jobs without heartbeats are reaped by the
+// scheduler. It responds with a conflict when the job is no longer running, for instance because it was
+// cancelled or reaped.
+func HeartbeatJob(c *gin.Context) {
+	jobId := c.Param("jobId")
+
+	_, exists := c.Get(middleware.ORGANISATION_ID_KEY)
+	if !exists {
+		c.String(http.StatusForbidden, "Not allowed to access this resource")
+		return
+	}
+
+	job, err := models.DB.GetDiggerJob(jobId)
+	if err != nil {
+		log.Printf("Error fetching job: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if job.Status != orchestrator_scheduler.DiggerJobStarted && job.Status != orchestrator_scheduler.DiggerJobTriggered {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("job is %v", job.Status.ToString())})
+		return
+	}
+
+	err = models.DB.RecordDiggerJobHeartbeat(job)
+	if err != nil {
+		log.Printf("Error recording heartbeat of job %v: %v", jobId, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording heartbeat"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
 func CreateRunForProject(c *gin.Context) {
 	repoName := c.Param("repo")
 	projectName := c.Param("projectName")
@@ -185,7 +185,21 @@ func ApproveRun(c *gin.Context) {
 		if err != nil {
 			log.Printf("Could update run: %v", err)
 			c.String(http.StatusInternalServerError, "Could not update approval")
+			return
+		}
+		event := models.AuditEvent{
+			OrganisationID: org.ID,
+			Type:           models.AuditRunApproved,
+			Actor:          run.ApprovalAuthor,
+			Repo:           run.Repo.RepoFullName,
+			ProjectName:    run.ProjectName,
+			CommitSha:      run.CommitId,
+			Outcome:        "approved",
+		}
+		if run.PrNumber != nil {
+			event.PrNumber = *run.PrNumber
 		}
+		models.DB.RecordAuditEvent(event)
 	} else {
 		log.Printf("Run has already been approved")
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/diggerhq/digger/backend/models"
 	"gorm.io/gorm"
+	"strings"
 )
 
 type BackendDBLock struct {
@@ -16,19 +17,40 @@ func (lock BackendDBLock) Lock(lockId int, resource string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("could not create lock record: %v", err)
 	}
+	recordLockAuditEvent(models.AuditLockAcquired, lock.OrgId, lockId, resource)
 	return true, nil
 }
 
 func (lock BackendDBLock) Unlock(resource string) (bool, error) {
+	existing, err := models.DB.GetDiggerLock(resource)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("could not get lock record: %v", err)
+	}
+
 	// delete all locks that match this resource
 	l := models.DiggerLock{}
-	err := models.DB.GormDB.Where("resource=?", resource).Delete(&l).Error
+	err = models.DB.GormDB.Where("resource=?", resource).Delete(&l).Error
 	if err != nil {
 		return false, fmt.Errorf("could not delete all locks: %v", err)
 	}
+	if existing != nil && existing.ID != 0 {
+		recordLockAuditEvent(models.AuditLockReleased, existing.OrganisationID, existing.LockId, resource)
+	}
 	return true, nil
 }
 
+// recordLockAuditEvent adds a lock operation to the audit log, resources are of the form owner/repo#project
+func recordLockAuditEvent(eventType string, orgId uint, lockId int, resource string) {
+	repo, project, _ := strings.Cut(resource, "#")
+	models.DB.RecordAuditEvent(models.AuditEvent{
+		OrganisationID: orgId,
+		Type:           eventType,
+		Repo:           repo,
+		ProjectName:    project,
+		PrNumber:       lockId,
+	})
+}
+
 func (lock BackendDBLock) GetLock(resource string) (*int, error) {
 	theLock, err := models.DB.GetDiggerLock(resource)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -2,7 +2,6 @@ package main
 
 import (
 	"embed"
-	"fmt"
 	"github.com/diggerhq/digger/backend/bootstrap"
 	"github.com/diggerhq/digger/backend/ci_backends"
 	"github.com/diggerhq/digger/backend/config"
@@ -21,6 +20,5 @@ func main() {
 	}
 	r := bootstrap.Bootstrap(templates, ghController)
 	r.GET("/", controllers.Home)
-	port := config.GetPort()
-	r.Run(fmt.Sprintf(":%d", port))
+	bootstrap.Run(r, config.GetPort())
 }
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/diggerhq/digger/backend/models"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var webhookEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_webhook_events_total",
+	Help: "Webhook events received, by VCS and event type.",
+}, []string{"vcs", "event"})
+
+var jobsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_jobs_finished_total",
+	Help: "Jobs that reached a final status, by status, command, repo and project.",
+}, []string{"status", "command", "repo", "project"})
+
+var jobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "digger_job_duration_seconds",
+	Help: "Time from the start of a job to its result, by status, command and repo.",
+	// 10s to about 85m
+	Buckets: prometheus.ExponentialBuckets(10, 2, 10),
+}, []string{"status", "command", "repo"})
+
+var lockContention = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_lock_contention_total",
+	Help: "Attempts to lock a project already locked by another pull request, by repo and project.",
+}, []string{"repo", "project"})
+
+var policyEvaluationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "digger_policy_evaluation_duration_seconds",
+	Help:    "Time taken by the cli to evaluate policies, by policy type and outcome.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"policy_type", "outcome"})
+
+var ciTriggerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_ci_trigger_failures_total",
+	Help: "Failures to trigger the CI run of a job, by repo.",
+}, []string{"repo"})
+
+// Serve serves the prometheus metrics at /metrics on address, apart from the API so that they are only exposed
+// to the monitoring network. Metrics are not served when address is empty.
+func Serve(address string) {
+	if address == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(address, mux)
+	if err != nil {
+		log.Printf("could not serve metrics on %v: %v", address, err)
+	}
+}
+
+// WebhookReceived counts a webhook event received from a VCS
+func WebhookReceived(vcs string, event string) {
+	webhookEvents.WithLabelValues(vcs, event).Inc()
+}
+
+// JobFinished counts a job that reached status, succeeded, failed or cancelled. Its duration is observed when
+// the time it started running is known.
+func JobFinished(job *models.DiggerJob, status orchestrator_scheduler.DiggerJobStatus, startedAt *time.Time) {
+	command, repo := "", ""
+	if job.Batch != nil {
+		command = string(job.Batch.BatchType)
+		repo = job.Batch.RepoFullName
+	}
+	project := ""
+	serializedJob, err := job.MapToJsonStruct()
+	if err == nil {
+		project = serializedJob.ProjectName
+	}
+	jobsFinished.WithLabelValues(status.ToString(), command, repo, project).Inc()
+	if startedAt != nil && !startedAt.IsZero() {
+		jobDuration.WithLabelValues(status.ToString(), command, repo).Observe(time.Since(*startedAt).Seconds())
+	}
+}
+
+// LockFailed counts a failed attempt of prNumber to lock resource, of the form owner/repo#project, as contention
+// when the lock is held by another pull request
+func LockFailed(resource string, prNumber int) {
+	lock, err := models.DB.GetDiggerLock(resource)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("could not get lock %v: %v", resource, err)
+		}
+		return
+	}
+	if lock.LockId == prNumber {
+		return
+	}
+	repo, project, _ := strings.Cut(resource, "#")
+	lockContention.WithLabelValues(repo, project).Inc()
+}
+
+// PolicyEvaluated observes the time taken by the cli to evaluate a policy
+func PolicyEvaluated(policyType string, allowed bool, duration time.Duration) {
+	outcome := "allowed"
+	if !allowed {
+		outcome = "denied"
+	}
+	policyEvaluationDuration.WithLabelValues(policyType, outcome).Observe(duration.Seconds())
+}
+
+// CiTriggerFailed counts a failure to trigger the CI run of a job of repoFullName
+func CiTriggerFailed(repoFullName string) {
+	ciTriggerFailures.WithLabelValues(repoFullName).Inc()
+}
+
+var queueDepthDesc = prometheus.NewDesc("digger_queue_depth", "Jobs queued for run and runs waiting in the run queues.", []string{"queue"}, nil)
+
+// queueCollector reads the depth of the queues from the database when metrics are scraped, so that every
+// replica of the backend reports the same value
+type queueCollector struct{}
+
+func (queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+func (queueCollector) Collect(ch chan<- prometheus.Metric) {
+	jobs, runs, err := models.DB.CountQueuedWork()
+	if err != nil {
+		log.Printf("could not count queued work: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(jobs), "jobs")
+	ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(runs), "runs")
+}
+
+// RegisterQueueCollector adds the depth of the queues to the metrics, it is only needed by one of the processes
+// sharing the database
+func RegisterQueueCollector() {
+	prometheus.MustRegister(queueCollector{})
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"github.com/diggerhq/digger/backend/models"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestJobFinished(t *testing.T) {
+	job := &models.DiggerJob{
+		DiggerJobID:       "job",
+		Batch:             &models.DiggerBatch{BatchType: orchestrator_scheduler.DiggerCommandPlan, RepoFullName: "acme/infra"},
+		SerializedJobSpec: []byte(`{"projectName": "dev"}`),
+	}
+
+	startedAt := time.Now().Add(-time.Minute)
+	JobFinished(job, orchestrator_scheduler.DiggerJobSucceeded, &startedAt)
+	JobFinished(job, orchestrator_scheduler.DiggerJobFailed, nil)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(jobsFinished.WithLabelValues("succeeded", "plan", "acme/infra", "dev")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(jobsFinished.WithLabelValues("failed", "plan", "acme/infra", "dev")))
+	// the duration is only known for the job that reported when it started
+	assert.Equal(t, 1, testutil.CollectAndCount(jobDuration))
+}
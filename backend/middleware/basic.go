@@ -23,6 +23,7 @@ func HttpBasicWebAuth() gin.HandlerFunc {
 			username: password,
 		})(c)
 		c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+		c.Set(IDENTITY_KEY, username)
 		setDefaultOrganisationId(c)
 		c.Next()
 	}
@@ -60,10 +61,12 @@ func HttpBasicApiAuth() gin.HandlerFunc {
 				setDefaultOrganisationId(c)
 				c.Set(ACCESS_LEVEL_KEY, jobToken.Type)
 				c.Set(JOB_TOKEN_KEY, jobToken.Value)
+				c.Set(IDENTITY_KEY, "job token")
 			}
 		} else if token == os.Getenv("BEARER_AUTH_TOKEN") {
 			setDefaultOrganisationId(c)
 			c.Set(ACCESS_LEVEL_KEY, models.AdminPolicyType)
+			c.Set(IDENTITY_KEY, "api token")
 			c.Next()
 		} else {
 			c.String(http.StatusForbidden, "Invalid Bearer token")
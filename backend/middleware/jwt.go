@@ -39,6 +39,11 @@ func SetContextParameters(c *gin.Context, auth services.Auth, token *jwt.Token)
 		}
 
 		c.Set(ORGANISATION_ID_KEY, org.ID)
+		if email, ok := claims["email"].(string); ok && email != "" {
+			c.Set(IDENTITY_KEY, email)
+		} else if sub, ok := claims["sub"].(string); ok {
+			c.Set(IDENTITY_KEY, sub)
+		}
 
 		segment.GetClient()
 		segment.IdentifyClient(strconv.Itoa(int(org.ID)), org.Name, org.Name, org.Name, org.Name, strconv.Itoa(int(org.ID)), "")
@@ -206,6 +211,7 @@ func JWTBearerTokenAuth(auth services.Auth) gin.HandlerFunc {
 			} else {
 				c.Set(ORGANISATION_ID_KEY, jobToken.OrganisationID)
 				c.Set(ACCESS_LEVEL_KEY, jobToken.Type)
+				c.Set(IDENTITY_KEY, "job token")
 			}
 		} else if strings.HasPrefix(token, "t:") {
 			var dbToken models.Token
@@ -225,6 +231,7 @@ func JWTBearerTokenAuth(auth services.Auth) gin.HandlerFunc {
 			}
 			c.Set(ORGANISATION_ID_KEY, dbToken.OrganisationID)
 			c.Set(ACCESS_LEVEL_KEY, dbToken.Type)
+			c.Set(IDENTITY_KEY, "api token")
 		} else {
 			jwtPublicKey := os.Getenv("JWT_PUBLIC_KEY")
 			if jwtPublicKey == "" {
@@ -310,3 +317,6 @@ func CORSMiddleware() gin.HandlerFunc {
 const ORGANISATION_ID_KEY = "organisation_ID"
 const ACCESS_LEVEL_KEY = "access_level"
 const JOB_TOKEN_KEY = "job_token"
+
+// IDENTITY_KEY is who made the request as established by the auth middleware, such as the email of the user
+const IDENTITY_KEY = "identity"
@@ -0,0 +1,26 @@
+-- Modify "policies" table
+ALTER TABLE "public"."policies" ADD COLUMN "version" bigint NULL, ADD COLUMN "bundle" bytea NULL, ADD COLUMN "checksum" text NULL;
+-- Create "policy_versions" table
+CREATE TABLE "public"."policy_versions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "policy_id" bigint NULL,
+  "version" bigint NULL,
+  "content" text NULL,
+  "bundle" bytea NULL,
+  "checksum" text NULL,
+  "author" text NULL,
+  "message" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_policy_versions_policy" FOREIGN KEY ("policy_id") REFERENCES "public"."policies" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_policy_version" to table: "policy_versions"
+CREATE INDEX "idx_policy_version" ON "public"."policy_versions" ("policy_id", "version");
+-- Create index "idx_policy_versions_deleted_at" to table: "policy_versions"
+CREATE INDEX "idx_policy_versions_deleted_at" ON "public"."policy_versions" ("deleted_at");
+-- Record existing policies as their first version
+UPDATE "public"."policies" SET "version" = 1, "checksum" = encode(sha256(convert_to(coalesce("policy", ''), 'UTF8')), 'hex');
+INSERT INTO "public"."policy_versions" ("created_at", "updated_at", "policy_id", "version", "content", "checksum", "author", "message")
+SELECT "updated_at", "updated_at", "id", 1, "policy", "checksum", 'unknown', 'initial version' FROM "public"."policies";
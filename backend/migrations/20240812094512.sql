@@ -0,0 +1,24 @@
+-- Modify "policies" table
+ALTER TABLE "public"."policies" ADD COLUMN "enforcement" text NULL;
+-- Create "policy_decisions" table
+CREATE TABLE "public"."policy_decisions" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "organisation_id" bigint NULL,
+  "policy_type" text NULL,
+  "enforcement" text NULL,
+  "repo" text NULL,
+  "project_name" text NULL,
+  "command" text NULL,
+  "requested_by" text NULL,
+  "allowed" boolean NULL,
+  "violations" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_policy_decisions_organisation" FOREIGN KEY ("organisation_id") REFERENCES "public"."organisations" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION
+);
+-- Create index "idx_policy_decisions_deleted_at" to table: "policy_decisions"
+CREATE INDEX "idx_policy_decisions_deleted_at" ON "public"."policy_decisions" ("deleted_at");
+-- Create index "idx_policy_decisions_organisation_id" to table: "policy_decisions"
+CREATE INDEX "idx_policy_decisions_organisation_id" ON "public"."policy_decisions" ("organisation_id");
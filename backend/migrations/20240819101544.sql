@@ -0,0 +1,2 @@
+-- Modify "projects" table
+ALTER TABLE "public"."projects" ADD COLUMN "environment" text NULL, ADD COLUMN "labels" text NULL, ADD COLUMN "owners" text NULL;
@@ -0,0 +1,6 @@
+-- Modify "digger_runs" table
+ALTER TABLE "public"."digger_runs" ADD COLUMN "status_updated_at" timestamptz NULL;
+-- Modify "organisations" table
+ALTER TABLE "public"."organisations" ADD COLUMN "require_run_approval" boolean NULL;
+-- Modify "projects" table
+ALTER TABLE "public"."projects" ADD COLUMN "require_owner_approval" boolean NULL;
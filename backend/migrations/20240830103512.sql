@@ -0,0 +1,4 @@
+-- Modify "digger_jobs" table
+ALTER TABLE "public"."digger_jobs" ADD COLUMN "claimed_until" timestamptz NULL;
+-- Modify "digger_run_queue_items" table
+ALTER TABLE "public"."digger_run_queue_items" ADD COLUMN "claimed_until" timestamptz NULL;
@@ -0,0 +1,4 @@
+-- Modify "organisations" table
+ALTER TABLE "public"."organisations" ADD COLUMN "max_concurrent_jobs" bigint NULL;
+-- Modify "repos" table
+ALTER TABLE "public"."repos" ADD COLUMN "max_concurrent_jobs" bigint NULL;
@@ -0,0 +1,2 @@
+-- Modify "digger_jobs" table
+ALTER TABLE "public"."digger_jobs" ADD COLUMN "attempt" bigint NULL, ADD COLUMN "next_attempt_at" timestamptz NULL, ADD COLUMN "failure_class" text NULL;
@@ -0,0 +1,14 @@
+-- Create "digger_job_log_chunks" table
+CREATE TABLE "public"."digger_job_log_chunks" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  "deleted_at" timestamptz NULL,
+  "digger_job_id" character varying(50) NULL,
+  "content" text NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_digger_job_log_chunks_deleted_at" to table: "digger_job_log_chunks"
+CREATE INDEX "idx_digger_job_log_chunks_deleted_at" ON "public"."digger_job_log_chunks" ("deleted_at");
+-- Create index "idx_digger_job_log_chunks_job_id" to table: "digger_job_log_chunks"
+CREATE INDEX "idx_digger_job_log_chunks_job_id" ON "public"."digger_job_log_chunks" ("digger_job_id");
@@ -0,0 +1,34 @@
+-- Create "audit_events" table
+CREATE TABLE "public"."audit_events" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NULL,
+  "organisation_id" bigint NULL,
+  "type" text NULL,
+  "actor" text NULL,
+  "repo" text NULL,
+  "project_name" text NULL,
+  "pr_number" bigint NULL,
+  "commit_sha" text NULL,
+  "comment_url" text NULL,
+  "command" text NULL,
+  "batch_id" text NULL,
+  "job_id" text NULL,
+  "outcome" text NULL,
+  "details" text NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_audit_events_created_at" to table: "audit_events"
+CREATE INDEX "idx_audit_events_created_at" ON "public"."audit_events" ("created_at");
+-- Create index "idx_audit_events_organisation_id" to table: "audit_events"
+CREATE INDEX "idx_audit_events_organisation_id" ON "public"."audit_events" ("organisation_id");
+-- Create index "idx_audit_events_type" to table: "audit_events"
+CREATE INDEX "idx_audit_events_type" ON "public"."audit_events" ("type");
+-- Create index "idx_audit_events_repo" to table: "audit_events"
+CREATE INDEX "idx_audit_events_repo" ON "public"."audit_events" ("repo");
+-- Make "audit_events" append only
+CREATE FUNCTION "public"."reject_audit_event_change"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit events are immutable';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER "audit_events_immutable" BEFORE UPDATE OR DELETE ON "public"."audit_events" FOR EACH ROW EXECUTE FUNCTION "public"."reject_audit_event_change"();
@@ -0,0 +1,4 @@
+-- Drop index "idx_policy_version" from table: "policy_versions"
+DROP INDEX "public"."idx_policy_version";
+-- Create index "idx_policy_version" to table: "policy_versions"
+CREATE UNIQUE INDEX "idx_policy_version" ON "public"."policy_versions" ("policy_id", "version");
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"log"
+	"time"
+)
+
+// types of the events of the audit log
+const (
+	AuditCommandRequested = "command_requested"
+	AuditPolicyDecision   = "policy_decision"
+	AuditLockAcquired     = "lock_acquired"
+	AuditLockReleased     = "lock_released"
+	AuditRunApproved      = "run_approved"
+	AuditJobCompleted     = "job_completed"
+)
+
+// AuditEvent is an entry of the audit log of an organisation. Events are only ever created, the table
+// rejects updates and deletes, which is why the model has no UpdatedAt and DeletedAt.
+type AuditEvent struct {
+	ID             uint      `gorm:"primarykey"`
+	CreatedAt      time.Time `gorm:"index"`
+	OrganisationID uint      `gorm:"index"`
+	Type           string    `gorm:"index"`
+	// login of the user who requested the action, empty for actions of digger itself
+	Actor string
+	// full name of the repo, e.g. owner/repo
+	Repo        string `gorm:"index"`
+	ProjectName string
+	PrNumber    int
+	CommitSha   string
+	// url of the comment the action was requested from
+	CommentUrl string
+	Command    string
+	BatchId    string
+	JobId      string
+	// result of the action, e.g. allowed, denied, succeeded or failed
+	Outcome string
+	Details map[string]string `gorm:"serializer:json"`
+}
+
+type AuditEventFilter struct {
+	Type        string
+	Repo        string
+	ProjectName string
+	Actor       string
+	PrNumber    int
+	Since       *time.Time
+	Until       *time.Time
+	// only events with an id lower than BeforeId are returned when set, used to page through the log
+	BeforeId uint
+}
+
+func (e *AuditEvent) MapToJsonStruct() interface{} {
+	return struct {
+		Id          uint              `json:"id"`
+		CreatedAt   time.Time         `json:"created_at"`
+		Type        string            `json:"type"`
+		Actor       string            `json:"actor"`
+		Repo        string            `json:"repo"`
+		ProjectName string            `json:"project_name"`
+		PrNumber    int               `json:"pr_number"`
+		CommitSha   string            `json:"commit_sha"`
+		CommentUrl  string            `json:"comment_url"`
+		Command     string            `json:"command"`
+		BatchId     string            `json:"batch_id"`
+		JobId       string            `json:"job_id"`
+		Outcome     string            `json:"outcome"`
+		Details     map[string]string `json:"details"`
+	}{
+		Id:          e.ID,
+		CreatedAt:   e.CreatedAt,
+		Type:        e.Type,
+		Actor:       e.Actor,
+		Repo:        e.Repo,
+		ProjectName: e.ProjectName,
+		PrNumber:    e.PrNumber,
+		CommitSha:   e.CommitSha,
+		CommentUrl:  e.CommentUrl,
+		Command:     e.Command,
+		BatchId:     e.BatchId,
+		JobId:       e.JobId,
+		Outcome:     e.Outcome,
+		Details:     e.Details,
+	}
+}
+
+// RecordAuditEvent adds an event to the audit log. Failures are logged and not returned so that auditing
+// never fails the action being audited.
+func (db *Database) RecordAuditEvent(event AuditEvent) {
+	err := db.GormDB.Create(&event).Error
+	if err != nil {
+		log.Printf("Failed to record %v audit event for org %v: %v", event.Type, event.OrganisationID, err)
+	}
+}
+
+// GetAuditEvents returns up to limit events of the organisation matching filter, latest first
+func (db *Database) GetAuditEvents(orgId uint, filter AuditEventFilter, limit int) ([]AuditEvent, error) {
+	query := db.GormDB.Where("organisation_id = ?", orgId)
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Repo != "" {
+		query = query.Where("repo = ?", filter.Repo)
+	}
+	if filter.ProjectName != "" {
+		query = query.Where("project_name = ?", filter.ProjectName)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.PrNumber != 0 {
+		query = query.Where("pr_number = ?", filter.PrNumber)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+	if filter.BeforeId != 0 {
+		query = query.Where("id < ?", filter.BeforeId)
+	}
+
+	events := make([]AuditEvent, 0)
+	err := query.Order("id desc").Limit(limit).Find(&events).Error
+	if err != nil {
+		log.Printf("Unknown error occurred while fetching database, %v\n", err)
+		return nil, err
+	}
+	return events, nil
+}
@@ -10,6 +10,10 @@ type Organisation struct {
 	Name           string `gorm:"uniqueIndex:idx_organisation"`
 	ExternalSource string `gorm:"uniqueIndex:idx_external_source"`
 	ExternalId     string `gorm:"uniqueIndex:idx_external_source"`
+	// RequireRunApproval makes every run wait for approval before apply
+	RequireRunApproval bool
+	// maximum number of jobs of the organisation running at the same time, 0 for no limit
+	MaxConcurrentJobs int
 }
 
 type Repo struct {
@@ -22,6 +26,8 @@ type Repo struct {
 	OrganisationID   uint `gorm:"uniqueIndex:idx_org_repo"`
 	Organisation     *Organisation
 	DiggerConfig     string
+	// maximum number of jobs of the repo running at the same time, 0 for no limit
+	MaxConcurrentJobs int
 }
 
 type ProjectRun struct {
@@ -82,6 +88,11 @@ type Project struct {
 	Status            ProjectStatus
 	IsGenerated       bool
 	IsInMainBranch    bool
+	Environment       string
+	Labels            map[string]string `gorm:"serializer:json"`
+	Owners            []string          `gorm:"serializer:json"`
+	// RequireOwnerApproval makes runs of the project wait for approval before apply
+	RequireOwnerApproval bool
 }
 
 func (p *Project) MapToJsonStruct() interface{} {
@@ -91,21 +102,24 @@ func (p *Project) MapToJsonStruct() interface{} {
 		status = lastRun.Status
 	}
 	return struct {
-		Id                    uint   `json:"id"`
-		Name                  string `json:"name"`
-		Directory             string `json:"directory"`
-		OrganisationID        uint   `json:"organisation_id"`
-		OrganisationName      string `json:"organisation_name"`
-		RepoID                uint   `json:"repo_id"`
-		RepoFullName          string `json:"repo_full_name"`
-		RepoName              string `json:"repo_name"`
-		RepoOrg               string `json:"repo_org"`
-		RepoUrl               string `json:"repo_url"`
-		IsInMainBranch        bool   `json:"is_in_main_branch"`
-		IsGenerated           bool   `json:"is_generated"`
-		LastActivityTimestamp string `json:"last_activity_timestamp"`
-		LastActivityAuthor    string `json:"last_activity_author"`
-		LastActivityStatus    string `json:"last_activity_status"`
+		Id                    uint              `json:"id"`
+		Name                  string            `json:"name"`
+		Directory             string            `json:"directory"`
+		OrganisationID        uint              `json:"organisation_id"`
+		OrganisationName      string            `json:"organisation_name"`
+		RepoID                uint              `json:"repo_id"`
+		RepoFullName          string            `json:"repo_full_name"`
+		RepoName              string            `json:"repo_name"`
+		RepoOrg               string            `json:"repo_org"`
+		RepoUrl               string            `json:"repo_url"`
+		IsInMainBranch        bool              `json:"is_in_main_branch"`
+		IsGenerated           bool              `json:"is_generated"`
+		Environment           string            `json:"environment"`
+		Labels                map[string]string `json:"labels"`
+		Owners                []string          `json:"owners"`
+		LastActivityTimestamp string            `json:"last_activity_timestamp"`
+		LastActivityAuthor    string            `json:"last_activity_author"`
+		LastActivityStatus    string            `json:"last_activity_status"`
 	}{
 		Id:                    p.ID,
 		Name:                  p.Name,
@@ -121,6 +135,9 @@ func (p *Project) MapToJsonStruct() interface{} {
 		LastActivityStatus:    string(status),
 		IsGenerated:           p.IsGenerated,
 		IsInMainBranch:        p.IsInMainBranch,
+		Environment:           p.Environment,
+		Labels:                p.Labels,
+		Owners:                p.Owners,
 	}
 
 }
@@ -1,6 +1,13 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 const (
 	POLICY_TYPE_ACCESS = "access"
@@ -8,6 +15,11 @@ const (
 	POLICY_TYPE_DRIFT  = "drift"
 )
 
+const (
+	POLICY_ENFORCEMENT_ENFORCE = "enforce"
+	POLICY_ENFORCEMENT_AUDIT   = "audit"
+)
+
 type Policy struct {
 	gorm.Model
 	Project        *Project
@@ -20,4 +32,110 @@ type Policy struct {
 	OrganisationID uint
 	Repo           *Repo
 	RepoID         *uint
+	// current version of the policy, every update creates a new PolicyVersion
+	Version uint
+	// OPA bundle (tar.gz with rego modules and data.json), set instead of Policy for bundle uploads
+	Bundle   []byte
+	Checksum string
+	// enforce (default) blocks on violations, audit only records the decisions
+	Enforcement string
+}
+
+func (p *Policy) EnforcementMode() string {
+	if p.Enforcement == "" {
+		return POLICY_ENFORCEMENT_ENFORCE
+	}
+	return p.Enforcement
+}
+
+func IsValidPolicyEnforcement(enforcement string) bool {
+	return enforcement == POLICY_ENFORCEMENT_ENFORCE || enforcement == POLICY_ENFORCEMENT_AUDIT
+}
+
+// PolicyDecision is a decision of a policy running in audit mode as reported by the cli,
+// it is used to observe the impact of a policy before enforcing it
+type PolicyDecision struct {
+	gorm.Model
+	OrganisationID uint `gorm:"index"`
+	Organisation   *Organisation
+	PolicyType     string
+	Enforcement    string
+	Repo           string
+	ProjectName    string
+	Command        string
+	RequestedBy    string
+	Allowed        bool
+	// violations separated by new lines
+	Violations string
+}
+
+func (d *PolicyDecision) MapToJsonStruct() interface{} {
+	violations := make([]string, 0)
+	if d.Violations != "" {
+		violations = strings.Split(d.Violations, "\n")
+	}
+	return struct {
+		Id          uint      `json:"id"`
+		PolicyType  string    `json:"policy_type"`
+		Enforcement string    `json:"enforcement"`
+		Repo        string    `json:"repo"`
+		ProjectName string    `json:"project_name"`
+		Command     string    `json:"command"`
+		RequestedBy string    `json:"requested_by"`
+		Allowed     bool      `json:"allowed"`
+		Violations  []string  `json:"violations"`
+		CreatedAt   time.Time `json:"created_at"`
+	}{
+		Id:          d.ID,
+		PolicyType:  d.PolicyType,
+		Enforcement: d.Enforcement,
+		Repo:        d.Repo,
+		ProjectName: d.ProjectName,
+		Command:     d.Command,
+		RequestedBy: d.RequestedBy,
+		Allowed:     d.Allowed,
+		Violations:  violations,
+		CreatedAt:   d.CreatedAt,
+	}
+}
+
+// PolicyVersion is an immutable snapshot of a policy, used for history, diffs and rollbacks
+type PolicyVersion struct {
+	gorm.Model
+	PolicyID uint `gorm:"uniqueIndex:idx_policy_version"`
+	Policy   *Policy
+	Version  uint `gorm:"uniqueIndex:idx_policy_version"`
+	Content  string
+	Bundle   []byte
+	Checksum string
+	Author   string
+	Message  string
+}
+
+func PolicyChecksum(content string, bundle []byte) string {
+	h := sha256.New()
+	if len(bundle) > 0 {
+		h.Write(bundle)
+	} else {
+		h.Write([]byte(content))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (v *PolicyVersion) MapToJsonStruct() interface{} {
+	return struct {
+		Version   uint      `json:"version"`
+		Author    string    `json:"author"`
+		Message   string    `json:"message"`
+		Checksum  string    `json:"checksum"`
+		IsBundle  bool      `json:"is_bundle"`
+		CreatedAt time.Time `json:"created_at"`
+	}{
+		Version:   v.Version,
+		Author:    v.Author,
+		Message:   v.Message,
+		Checksum:  v.Checksum,
+		IsBundle:  len(v.Bundle) > 0,
+		CreatedAt: v.CreatedAt,
+	}
 }
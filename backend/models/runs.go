@@ -19,6 +19,7 @@ const (
 	RunApplying        DiggerRunStatus = "Running Apply"
 	RunSucceeded       DiggerRunStatus = "Succeeded"
 	RunFailed          DiggerRunStatus = "Failed"
+	RunCancelled       DiggerRunStatus = "Cancelled"
 )
 
 type RunType string
@@ -35,6 +36,8 @@ type DiggerRunQueueItem struct {
 	ProjectId   uint
 	Project     *Project
 	time        time.Time
+	// set while a scheduler worker is processing the item, so that other workers skip it
+	ClaimedUntil *time.Time
 }
 
 type DiggerRun struct {
@@ -56,6 +59,8 @@ type DiggerRun struct {
 	IsApproved           bool
 	ApprovalAuthor       string
 	ApprovalDate         time.Time
+	// StatusUpdatedAt is when the run entered its current status, used to time out stuck stages
+	StatusUpdatedAt time.Time
 }
 
 type DiggerRunStage struct {
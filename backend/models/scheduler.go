@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"log"
+	"os"
 	"time"
 )
 
@@ -37,6 +38,8 @@ type DiggerBatch struct {
 	BatchType            orchestrator_scheduler.DiggerCommand
 	// used for module source grouping comments
 	SourceDetails []byte
+	// trace context of the event which created the batch, the spans of its jobs join that trace
+	TraceContext map[string]string `gorm:"serializer:json"`
 }
 
 type DiggerJob struct {
@@ -55,6 +58,27 @@ type DiggerJob struct {
 	WorkflowFile    string
 	WorkflowRunUrl  *string
 	StatusUpdatedAt time.Time
+	// non sensitive terraform outputs after a successful apply, passed to jobs depending on this one
+	Outputs map[string]string `gorm:"serializer:json"`
+	// set while a scheduler worker is triggering the job, so that other workers skip it
+	ClaimedUntil *time.Time
+	// number of times the job was run, it is retried when it fails for a retryable reason
+	Attempt int
+	// a job queued for a retry is triggered after this time
+	NextAttemptAt *time.Time
+	// class of the last failure of the job, empty when it isn't known
+	FailureClass string
+	// last time the cli running the job reported that it is alive
+	HeartbeatAt *time.Time
+}
+
+// Attempts returns the number of times the job ran or is running, jobs created before attempts were
+// counted ran once
+func (j *DiggerJob) Attempts() int {
+	if j.Attempt < 1 {
+		return 1
+	}
+	return j.Attempt
 }
 
 type DiggerJobSummary struct {
@@ -108,9 +132,32 @@ func (j *DiggerJob) MapToJsonStruct() (orchestrator_scheduler.SerializedJob, err
 		ResourcesCreated: j.DiggerJobSummary.ResourcesCreated,
 		ResourcesUpdated: j.DiggerJobSummary.ResourcesUpdated,
 		ResourcesDeleted: j.DiggerJobSummary.ResourcesDeleted,
+		Attempt:          j.Attempts(),
+		LogsUrl:          JobLogsUrl(j.DiggerJobID),
 	}, nil
 }
-func (b *DiggerBatch) MapToJsonStruct() (orchestrator_scheduler.SerializedBatch, error) {
+
+// DiggerJobLogChunk is a chunk of the output of a job streamed by the cli while the job runs, chunks are
+// ordered by ID
+type DiggerJobLogChunk struct {
+	gorm.Model
+	DiggerJobID string `gorm:"size:50;index:idx_digger_job_log_chunks_job_id"`
+	Content     string
+}
+
+// JobLogsUrl returns the page following the output of a job live, empty when the hostname of the backend
+// isn't configured
+func JobLogsUrl(diggerJobId string) string {
+	hostname := os.Getenv("HOSTNAME")
+	if hostname == "" {
+		return ""
+	}
+	return fmt.Sprintf("%v/jobs/%v/logs", hostname, diggerJobId)
+}
+
+// MapToJsonStruct serializes the batch with its jobs, queuePositions are the positions in the queue of the
+// jobs queued for run as returned by GetQueuePositions
+func (b *DiggerBatch) MapToJsonStruct(queuePositions map[string]int) (orchestrator_scheduler.SerializedBatch, error) {
 	res := orchestrator_scheduler.SerializedBatch{
 		ID:           b.ID.String(),
 		PrNumber:     b.PrNumber,
@@ -132,6 +179,7 @@ func (b *DiggerBatch) MapToJsonStruct() (orchestrator_scheduler.SerializedBatch,
 		if err != nil {
 			return res, fmt.Errorf("error mapping job to struct (ID: %v); %v", job.ID, err)
 		}
+		jobJson.QueuePosition = queuePositions[job.DiggerJobID]
 		serializedJobs = append(serializedJobs, jobJson)
 	}
 	res.Jobs = serializedJobs
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -39,7 +42,8 @@ func setupSuiteScheduler(tb testing.TB) (func(tb testing.TB), *Database) {
 	// migrate tables
 	err = gdb.AutoMigrate(&Policy{}, &Organisation{}, &Repo{}, &Project{}, &Token{},
 		&User{}, &ProjectRun{}, &GithubAppInstallation{}, &GithubApp{}, &GithubAppInstallationLink{},
-		&GithubDiggerJobLink{}, &DiggerJob{}, &DiggerJobParentLink{})
+		&GithubDiggerJobLink{}, &DiggerJob{}, &DiggerJobParentLink{}, &DiggerBatch{},
+		&DiggerRunStage{}, &DiggerRun{}, &DiggerRunQueueItem{}, &DiggerJobLogChunk{}, &AuditEvent{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -131,3 +135,374 @@ func TestFindDiggerJobsByParentJobId(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(jobs))
 }
+
+func TestClaimQueuedDiggerJobs(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+
+	queued := make([]*DiggerJob, 0)
+	for i := 0; i < 3; i++ {
+		job, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+		assert.NoError(t, err)
+		job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
+		assert.NoError(t, database.UpdateDiggerJob(job))
+		queued = append(queued, job)
+	}
+	_, err = database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+	assert.NoError(t, err)
+
+	jobs, err := database.ClaimQueuedDiggerJobs(2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, queued[0].ID, jobs[0].ID)
+	assert.Equal(t, queued[1].ID, jobs[1].ID)
+	assert.NotNil(t, jobs[0].Batch)
+
+	// claimed jobs are skipped by other workers
+	jobs2, err := database.ClaimQueuedDiggerJobs(2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(jobs2))
+	assert.Equal(t, queued[2].ID, jobs2[0].ID)
+
+	// released jobs can be claimed again
+	assert.NoError(t, database.ReleaseDiggerJob(&jobs[0]))
+	jobs3, err := database.ClaimQueuedDiggerJobs(2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(jobs3))
+	assert.Equal(t, queued[0].ID, jobs3[0].ID)
+
+	// nothing is left to claim until the claims expire
+	jobs4, err := database.ClaimQueuedDiggerJobs(2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(jobs4))
+}
+
+func TestClaimQueuedDiggerJobsIsFairAcrossBatches(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	queue := func(installationId int64, n int) []*DiggerJob {
+		batch, err := database.CreateDiggerBatch(DiggerVCSGithub, installationId, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+		assert.NoError(t, err)
+		jobs := make([]*DiggerJob, 0)
+		for i := 0; i < n; i++ {
+			job, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+			assert.NoError(t, err)
+			job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
+			assert.NoError(t, database.UpdateDiggerJob(job))
+			jobs = append(jobs, job)
+		}
+		return jobs
+	}
+	large := queue(1, 3)
+	small := queue(2, 1)
+
+	// the limit applies after the jobs are ordered, so the later batch isn't starved by the larger one
+	jobs, err := database.ClaimQueuedDiggerJobs(2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(jobs))
+	assert.Equal(t, large[0].ID, jobs[0].ID)
+	assert.Equal(t, small[0].ID, jobs[1].ID)
+}
+
+func TestClaimRunQueueItem(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	queueItem, err := database.CreateDiggerRunQueueItem(1, 1)
+	assert.NoError(t, err)
+
+	claimed, err := database.ClaimRunQueueItem(queueItem, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = database.ClaimRunQueueItem(queueItem, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	assert.NoError(t, database.ReleaseRunQueueItem(queueItem))
+	claimed, err = database.ClaimRunQueueItem(queueItem, -time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	// the claim already expired
+	claimed, err = database.ClaimRunQueueItem(queueItem, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestGetFirstRunQueueForEveryProjectSkipsDequeuedItems(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	first, err := database.CreateDiggerRunQueueItem(1, 1)
+	assert.NoError(t, err)
+	second, err := database.CreateDiggerRunQueueItem(2, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, database.DequeueRunItem(first))
+	queueItems, err := database.GetFirstRunQueueForEveryProject()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(queueItems))
+	assert.Equal(t, second.ID, queueItems[0].ID)
+}
+
+func TestFairQueueOrder(t *testing.T) {
+	batchA := "a"
+	batchB := "b"
+	now := time.Now()
+	job := func(id uint, batchId *string, minutes int) DiggerJob {
+		j := DiggerJob{BatchID: batchId}
+		j.ID = id
+		j.CreatedAt = now.Add(time.Duration(minutes) * time.Minute)
+		return j
+	}
+	jobs := []DiggerJob{job(1, &batchA, 0), job(2, &batchA, 1), job(3, &batchA, 2), job(4, &batchB, 3), job(5, &batchB, 4)}
+
+	ordered := FairQueueOrder(jobs)
+	ids := make([]uint, len(ordered))
+	for i, j := range ordered {
+		ids[i] = j.ID
+	}
+	assert.Equal(t, []uint{1, 4, 2, 5, 3}, ids)
+}
+
+func TestCountActiveDiggerJobsAndQueuePositions(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	createBatch := func(repoFullName string, statuses ...orchestrator_scheduler.DiggerJobStatus) (*DiggerBatch, []*DiggerJob) {
+		batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", repoFullName, 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+		assert.NoError(t, err)
+		jobs := make([]*DiggerJob, 0)
+		for _, status := range statuses {
+			job, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+			assert.NoError(t, err)
+			job.Status = status
+			assert.NoError(t, database.UpdateDiggerJob(job))
+			jobs = append(jobs, job)
+		}
+		return batch, jobs
+	}
+	batchA, jobsA := createBatch("test/a", orchestrator_scheduler.DiggerJobStarted, orchestrator_scheduler.DiggerJobTriggered, orchestrator_scheduler.DiggerJobQueuedForRun, orchestrator_scheduler.DiggerJobQueuedForRun)
+	batchB, jobsB := createBatch("test/b", orchestrator_scheduler.DiggerJobStarted, orchestrator_scheduler.DiggerJobSucceeded, orchestrator_scheduler.DiggerJobQueuedForRun)
+
+	count, err := database.CountActiveDiggerJobs("digger_batches.id = ?", batchA.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	count, err = database.CountActiveDiggerJobs("digger_batches.github_installation_id = ?", 123)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	count, err = database.CountActiveDiggerJobs("digger_batches.github_installation_id = ? AND digger_batches.repo_full_name = ?", 123, "test/b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	positions, err := database.GetQueuePositions(batchA.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		jobsA[2].DiggerJobID: 1,
+		jobsA[3].DiggerJobID: 3,
+	}, positions)
+	positions, err = database.GetQueuePositions(batchB.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{jobsB[2].DiggerJobID: 2}, positions)
+}
+
+func TestGetActiveDiggerBatchesForPr(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	createBatch := func(prNumber int, status orchestrator_scheduler.DiggerBatchStatus) *DiggerBatch {
+		batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", prNumber, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+		assert.NoError(t, err)
+		batch.Status = status
+		assert.NoError(t, database.UpdateDiggerBatch(batch))
+		return batch
+	}
+	created := createBatch(1, orchestrator_scheduler.BatchJobCreated)
+	started := createBatch(1, orchestrator_scheduler.BatchJobStarted)
+	createBatch(1, orchestrator_scheduler.BatchJobSucceeded)
+	createBatch(1, orchestrator_scheduler.BatchJobSuperseded)
+	createBatch(2, orchestrator_scheduler.BatchJobStarted)
+
+	batches, err := database.GetActiveDiggerBatchesForPr(DiggerVCSGithub, "test/test", 1)
+	assert.NoError(t, err)
+	ids := make([]uuid.UUID, len(batches))
+	for i, b := range batches {
+		ids[i] = b.ID
+	}
+	assert.ElementsMatch(t, []uuid.UUID{created.ID, started.ID}, ids)
+}
+
+func TestClaimQueuedDiggerJobsWaitsForNextAttempt(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+	job, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+	assert.NoError(t, err)
+	nextAttemptAt := time.Now().Add(time.Hour)
+	job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
+	job.Attempt = 2
+	job.NextAttemptAt = &nextAttemptAt
+	assert.NoError(t, database.UpdateDiggerJob(job))
+
+	jobs, err := database.ClaimQueuedDiggerJobs(10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	nextAttemptAt = time.Now().Add(-time.Second)
+	assert.NoError(t, database.UpdateDiggerJob(job))
+	jobs, err = database.ClaimQueuedDiggerJobs(10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, 2, jobs[0].Attempts())
+}
+
+func TestGetDiggerJobsTriggeredBefore(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+	triggered, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+	assert.NoError(t, err)
+	triggered.Status = orchestrator_scheduler.DiggerJobTriggered
+	triggered.StatusUpdatedAt = time.Now()
+	assert.NoError(t, database.UpdateDiggerJob(triggered))
+	started, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+	assert.NoError(t, err)
+	started.Status = orchestrator_scheduler.DiggerJobStarted
+	assert.NoError(t, database.UpdateDiggerJob(started))
+
+	jobs, err := database.GetDiggerJobsTriggeredBefore(time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	jobs, err = database.GetDiggerJobsTriggeredBefore(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, triggered.ID, jobs[0].ID)
+	assert.NotNil(t, jobs[0].Batch)
+}
+
+func TestGetDiggerJobsSilentSince(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandApply, &commentId, 0)
+	assert.NoError(t, err)
+	createStartedJob := func(heartbeatAt *time.Time) *DiggerJob {
+		job, err := database.CreateDiggerJob(batch.ID, []byte{100}, "digger_workflow.yml")
+		assert.NoError(t, err)
+		job.Status = orchestrator_scheduler.DiggerJobStarted
+		job.StatusUpdatedAt = time.Now().Add(-time.Hour)
+		job.HeartbeatAt = heartbeatAt
+		assert.NoError(t, database.UpdateDiggerJob(job))
+		return job
+	}
+	silent := createStartedJob(nil)
+	oldHeartbeat := time.Now().Add(-30 * time.Minute)
+	silentWithHeartbeats := createStartedJob(&oldHeartbeat)
+	alive := createStartedJob(nil)
+	assert.NoError(t, database.RecordDiggerJobHeartbeat(alive))
+
+	jobs, err := database.GetDiggerJobsSilentSince(time.Now().Add(-10 * time.Minute))
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	for _, job := range jobs {
+		ids = append(ids, job.DiggerJobID)
+	}
+	assert.ElementsMatch(t, []string{silent.DiggerJobID, silentWithHeartbeats.DiggerJobID}, ids)
+}
+
+func TestGetDiggerJobLogChunks(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	for i := 0; i < 5; i++ {
+		_, err := database.CreateDiggerJobLogChunk("job", fmt.Sprintf("line %v\n", i))
+		assert.NoError(t, err)
+	}
+	_, err := database.CreateDiggerJobLogChunk("other job", "other\n")
+	assert.NoError(t, err)
+
+	chunks, err := database.GetDiggerJobLogChunks("job", 0, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(chunks))
+	assert.Equal(t, "line 0\n", chunks[0].Content)
+
+	chunks, err = database.GetDiggerJobLogChunks("job", chunks[2].ID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(chunks))
+	assert.Equal(t, "line 3\n", chunks[0].Content)
+	assert.Equal(t, "line 4\n", chunks[1].Content)
+}
+
+func TestJobLogsUrl(t *testing.T) {
+	t.Setenv("HOSTNAME", "")
+	assert.Equal(t, "", JobLogsUrl("123"))
+	t.Setenv("HOSTNAME", "https://digger.example.com")
+	assert.Equal(t, "https://digger.example.com/jobs/123/logs", JobLogsUrl("123"))
+}
+
+func TestGetAuditEvents(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	for i := 0; i < 5; i++ {
+		database.RecordAuditEvent(AuditEvent{OrganisationID: 1, Type: AuditCommandRequested, Repo: "org/repo", PrNumber: i, Actor: "alice"})
+	}
+	database.RecordAuditEvent(AuditEvent{OrganisationID: 1, Type: AuditLockAcquired, Repo: "org/repo", PrNumber: 1})
+	database.RecordAuditEvent(AuditEvent{OrganisationID: 2, Type: AuditCommandRequested, Repo: "other/repo"})
+
+	events, err := database.GetAuditEvents(1, AuditEventFilter{Type: AuditCommandRequested}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, 4, events[0].PrNumber)
+	assert.Equal(t, 2, events[2].PrNumber)
+
+	events, err = database.GetAuditEvents(1, AuditEventFilter{Type: AuditCommandRequested, BeforeId: events[2].ID}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, 1, events[0].PrNumber)
+
+	events, err = database.GetAuditEvents(1, AuditEventFilter{PrNumber: 1}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, AuditLockAcquired, events[0].Type)
+
+	future := time.Now().Add(time.Hour)
+	events, err = database.GetAuditEvents(1, AuditEventFilter{Since: &future}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestCountQueuedWork(t *testing.T) {
+	teardownSuite, database := setupSuiteScheduler(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := database.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+	for i, status := range []orchestrator_scheduler.DiggerJobStatus{orchestrator_scheduler.DiggerJobQueuedForRun, orchestrator_scheduler.DiggerJobQueuedForRun, orchestrator_scheduler.DiggerJobStarted} {
+		job, err := database.CreateDiggerJob(batch.ID, []byte{byte(i)}, "digger_workflow.yml")
+		assert.NoError(t, err)
+		job.Status = status
+		assert.NoError(t, database.UpdateDiggerJob(job))
+	}
+
+	jobs, runs, err := database.CountQueuedWork()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), jobs)
+	assert.Equal(t, int64(0), runs)
+}
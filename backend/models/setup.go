@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"gorm.io/driver/postgres"
 	_ "gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -15,6 +16,9 @@ type Database struct {
 
 var DEFAULT_ORG_NAME = "digger"
 
+// SchedulerChannel is the postgres channel the scheduler workers listen on to react to new work immediately
+const SchedulerChannel = "digger_scheduler"
+
 // var DB *gorm.DB
 var DB *Database
 
@@ -38,3 +42,30 @@ func ConnectDatabase() {
 	}
 
 }
+
+// NotifyScheduler wakes up the scheduler workers, for instance when a job finished and freed a concurrency
+// slot. It is a no-op on databases without LISTEN/NOTIFY, where workers only poll.
+func (db *Database) NotifyScheduler() {
+	if db.GormDB.Dialector.Name() != "postgres" {
+		return
+	}
+	err := db.GormDB.Exec("SELECT pg_notify(?, '')", SchedulerChannel).Error
+	if err != nil {
+		log.Printf("could not notify scheduler: %v", err)
+	}
+}
+
+// WithAdvisoryLock runs fn while holding the postgres advisory lock named key, so that only one backend
+// instance runs it at a time. The lock is released when fn returns. On other databases fn runs unlocked.
+func (db *Database) WithAdvisoryLock(key string, fn func() error) error {
+	if db.GormDB.Dialector.Name() != "postgres" {
+		return fn()
+	}
+	return db.GormDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+		if err != nil {
+			return fmt.Errorf("could not take advisory lock %v: %v", key, err)
+		}
+		return fn()
+	})
+}
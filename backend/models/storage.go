@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/dchest/uniuri"
@@ -10,8 +11,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
 	"net/http"
+	"sort"
 	"time"
 )
 
@@ -265,6 +268,141 @@ func (db *Database) GetPolicyByPolicyId(c *gin.Context, policyId uint, orgIdKey
 	return &policy, true
 }
 
+// CreatePolicyVersion stores a new version of the policy and makes it the current one
+func (db *Database) CreatePolicyVersion(policy *Policy, content string, bundle []byte, author string, message string) (*PolicyVersion, error) {
+	var policyVersion *PolicyVersion
+	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
+		// the policy row is locked until the version is created, so that concurrent updates get consecutive versions
+		var current Policy
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&current, policy.ID).Error
+		if err != nil {
+			return err
+		}
+		policy.Version = current.Version + 1
+		policy.Policy = content
+		policy.Bundle = bundle
+		policy.Checksum = PolicyChecksum(content, bundle)
+		err = tx.Model(policy).Updates(map[string]interface{}{
+			"version":  policy.Version,
+			"policy":   policy.Policy,
+			"bundle":   policy.Bundle,
+			"checksum": policy.Checksum,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		policyVersion = &PolicyVersion{
+			PolicyID: policy.ID,
+			Version:  policy.Version,
+			Content:  content,
+			Bundle:   bundle,
+			Checksum: policy.Checksum,
+			Author:   author,
+			Message:  message,
+		}
+		return tx.Create(policyVersion).Error
+	})
+	if err != nil {
+		log.Printf("Failed to create policy version for policy %v, error: %v\n", policy.ID, err)
+		return nil, err
+	}
+	log.Printf("Policy %v version %v has been created successfully\n", policy.ID, policyVersion.Version)
+	return policyVersion, nil
+}
+
+func (db *Database) UpdatePolicyEnforcement(policy *Policy, enforcement string) error {
+	policy.Enforcement = enforcement
+	err := db.GormDB.Model(policy).Update("enforcement", enforcement).Error
+	if err != nil {
+		log.Printf("Failed to update enforcement of policy %v, error: %v\n", policy.ID, err)
+		return err
+	}
+	log.Printf("Policy %v enforcement set to %v\n", policy.ID, enforcement)
+	return nil
+}
+
+func (db *Database) CreatePolicyDecision(decision *PolicyDecision) error {
+	result := db.GormDB.Create(decision)
+	if result.Error != nil {
+		log.Printf("Failed to create policy decision for org %v, error: %v\n", decision.OrganisationID, result.Error)
+		return result.Error
+	}
+	return nil
+}
+
+// GetPolicyDecisions returns the latest decisions of the organisation, repo, projectName and policyType filters are skipped if empty
+func (db *Database) GetPolicyDecisions(orgId uint, repo string, projectName string, policyType string, limit int) ([]PolicyDecision, error) {
+	var decisions []PolicyDecision
+	query := db.GormDB.Where("organisation_id = ?", orgId)
+	if repo != "" {
+		query = query.Where("repo = ?", repo)
+	}
+	if projectName != "" {
+		query = query.Where("project_name = ?", projectName)
+	}
+	if policyType != "" {
+		query = query.Where("policy_type = ?", policyType)
+	}
+	err := query.Order("created_at desc").Limit(limit).Find(&decisions).Error
+	if err != nil {
+		log.Printf("Unknown error occurred while fetching database, %v\n", err)
+		return nil, err
+	}
+	return decisions, nil
+}
+
+func (db *Database) GetPolicyVersions(policyId uint) ([]PolicyVersion, error) {
+	var versions []PolicyVersion
+	err := db.GormDB.Where("policy_id = ?", policyId).Order("version desc").Find(&versions).Error
+	if err != nil {
+		log.Printf("Unknown error occurred while fetching database, %v\n", err)
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (db *Database) GetPolicyVersion(policyId uint, version uint) (*PolicyVersion, error) {
+	policyVersion := &PolicyVersion{}
+	err := db.GormDB.Where("policy_id = ? AND version = ?", policyId, version).First(policyVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return policyVersion, nil
+}
+
+// GetProjectPolicy returns the policy of policyType set on a project of the organisation, nil if there is none
+func (db *Database) GetProjectPolicy(orgId uint, repoName string, projectName string, policyType string) (*Policy, error) {
+	var policy Policy
+	err := db.GormDB.
+		Joins("INNER JOIN repos ON policies.repo_id = repos.id").
+		Joins("INNER JOIN projects ON policies.project_id = projects.id").
+		Where("policies.organisation_id = ? AND repos.name = ? AND projects.name = ? AND policies.type = ?", orgId, repoName, projectName, policyType).
+		First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetOrganisationPolicy returns the policy of policyType set on the organisation, nil if there is none
+func (db *Database) GetOrganisationPolicy(orgId uint, policyType string) (*Policy, error) {
+	var policy Policy
+	err := db.GormDB.
+		Where("organisation_id = ? AND repo_id IS NULL AND project_id IS NULL AND type = ?", orgId, policyType).
+		First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
 func (db *Database) GetDefaultRepo(c *gin.Context, orgIdKey string) (*Repo, bool) {
 	loggedInOrganisationId, exists := c.Get(orgIdKey)
 	if !exists {
@@ -630,8 +768,27 @@ func (db *Database) UpdateDiggerBatch(batch *DiggerBatch) error {
 	return nil
 }
 
+// UpdateDiggerBatchTraceContext saves the trace context of a batch, only the trace context is written so that a
+// concurrent update of the batch isn't overwritten
+func (db *Database) UpdateDiggerBatchTraceContext(batch *DiggerBatch, traceContext map[string]string) error {
+	batch.TraceContext = traceContext
+	return db.GormDB.Model(&DiggerBatch{ID: batch.ID}).Select("TraceContext").Updates(&DiggerBatch{TraceContext: traceContext}).Error
+}
+
+// GetActiveDiggerBatchesForPr returns the batches of a pull request that haven't finished yet
+func (db *Database) GetActiveDiggerBatchesForPr(vcs DiggerVCSType, repoFullName string, prNumber int) ([]DiggerBatch, error) {
+	batches := make([]DiggerBatch, 0)
+	result := db.GormDB.Where("vcs = ? AND repo_full_name = ? AND pr_number = ? AND status IN ?", vcs, repoFullName, prNumber,
+		[]scheduler.DiggerBatchStatus{scheduler.BatchJobCreated, scheduler.BatchJobStarted}).Find(&batches)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return batches, nil
+}
+
 func (db *Database) UpdateBatchStatus(batch *DiggerBatch) error {
-	if batch.Status == scheduler.BatchJobInvalidated || batch.Status == scheduler.BatchJobFailed || batch.Status == scheduler.BatchJobSucceeded {
+	if batch.Status == scheduler.BatchJobInvalidated || batch.Status == scheduler.BatchJobFailed || batch.Status == scheduler.BatchJobSucceeded ||
+		batch.Status == scheduler.BatchJobSuperseded || batch.Status == scheduler.BatchJobCancelled {
 		return nil
 	}
 	batchId := batch.ID
@@ -767,6 +924,7 @@ func (db *Database) CreateDiggerRunQueueItem(diggeRrunId uint, projectId uint) (
 		return nil, result.Error
 	}
 	log.Printf("DiggerRunQueueItem %v, has been created successfully\n", drq.ID)
+	db.NotifyScheduler()
 	return drq, nil
 }
 
@@ -821,6 +979,8 @@ func (db *Database) GetFirstRunQueueForEveryProject() ([]DiggerRunQueueItem, err
     ROW_NUMBER() OVER (PARTITION BY digger_run_queue_items.project_id ORDER BY digger_run_queue_items.created_at  ASC) AS QueuePosition
   FROM
     digger_run_queue_items
+  WHERE
+    digger_run_queue_items.deleted_at IS NULL
 )
 SELECT
   RankedRuns.digger_run_id ,
@@ -860,6 +1020,228 @@ WHERE
 	return runqueuesWithData, nil
 }
 
+// ClaimRunQueueItem claims a queue item for a worker until lease expires, it returns false if the item
+// is already claimed by another worker. Claims of workers that died expire so that the item is picked up again.
+func (db *Database) ClaimRunQueueItem(queueItem *DiggerRunQueueItem, lease time.Duration) (bool, error) {
+	now := time.Now()
+	claimedUntil := now.Add(lease)
+	result := db.GormDB.Model(&DiggerRunQueueItem{}).
+		Where("id = ? AND (claimed_until IS NULL OR claimed_until < ?)", queueItem.ID, now).
+		Update("claimed_until", claimedUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	queueItem.ClaimedUntil = &claimedUntil
+	return true, nil
+}
+
+func (db *Database) ReleaseRunQueueItem(queueItem *DiggerRunQueueItem) error {
+	queueItem.ClaimedUntil = nil
+	return db.GormDB.Model(&DiggerRunQueueItem{}).Where("id = ?", queueItem.ID).Update("claimed_until", nil).Error
+}
+
+// fairQueue selects the jobs queued for run with their position_in_batch, 1 for the oldest job of every batch.
+// Ordering by position_in_batch then created_at goes round-robin across batches, so that a batch with many
+// jobs doesn't starve the others.
+func fairQueue(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&DiggerJob{}).
+		Select("id, digger_job_id, batch_id, created_at, ROW_NUMBER() OVER (PARTITION BY batch_id ORDER BY created_at) AS position_in_batch").
+		Where("status = ?", scheduler.DiggerJobQueuedForRun)
+}
+
+// ClaimQueuedDiggerJobs claims up to limit jobs queued for run until lease expires, skipping the jobs claimed
+// or being claimed by other workers so that several replicas can consume the queue at the same time. Jobs
+// are claimed in FairQueueOrder across every installation.
+func (db *Database) ClaimQueuedDiggerJobs(limit int, lease time.Duration) ([]DiggerJob, error) {
+	now := time.Now()
+	var ids []uint
+	err := db.GormDB.Transaction(func(tx *gorm.DB) error {
+		queue := fairQueue(tx.Session(&gorm.Session{NewDB: true})).
+			Where("claimed_until IS NULL OR claimed_until < ?", now).
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now)
+		next := tx.Session(&gorm.Session{NewDB: true}).Table("(?) AS queue", queue).
+			Select("id").
+			Order("position_in_batch, created_at").
+			Limit(limit)
+		var jobs []DiggerJob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id IN (?)", next).
+			Find(&jobs).Error
+		if err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		ids = lo.Map(jobs, func(job DiggerJob, _ int) uint {
+			return job.ID
+		})
+		return tx.Model(&DiggerJob{}).Where("id IN ?", ids).Update("claimed_until", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]DiggerJob, 0, len(ids))
+	if len(ids) == 0 {
+		return jobs, nil
+	}
+	err = db.GormDB.Preload("Batch").Where("id IN ?", ids).Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return FairQueueOrder(jobs), nil
+}
+
+// FairQueueOrder orders queued jobs round-robin across their batches: the oldest job of every batch comes
+// before the second oldest job of any batch, so that a batch with many jobs doesn't starve the others
+func FairQueueOrder(jobs []DiggerJob) []DiggerJob {
+	ordered := make([]DiggerJob, len(jobs))
+	copy(ordered, jobs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].CreatedAt.Before(ordered[j].CreatedAt)
+	})
+
+	positionInBatch := make(map[uint]int)
+	jobsPerBatch := make(map[string]int)
+	for _, job := range ordered {
+		batchId := lo.FromPtr(job.BatchID)
+		positionInBatch[job.ID] = jobsPerBatch[batchId]
+		jobsPerBatch[batchId]++
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return positionInBatch[ordered[i].ID] < positionInBatch[ordered[j].ID]
+	})
+	return ordered
+}
+
+// GetQueuePositions returns the position in the queue of the jobs of the batch that are queued for run. The
+// queue is shared by every installation and ordered the way ClaimQueuedDiggerJobs claims jobs.
+func (db *Database) GetQueuePositions(batchId uuid.UUID) (map[string]int, error) {
+	var positions []struct {
+		DiggerJobID string
+		Position    int
+	}
+	queue := fairQueue(db.GormDB.Session(&gorm.Session{NewDB: true}))
+	ranked := db.GormDB.Session(&gorm.Session{NewDB: true}).Table("(?) AS queue", queue).
+		Select("digger_job_id, batch_id, ROW_NUMBER() OVER (ORDER BY position_in_batch, created_at) AS position")
+	err := db.GormDB.Table("(?) AS ranked", ranked).
+		Select("digger_job_id, position").
+		Where("batch_id = ?", batchId).
+		Find(&positions).Error
+	if err != nil {
+		return nil, err
+	}
+	batchPositions := make(map[string]int)
+	for _, p := range positions {
+		batchPositions[p.DiggerJobID] = p.Position
+	}
+	return batchPositions, nil
+}
+
+// CountActiveDiggerJobs counts the triggered and running jobs of the batches matching batchQuery, a condition
+// on the digger_batches table
+func (db *Database) CountActiveDiggerJobs(batchQuery string, args ...interface{}) (int64, error) {
+	var count int64
+	err := db.GormDB.Model(&DiggerJob{}).
+		Joins("INNER JOIN digger_batches ON digger_batches.id = digger_jobs.batch_id").
+		Where("digger_jobs.status IN ?", []scheduler.DiggerJobStatus{scheduler.DiggerJobTriggered, scheduler.DiggerJobStarted}).
+		Where(batchQuery, args...).
+		Count(&count).Error
+	return count, err
+}
+
+// CountQueuedWork returns the number of jobs queued for run and of runs waiting in the run queues
+func (db *Database) CountQueuedWork() (int64, int64, error) {
+	var jobs, runs int64
+	err := db.GormDB.Model(&DiggerJob{}).Where("status = ?", scheduler.DiggerJobQueuedForRun).Count(&jobs).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = db.GormDB.Model(&DiggerRunQueueItem{}).Count(&runs).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return jobs, runs, nil
+}
+
+// ClaimDiggerJob claims a job for a worker until lease expires, it returns false if the job is already
+// claimed by another worker
+func (db *Database) ClaimDiggerJob(job *DiggerJob, lease time.Duration) (bool, error) {
+	now := time.Now()
+	claimedUntil := now.Add(lease)
+	result := db.GormDB.Model(&DiggerJob{}).
+		Where("id = ? AND (claimed_until IS NULL OR claimed_until < ?)", job.ID, now).
+		Update("claimed_until", claimedUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	job.ClaimedUntil = &claimedUntil
+	return true, nil
+}
+
+// GetDiggerJobsTriggeredBefore returns the jobs that were triggered before cutoff and didn't start since
+func (db *Database) GetDiggerJobsTriggeredBefore(cutoff time.Time) ([]DiggerJob, error) {
+	jobs := make([]DiggerJob, 0)
+	err := db.GormDB.Preload("Batch").
+		Where("status = ? AND status_updated_at < ? AND updated_at < ?", scheduler.DiggerJobTriggered, cutoff, cutoff).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GetDiggerJobsSilentSince returns the started jobs that didn't report a status or a heartbeat since cutoff
+func (db *Database) GetDiggerJobsSilentSince(cutoff time.Time) ([]DiggerJob, error) {
+	jobs := make([]DiggerJob, 0)
+	err := db.GormDB.Preload("Batch").
+		Where("status = ? AND status_updated_at < ? AND (heartbeat_at IS NULL OR heartbeat_at < ?)", scheduler.DiggerJobStarted, cutoff, cutoff).
+		Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RecordDiggerJobHeartbeat records that the cli running the job is alive, only the heartbeat is written so
+// that a concurrent status update isn't overwritten
+func (db *Database) RecordDiggerJobHeartbeat(job *DiggerJob) error {
+	now := time.Now()
+	job.HeartbeatAt = &now
+	return db.GormDB.Model(&DiggerJob{}).Where("id = ?", job.ID).Update("heartbeat_at", now).Error
+}
+
+func (db *Database) CreateDiggerJobLogChunk(diggerJobId string, content string) (*DiggerJobLogChunk, error) {
+	chunk := &DiggerJobLogChunk{DiggerJobID: diggerJobId, Content: content}
+	err := db.GormDB.Create(chunk).Error
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// GetDiggerJobLogChunks returns up to limit chunks of the logs of a job following the chunk with id afterId
+func (db *Database) GetDiggerJobLogChunks(diggerJobId string, afterId uint, limit int) ([]DiggerJobLogChunk, error) {
+	chunks := make([]DiggerJobLogChunk, 0)
+	err := db.GormDB.Where("digger_job_id = ? AND id > ?", diggerJobId, afterId).Order("id").Limit(limit).Find(&chunks).Error
+	if err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (db *Database) ReleaseDiggerJob(job *DiggerJob) error {
+	job.ClaimedUntil = nil
+	return db.GormDB.Model(&DiggerJob{}).Where("id = ?", job.ID).Update("claimed_until", nil).Error
+}
+
 func (db *Database) UpdateDiggerJobSummary(diggerJobId string, resourcesCreated uint, resourcesUpdated uint, resourcesDeleted uint) (*DiggerJob, error) {
 	diggerJob, err := db.GetDiggerJob(diggerJobId)
 	if err != nil {
@@ -1126,6 +1508,27 @@ func (db *Database) GetJobToken(tenantId any) (*JobToken, error) {
 	return token, nil
 }
 
+// GetOrganisationIdOfJob returns the organisation the job was created for, the one its job token belongs to,
+// whatever the VCS of its batch. It returns 0 when the organisation can't be resolved.
+func (db *Database) GetOrganisationIdOfJob(job *DiggerJob) (uint, error) {
+	var jobSpec scheduler.JobJson
+	err := json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+	if err != nil {
+		return 0, fmt.Errorf("could not unmarshal job spec: %v", err)
+	}
+	if jobSpec.BackendJobToken == "" {
+		return 0, nil
+	}
+	token, err := db.GetJobToken(jobSpec.BackendJobToken)
+	if err != nil {
+		return 0, fmt.Errorf("could not get job token: %v", err)
+	}
+	if token == nil {
+		return 0, nil
+	}
+	return token.OrganisationID, nil
+}
+
 func (db *Database) DeleteJobTokenArtefacts(jobTokenId uint) error {
 	artefact := JobArtefact{}
 	result := db.GormDB.Where("job_token_id = ?", jobTokenId).Delete(&artefact)
@@ -1171,6 +1574,8 @@ func validateDiggerConfigYaml(configYaml string) (*configuration.DiggerConfig, e
 	return diggerConfig, nil
 }
 
+// UpdateRepoDiggerConfig stores the projects of a config loaded with LoadDiggerConfigYaml, which has defaults
+// and extends applied already
 func (db *Database) UpdateRepoDiggerConfig(orgId any, config configuration.DiggerConfigYaml, repo *Repo, isMainBranch bool) error {
 	log.Printf("UpdateRepoDiggerConfig, repo: %v\n", repo)
 
@@ -1201,17 +1606,19 @@ func (db *Database) UpdateRepoDiggerConfig(orgId any, config configuration.Digge
 				return fmt.Errorf("error retriving project by name: %v", err)
 			}
 			if p == nil {
-				_, err := db.CreateProject(projectName, org, repo, dc.Generated, isMainBranch)
+				p, err = db.CreateProject(projectName, org, repo, dc.Generated, isMainBranch)
 				if err != nil {
 					return fmt.Errorf("could not create project: %v", err)
 				}
-			} else {
-				if isMainBranch == true {
-					p.IsInMainBranch = isMainBranch
-				}
-				p.IsGenerated = dc.Generated
-				db.UpdateProject(p)
+			} else if isMainBranch == true {
+				p.IsInMainBranch = isMainBranch
 			}
+			p.IsGenerated = dc.Generated
+			p.Environment = dc.Environment
+			p.Labels = dc.Labels
+			p.Owners = dc.Owners
+			p.RequireOwnerApproval = dc.OwnerApprovalRequired()
+			db.UpdateProject(p)
 		}
 		return nil
 	})
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"github.com/diggerhq/digger/libs/scheduler"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -37,7 +38,7 @@ func setupSuite(tb testing.TB) (func(tb testing.TB), *Database, *Organisation) {
 	// migrate tables
 	err = gdb.AutoMigrate(&Policy{}, &Organisation{}, &Repo{}, &Project{}, &Token{},
 		&User{}, &ProjectRun{}, &GithubAppInstallation{}, &GithubApp{}, &GithubAppInstallationLink{},
-		&GithubDiggerJobLink{}, &DiggerJob{}, &DiggerJobParentLink{})
+		&GithubDiggerJobLink{}, &DiggerJob{}, &DiggerJobParentLink{}, &PolicyVersion{}, &PolicyDecision{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -165,3 +166,147 @@ func TestGetDiggerJobsForBatchPreloadsSummary(t *testing.T) {
 	assert.Equal(t, jobssss[0].DiggerJobSummary.ResourcesUpdated, resourcesUpdated)
 	assert.Equal(t, jobssss[0].DiggerJobSummary.ResourcesDeleted, resourcesDeleted)
 }
+
+func TestCreatePolicyVersionKeepsHistory(t *testing.T) {
+	teardownSuite, _, org := setupSuite(t)
+	defer teardownSuite(t)
+
+	policy := &Policy{OrganisationID: org.ID, Type: POLICY_TYPE_ACCESS}
+	err := DB.GormDB.Create(policy).Error
+	assert.NoError(t, err)
+
+	v1, err := DB.CreatePolicyVersion(policy, "package digger\n", nil, "alice", "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), v1.Version)
+
+	v2, err := DB.CreatePolicyVersion(policy, "package digger\ndefault allow = false\n", nil, "bob", "lock down")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), v2.Version)
+	assert.Equal(t, uint(2), policy.Version)
+	assert.Equal(t, PolicyChecksum("package digger\ndefault allow = false\n", nil), policy.Checksum)
+
+	versions, err := DB.GetPolicyVersions(policy.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(versions))
+	assert.Equal(t, "bob", versions[0].Author)
+	assert.Equal(t, "alice", versions[1].Author)
+
+	first, err := DB.GetPolicyVersion(policy.ID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "package digger\n", first.Content)
+
+	var stored Policy
+	err = DB.GormDB.First(&stored, policy.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, "package digger\ndefault allow = false\n", stored.Policy)
+}
+
+func TestCreatePolicyVersionFromStalePolicy(t *testing.T) {
+	teardownSuite, _, org := setupSuite(t)
+	defer teardownSuite(t)
+
+	policy := &Policy{OrganisationID: org.ID, Type: POLICY_TYPE_ACCESS}
+	err := DB.GormDB.Create(policy).Error
+	assert.NoError(t, err)
+	// two requests loaded the policy before either created a version
+	stale := *policy
+
+	_, err = DB.CreatePolicyVersion(policy, "package digger\n", nil, "alice", "")
+	assert.NoError(t, err)
+	v2, err := DB.CreatePolicyVersion(&stale, "package digger\ndefault allow = false\n", nil, "bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), v2.Version)
+
+	err = DB.GormDB.Create(&PolicyVersion{PolicyID: policy.ID, Version: 2}).Error
+	assert.Error(t, err)
+}
+
+func TestPolicyEnforcementAndDecisions(t *testing.T) {
+	teardownSuite, _, org := setupSuite(t)
+	defer teardownSuite(t)
+
+	policy := &Policy{OrganisationID: org.ID, Type: POLICY_TYPE_PLAN}
+	err := DB.GormDB.Create(policy).Error
+	assert.NoError(t, err)
+	assert.Equal(t, POLICY_ENFORCEMENT_ENFORCE, policy.EnforcementMode())
+
+	err = DB.UpdatePolicyEnforcement(policy, POLICY_ENFORCEMENT_AUDIT)
+	assert.NoError(t, err)
+	var stored Policy
+	err = DB.GormDB.First(&stored, policy.ID).Error
+	assert.NoError(t, err)
+	assert.Equal(t, POLICY_ENFORCEMENT_AUDIT, stored.EnforcementMode())
+
+	for _, projectName := range []string{"dev", "prod"} {
+		err = DB.CreatePolicyDecision(&PolicyDecision{
+			OrganisationID: org.ID,
+			PolicyType:     POLICY_TYPE_PLAN,
+			Enforcement:    POLICY_ENFORCEMENT_AUDIT,
+			Repo:           "diggerhq-digger",
+			ProjectName:    projectName,
+			Allowed:        false,
+			Violations:     "aws_instance.web is not allowed",
+		})
+		assert.NoError(t, err)
+	}
+
+	decisions, err := DB.GetPolicyDecisions(org.ID, "diggerhq-digger", "prod", POLICY_TYPE_PLAN, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(decisions))
+	assert.Equal(t, "prod", decisions[0].ProjectName)
+
+	decisions, err = DB.GetPolicyDecisions(org.ID, "", "", POLICY_TYPE_PLAN, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(decisions))
+}
+
+func TestUpdateDiggerBatchTraceContext(t *testing.T) {
+	teardownSuite, _, _ := setupSuite(t)
+	defer teardownSuite(t)
+
+	commentId := int64(123)
+	batch, err := DB.CreateDiggerBatch(DiggerVCSGithub, 123, "test", "test", "test/test", 123, "", "main", scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+
+	// the batch was updated since it was read
+	stored, err := DB.GetDiggerBatch(&batch.ID)
+	assert.NoError(t, err)
+	stored.Status = scheduler.BatchJobSucceeded
+	assert.NoError(t, DB.UpdateDiggerBatch(stored))
+
+	traceContext := map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+	assert.NoError(t, DB.UpdateDiggerBatchTraceContext(batch, traceContext))
+
+	stored, err = DB.GetDiggerBatch(&batch.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, traceContext, stored.TraceContext)
+	assert.Equal(t, scheduler.BatchJobSucceeded, stored.Status)
+}
+
+func TestGetOrganisationIdOfJob(t *testing.T) {
+	teardownSuite, _, org := setupSuite(t)
+	defer teardownSuite(t)
+	assert.NoError(t, DB.GormDB.AutoMigrate(&JobToken{}, &DiggerBatch{}))
+
+	// the organisation is resolved from the job token for batches of every VCS
+	batch, err := DB.CreateDiggerBatch(DiggerVCSGitlab, 0, "test", "test", "test/test", 123, "", "main", scheduler.DiggerCommandPlan, nil, 42)
+	assert.NoError(t, err)
+	jobToken, err := DB.CreateDiggerJobToken(org.ID)
+	assert.NoError(t, err)
+	jobSpec, err := json.Marshal(scheduler.JobJson{ProjectName: "prod", BackendJobToken: jobToken.Value})
+	assert.NoError(t, err)
+	job, err := DB.CreateDiggerJob(batch.ID, jobSpec, "workflow_file.yml")
+	assert.NoError(t, err)
+
+	orgId, err := DB.GetOrganisationIdOfJob(job)
+	assert.NoError(t, err)
+	assert.Equal(t, org.ID, orgId)
+
+	jobSpec, err = json.Marshal(scheduler.JobJson{ProjectName: "prod", BackendJobToken: "cli:unknown"})
+	assert.NoError(t, err)
+	job, err = DB.CreateDiggerJob(batch.ID, jobSpec, "workflow_file.yml")
+	assert.NoError(t, err)
+	orgId, err = DB.GetOrganisationIdOfJob(job)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), orgId)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/ci_backends"
+	"github.com/diggerhq/digger/backend/metrics"
+	"github.com/diggerhq/digger/backend/models"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"log"
+)
+
+// CancelBatch moves a batch to status, superseded or cancelled, cancels its jobs that didn't finish and the
+// CI runs of the ones already triggered. It returns the number of jobs cancelled.
+func CancelBatch(ciBackend ci_backends.CiBackend, batch *models.DiggerBatch, status orchestrator_scheduler.DiggerBatchStatus) (int, error) {
+	jobs, err := models.DB.GetDiggerJobsForBatchWithStatus(batch.ID, []orchestrator_scheduler.DiggerJobStatus{
+		orchestrator_scheduler.DiggerJobCreated,
+		orchestrator_scheduler.DiggerJobQueuedForRun,
+		orchestrator_scheduler.DiggerJobTriggered,
+		orchestrator_scheduler.DiggerJobStarted,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not get jobs of batch %v: %v", batch.ID, err)
+	}
+
+	batch.Status = status
+	err = models.DB.UpdateDiggerBatch(batch)
+	if err != nil {
+		return 0, fmt.Errorf("could not update batch %v: %v", batch.ID, err)
+	}
+
+	for _, job := range jobs {
+		if job.Status == orchestrator_scheduler.DiggerJobTriggered || job.Status == orchestrator_scheduler.DiggerJobStarted {
+			err := CancelJobWorkflow(ciBackend, job)
+			if err != nil {
+				// the job is still marked as cancelled so that nothing depending on it gets triggered
+				log.Printf("could not cancel workflow of job %v: %v", job.DiggerJobID, err)
+			}
+		}
+		job.Status = orchestrator_scheduler.DiggerJobCancelled
+		err = models.DB.UpdateDiggerJob(&job)
+		if err != nil {
+			return 0, fmt.Errorf("could not update job %v: %v", job.DiggerJobID, err)
+		}
+		metrics.JobFinished(&job, job.Status, nil)
+	}
+	log.Printf("batch %v moved to status %v, %v jobs cancelled", batch.ID, status, len(jobs))
+
+	// cancelled jobs free their concurrency slots
+	models.DB.NotifyScheduler()
+	return len(jobs), nil
+}
+
+// CancelJobWorkflow cancels the CI run of a triggered or running job
+func CancelJobWorkflow(ciBackend ci_backends.CiBackend, job models.DiggerJob) error {
+	spec, err := GetSpecFromJob(job)
+	if err != nil {
+		return fmt.Errorf("could not get spec: %v", err)
+	}
+	workflowRunUrl := "#"
+	if job.WorkflowRunUrl != nil {
+		workflowRunUrl = *job.WorkflowRunUrl
+	}
+	return ciBackend.CancelWorkflow(*spec, workflowRunUrl)
+}
+
+// CancelActiveBatchesForPr moves every batch of the pull request that didn't finish to status. When
+// onlyPlans is set apply batches are left running, so that an apply isn't interrupted half way.
+func CancelActiveBatchesForPr(ciBackend ci_backends.CiBackend, vcs models.DiggerVCSType, repoFullName string, prNumber int, status orchestrator_scheduler.DiggerBatchStatus, onlyPlans bool) (int, error) {
+	batches, err := models.DB.GetActiveDiggerBatchesForPr(vcs, repoFullName, prNumber)
+	if err != nil {
+		return 0, fmt.Errorf("could not get batches of pull request %v: %v", prNumber, err)
+	}
+	cancelled := 0
+	for _, batch := range batches {
+		if onlyPlans && batch.BatchType != orchestrator_scheduler.DiggerCommandPlan {
+			continue
+		}
+		n, err := CancelBatch(ciBackend, &batch, status)
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled += n
+	}
+	return cancelled, nil
+}
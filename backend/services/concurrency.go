@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/models"
+	"log"
+	"strings"
+)
+
+// concurrencyLimit caps the number of triggered and running jobs of the batches matching query
+type concurrencyLimit struct {
+	name  string
+	limit int
+	query string
+	args  []interface{}
+}
+
+// ciBackendName returns the name of the CI backend running the jobs of batches of a vcs, used in the config
+// key of its concurrency limit
+func ciBackendName(vcs models.DiggerVCSType) string {
+	switch vcs {
+	case models.DiggerVCSGitlab:
+		return "gitlab_ci"
+	default:
+		return "github_actions"
+	}
+}
+
+// batchOrganisationAndRepo returns the organisation and repo a batch belongs to, nil when they can't be
+// found such as for batches not coming from a github app installation
+func batchOrganisationAndRepo(batch *models.DiggerBatch) (*models.Organisation, *models.Repo, error) {
+	if batch.VCS != models.DiggerVCSGithub {
+		return nil, nil, nil
+	}
+	link, err := models.DB.GetGithubInstallationLinkForInstallationId(batch.GithubInstallationId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get installation link: %v", err)
+	}
+	if link.ID == 0 {
+		return nil, nil, nil
+	}
+	org, err := models.DB.GetOrganisationById(link.OrganisationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := models.DB.GetRepo(link.OrganisationId, strings.ReplaceAll(batch.RepoFullName, "/", "-"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not get repo: %v", err)
+	}
+	return org, repo, nil
+}
+
+// concurrencyLimits returns the limits a job of the batch has to fit in to be triggered: per batch, per CI
+// backend, per organisation and per repo
+func concurrencyLimits(batch *models.DiggerBatch) ([]concurrencyLimit, error) {
+	backend := ciBackendName(batch.VCS)
+	limits := []concurrencyLimit{
+		{name: "batch", limit: config.DiggerConfig.GetInt("max_concurrency_per_batch"), query: "digger_batches.id = ?", args: []interface{}{batch.ID}},
+		{name: "ci backend " + backend, limit: config.DiggerConfig.GetInt("max_concurrency_" + backend), query: "digger_batches.vcs = ?", args: []interface{}{batch.VCS}},
+	}
+
+	org, repo, err := batchOrganisationAndRepo(batch)
+	if err != nil {
+		return nil, err
+	}
+	if org != nil {
+		// the organisation may have several installations, the jobs of all of them count towards its limit
+		limits = append(limits, concurrencyLimit{name: "organisation", limit: org.MaxConcurrentJobs,
+			query: "digger_batches.vcs = ? AND digger_batches.github_installation_id IN (SELECT github_installation_id FROM github_app_installation_links WHERE organisation_id = ? AND status = ? AND deleted_at IS NULL)",
+			args:  []interface{}{models.DiggerVCSGithub, org.ID, models.GithubAppInstallationLinkActive}})
+	}
+	if repo != nil {
+		limits = append(limits, concurrencyLimit{name: "repo", limit: repo.MaxConcurrentJobs, query: "digger_batches.github_installation_id = ? AND digger_batches.repo_full_name = ?", args: []interface{}{batch.GithubInstallationId, batch.RepoFullName}})
+	}
+	return limits, nil
+}
+
+// reachedConcurrencyLimit returns the name of a limit that doesn't allow another job of the batch to be
+// triggered, or an empty string if the job can be triggered now. A limit of 0 means no limit.
+func reachedConcurrencyLimit(batch *models.DiggerBatch) (string, error) {
+	limits, err := concurrencyLimits(batch)
+	if err != nil {
+		return "", err
+	}
+	for _, l := range limits {
+		if l.limit <= 0 {
+			continue
+		}
+		active, err := models.DB.CountActiveDiggerJobs(l.query, l.args...)
+		if err != nil {
+			return "", fmt.Errorf("could not count active jobs: %v", err)
+		}
+		if active >= int64(l.limit) {
+			log.Printf("max concurrency per %v reached: %v of %v jobs running", l.name, active, l.limit)
+			return l.name, nil
+		}
+	}
+	return "", nil
+}
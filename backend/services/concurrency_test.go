@@ -0,0 +1,52 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/diggerhq/digger/backend/models"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestReachedOrganisationConcurrencyLimit(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = gdb.AutoMigrate(&models.Organisation{}, &models.Repo{}, &models.GithubAppInstallationLink{},
+		&models.DiggerBatch{}, &models.DiggerJob{}, &models.DiggerJobSummary{})
+	assert.NoError(t, err)
+	defer func(previous *models.Database) { models.DB = previous }(models.DB)
+	models.DB = &models.Database{GormDB: gdb}
+
+	acme, err := models.DB.CreateOrganisation("acme", "test", "11111111-1111-1111-1111-111111111111")
+	assert.NoError(t, err)
+	assert.NoError(t, gdb.Model(acme).Update("max_concurrent_jobs", 1).Error)
+	other, err := models.DB.CreateOrganisation("other", "test", "22222222-2222-2222-2222-222222222222")
+	assert.NoError(t, err)
+	// acme has two installations, the jobs of both count towards its limit
+	for installationId, org := range map[int64]*models.Organisation{1: acme, 2: acme, 3: other} {
+		assert.NoError(t, gdb.Create(&models.GithubAppInstallationLink{GithubInstallationId: installationId, OrganisationId: org.ID, Status: models.GithubAppInstallationLinkActive}).Error)
+	}
+
+	running, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 2, "acme", "network", "acme/network", 1, "", "main", orchestrator_scheduler.DiggerCommandPlan, nil, 0)
+	assert.NoError(t, err)
+	jobSpec, err := json.Marshal(orchestrator_scheduler.JobJson{ProjectName: "network"})
+	assert.NoError(t, err)
+	job, err := models.DB.CreateDiggerJob(running.ID, jobSpec, "digger_workflow.yml")
+	assert.NoError(t, err)
+	job.Status = orchestrator_scheduler.DiggerJobStarted
+	assert.NoError(t, models.DB.UpdateDiggerJob(job))
+
+	batch, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 1, "acme", "infra", "acme/infra", 7, "", "main", orchestrator_scheduler.DiggerCommandPlan, nil, 0)
+	assert.NoError(t, err)
+	limit, err := reachedConcurrencyLimit(batch)
+	assert.NoError(t, err)
+	assert.Equal(t, "organisation", limit)
+
+	otherBatch, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 3, "other", "infra", "other/infra", 7, "", "main", orchestrator_scheduler.DiggerCommandPlan, nil, 0)
+	assert.NoError(t, err)
+	limit, err = reachedConcurrencyLimit(otherBatch)
+	assert.NoError(t, err)
+	assert.Equal(t, "", limit)
+}
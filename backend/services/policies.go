@@ -0,0 +1,125 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/ci"
+	dg_policy "github.com/diggerhq/digger/libs/policy"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"log"
+)
+
+// DBPolicyProvider reads the policies of an organisation from the database, for the commands that the
+// backend handles itself instead of the cli such as digger cancel. Policies are looked up like the cli
+// does: the project policy first, then the organisation policy.
+type DBPolicyProvider struct {
+	OrganisationID uint
+}
+
+func (p DBPolicyProvider) getPolicy(policyType string, organisation string, repo string, projectName string) (*models.Policy, error) {
+	policy, err := models.DB.GetProjectPolicy(p.OrganisationID, fmt.Sprintf("%v-%v", organisation, repo), projectName, policyType)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %v policy of project %v: %v", policyType, projectName, err)
+	}
+	if policy != nil && (policy.Policy != "" || len(policy.Bundle) > 0) {
+		return policy, nil
+	}
+	policy, err = models.DB.GetOrganisationPolicy(p.OrganisationID, policyType)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %v policy of organisation: %v", policyType, err)
+	}
+	return policy, nil
+}
+
+func policyContent(policy *models.Policy) string {
+	if len(policy.Bundle) > 0 {
+		return string(policy.Bundle)
+	}
+	return policy.Policy
+}
+
+func (p DBPolicyProvider) GetAccessPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	policy, err := p.getPolicy(models.POLICY_TYPE_ACCESS, organisation, repo, projectName)
+	if err != nil {
+		return "", err
+	}
+	if policy == nil {
+		return dg_policy.DefaultAccessPolicy, nil
+	}
+	return policyContent(policy), nil
+}
+
+func (p DBPolicyProvider) GetPlanPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	policy, err := p.getPolicy(models.POLICY_TYPE_PLAN, organisation, repo, projectName)
+	if err != nil || policy == nil {
+		return "", err
+	}
+	return policyContent(policy), nil
+}
+
+func (p DBPolicyProvider) GetDriftPolicy() (string, error) {
+	policy, err := models.DB.GetOrganisationPolicy(p.OrganisationID, models.POLICY_TYPE_DRIFT)
+	if err != nil || policy == nil {
+		return "", err
+	}
+	return policyContent(policy), nil
+}
+
+func (p DBPolicyProvider) GetOrganisation() string {
+	return ""
+}
+
+func (p DBPolicyProvider) GetEnforcement(policyType string, organisation string, repo string, projectName string) string {
+	policy, err := p.getPolicy(policyType, organisation, repo, projectName)
+	if err != nil {
+		log.Printf("could not get enforcement mode: %v", err)
+		return models.POLICY_ENFORCEMENT_ENFORCE
+	}
+	if policy == nil {
+		return models.POLICY_ENFORCEMENT_ENFORCE
+	}
+	return policy.EnforcementMode()
+}
+
+// CancelDeniedProjects returns the projects with jobs left to cancel on the pull request whose access policy
+// doesn't allow requestedBy to run digger cancel
+func CancelDeniedProjects(orgId uint, orgService ci.OrgService, prService ci.PullRequestService, vcs models.DiggerVCSType, repoFullName string, repoOwner string, repoName string, prNumber int, requestedBy string) ([]string, error) {
+	batches, err := models.DB.GetActiveDiggerBatchesForPr(vcs, repoFullName, prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not get batches of pull request %v: %v", prNumber, err)
+	}
+	checker := dg_policy.DiggerPolicyChecker{PolicyProvider: DBPolicyProvider{OrganisationID: orgId}}
+	checked := make(map[string]bool)
+	denied := make([]string, 0)
+	for _, batch := range batches {
+		jobs, err := models.DB.GetDiggerJobsForBatch(batch.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get jobs of batch %v: %v", batch.ID, err)
+		}
+		for _, job := range jobs {
+			var jobSpec orchestrator_scheduler.JobJson
+			err := json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+			if err != nil {
+				return nil, fmt.Errorf("could not unmarshal spec of job %v: %v", job.DiggerJobID, err)
+			}
+			if checked[jobSpec.ProjectName] {
+				continue
+			}
+			checked[jobSpec.ProjectName] = true
+			projectMetadata := dg_policy.ProjectMetadata{
+				Environment: jobSpec.ProjectEnvironment,
+				Labels:      jobSpec.ProjectLabels,
+				Owners:      jobSpec.ProjectOwners,
+			}
+			allowed, err := checker.CheckAccessPolicy(orgService, &prService, repoOwner, repoName, jobSpec.ProjectName, jobSpec.ProjectDir, projectMetadata, "digger "+string(orchestrator_scheduler.DiggerCommandCancel), &prNumber, requestedBy, []string{})
+			if err != nil {
+				return nil, fmt.Errorf("could not check access policy of project %v: %v", jobSpec.ProjectName, err)
+			}
+			if !allowed {
+				denied = append(denied, jobSpec.ProjectName)
+			}
+		}
+	}
+	return denied, nil
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/ci"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestCancelDeniedProjects(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = gdb.AutoMigrate(&models.Policy{}, &models.Organisation{}, &models.Repo{}, &models.Project{},
+		&models.DiggerBatch{}, &models.DiggerJob{}, &models.DiggerJobSummary{})
+	assert.NoError(t, err)
+	defer func(previous *models.Database) { models.DB = previous }(models.DB)
+	models.DB = &models.Database{GormDB: gdb}
+
+	org, err := models.DB.CreateOrganisation("org", "test", "11111111-1111-1111-1111-111111111111")
+	assert.NoError(t, err)
+	repo, err := models.DB.CreateRepo("acme-infra", "acme/infra", "acme", "infra", "", org, "")
+	assert.NoError(t, err)
+	project, err := models.DB.CreateProject("prod", org, repo, false, true)
+	assert.NoError(t, err)
+
+	// the organisation policy allows everyone, the policy of prod only allows alice to cancel
+	assert.NoError(t, gdb.Create(&models.Policy{OrganisationID: org.ID, Type: models.POLICY_TYPE_ACCESS, Policy: "package digger\ndefault allow = true\n"}).Error)
+	assert.NoError(t, gdb.Create(&models.Policy{OrganisationID: org.ID, RepoID: &repo.ID, ProjectID: &project.ID, Type: models.POLICY_TYPE_ACCESS,
+		Policy: "package digger\ndefault allow = false\nallow {\n    input.user == \"alice\"\n    input.action == \"digger cancel\"\n}\n"}).Error)
+
+	commentId := int64(1)
+	batch, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 1, "acme", "infra", "acme/infra", 7, "", "main", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+	assert.NoError(t, err)
+	for _, projectName := range []string{"prod", "dev"} {
+		jobSpec, err := json.Marshal(orchestrator_scheduler.JobJson{ProjectName: projectName})
+		assert.NoError(t, err)
+		_, err = models.DB.CreateDiggerJob(batch.ID, jobSpec, "digger_workflow.yml")
+		assert.NoError(t, err)
+	}
+
+	prService := ci.MockPullRequestManager{}
+	denied, err := CancelDeniedProjects(org.ID, prService, prService, models.DiggerVCSGithub, "acme/infra", "acme", "infra", 7, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, denied)
+
+	denied, err = CancelDeniedProjects(org.ID, prService, prService, models.DiggerVCSGithub, "acme/infra", "acme", "infra", 7, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, denied)
+}
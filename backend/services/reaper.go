@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/metrics"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/digger_config"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"log"
+	"time"
+)
+
+// FailLostJob handles a job whose CI run is gone without reporting a result. The job is retried when its
+// retry policy allows runner_lost failures, otherwise it is failed and the PR lock of its project is released
+// so that other pull requests aren't blocked by a job that will never finish.
+func FailLostJob(job *models.DiggerJob) error {
+	retried, err := RetryJobIfRetryable(job, digger_config.FailureClassRunnerLost)
+	if err != nil {
+		log.Printf("could not retry job %v: %v", job.DiggerJobID, err)
+	}
+	if !retried {
+		job.Status = orchestrator_scheduler.DiggerJobFailed
+		job.FailureClass = digger_config.FailureClassRunnerLost
+		job.StatusUpdatedAt = time.Now()
+		err = models.DB.UpdateDiggerJob(job)
+		if err != nil {
+			return fmt.Errorf("could not fail job %v: %v", job.DiggerJobID, err)
+		}
+		metrics.JobFinished(job, job.Status, nil)
+		err = releaseJobPrLock(job)
+		if err != nil {
+			log.Printf("could not release PR lock of job %v: %v", job.DiggerJobID, err)
+		}
+	}
+
+	if job.Batch != nil {
+		err = models.DB.UpdateBatchStatus(job.Batch)
+		if err != nil {
+			return fmt.Errorf("could not update status of batch %v: %v", job.Batch.ID, err)
+		}
+	}
+	// the job no longer holds a concurrency slot
+	models.DB.NotifyScheduler()
+	return nil
+}
+
+// releaseJobPrLock releases the lock of the project of the job if it is held by the pull request of its batch
+func releaseJobPrLock(job *models.DiggerJob) error {
+	batch := job.Batch
+	if batch == nil || batch.PrNumber == 0 {
+		return nil
+	}
+	serializedJob, err := job.MapToJsonStruct()
+	if err != nil {
+		return err
+	}
+	resource := batch.RepoFullName + "#" + serializedJob.ProjectName
+	lock := locking.BackendDBLock{}
+	lockId, err := lock.GetLock(resource)
+	if err != nil {
+		return err
+	}
+	if lockId == nil || *lockId != batch.PrNumber {
+		return nil
+	}
+	log.Printf("releasing lock %v held by PR %v", resource, batch.PrNumber)
+	_, err = lock.Unlock(resource)
+	return err
+}
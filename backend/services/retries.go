@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/digger_config"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"log"
+	"time"
+)
+
+// retryPolicyForBatch returns the retry policy of the digger.yml the batch was created with
+func retryPolicyForBatch(batch *models.DiggerBatch) (digger_config.RetryPolicy, error) {
+	configYaml, err := digger_config.LoadDiggerConfigYamlFromString(batch.DiggerConfig)
+	if err != nil {
+		return digger_config.RetryPolicy{}, fmt.Errorf("could not load digger config of batch: %v", err)
+	}
+	return digger_config.RetryPolicyFromYaml(configYaml.Retries)
+}
+
+// isJobCancelled returns whether the job, or its batch, was cancelled or superseded since it was loaded
+func isJobCancelled(job *models.DiggerJob) (bool, error) {
+	current, err := models.DB.GetDiggerJob(job.DiggerJobID)
+	if err != nil {
+		return false, fmt.Errorf("could not get job %v: %v", job.DiggerJobID, err)
+	}
+	if current.Status == orchestrator_scheduler.DiggerJobCancelled {
+		return true, nil
+	}
+	if current.Batch == nil {
+		return false, nil
+	}
+	return current.Batch.Status == orchestrator_scheduler.BatchJobCancelled || current.Batch.Status == orchestrator_scheduler.BatchJobSuperseded, nil
+}
+
+// RetryJobIfRetryable queues a job that failed with a failure of class to run again after the backoff of
+// its retry policy. It returns false when the job can't be retried, in which case the job is left untouched.
+// Cancelled jobs and jobs of cancelled or superseded batches are never retried.
+func RetryJobIfRetryable(job *models.DiggerJob, class string) (bool, error) {
+	if class == "" || job.Batch == nil {
+		return false, nil
+	}
+	cancelled, err := isJobCancelled(job)
+	if err != nil {
+		return false, err
+	}
+	if cancelled {
+		log.Printf("job %v or its batch was cancelled, not retrying it", job.DiggerJobID)
+		return false, nil
+	}
+	policy, err := retryPolicyForBatch(job.Batch)
+	if err != nil {
+		return false, err
+	}
+	attempts := job.Attempts()
+	if !policy.IsRetryable(class, attempts) {
+		return false, nil
+	}
+
+	nextAttemptAt := time.Now().Add(policy.BackoffAfter(attempts))
+	log.Printf("job %v failed with a %v failure on attempt %v of %v, retrying it at %v", job.DiggerJobID, class, attempts, policy.MaxAttempts, nextAttemptAt)
+	job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
+	job.Attempt = attempts + 1
+	job.NextAttemptAt = &nextAttemptAt
+	job.FailureClass = class
+	err = models.DB.UpdateDiggerJob(job)
+	if err != nil {
+		return false, fmt.Errorf("could not queue job %v for a retry: %v", job.DiggerJobID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/digger_config"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestRetryJobIfRetryableSkipsCancelledJobs(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = gdb.AutoMigrate(&models.DiggerBatch{}, &models.DiggerJob{}, &models.DiggerJobSummary{})
+	assert.NoError(t, err)
+	defer func(previous *models.Database) { models.DB = previous }(models.DB)
+	models.DB = &models.Database{GormDB: gdb}
+
+	diggerYml := "projects:\n- name: prod\n  dir: prod\nretries:\n  max_attempts: 3\n"
+	createFailedJob := func(batchStatus orchestrator_scheduler.DiggerBatchStatus, jobStatus orchestrator_scheduler.DiggerJobStatus) *models.DiggerJob {
+		batch, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 1, "acme", "infra", "acme/infra", 7, diggerYml, "main", orchestrator_scheduler.DiggerCommandPlan, nil, 0)
+		assert.NoError(t, err)
+		batch.Status = batchStatus
+		assert.NoError(t, models.DB.UpdateDiggerBatch(batch))
+		jobSpec, err := json.Marshal(orchestrator_scheduler.JobJson{ProjectName: "prod"})
+		assert.NoError(t, err)
+		job, err := models.DB.CreateDiggerJob(batch.ID, jobSpec, "digger_workflow.yml")
+		assert.NoError(t, err)
+		job.Status = jobStatus
+		assert.NoError(t, models.DB.UpdateDiggerJob(job))
+		job, err = models.DB.GetDiggerJob(job.DiggerJobID)
+		assert.NoError(t, err)
+		return job
+	}
+
+	job := createFailedJob(orchestrator_scheduler.BatchJobCreated, orchestrator_scheduler.DiggerJobFailed)
+	retried, err := RetryJobIfRetryable(job, digger_config.FailureClassRunnerLost)
+	assert.NoError(t, err)
+	assert.True(t, retried)
+
+	for _, batchStatus := range []orchestrator_scheduler.DiggerBatchStatus{orchestrator_scheduler.BatchJobCancelled, orchestrator_scheduler.BatchJobSuperseded} {
+		job = createFailedJob(batchStatus, orchestrator_scheduler.DiggerJobFailed)
+		retried, err = RetryJobIfRetryable(job, digger_config.FailureClassRunnerLost)
+		assert.NoError(t, err)
+		assert.False(t, retried)
+	}
+
+	job = createFailedJob(orchestrator_scheduler.BatchJobCreated, orchestrator_scheduler.DiggerJobCancelled)
+	retried, err = RetryJobIfRetryable(job, digger_config.FailureClassRunnerLost)
+	assert.NoError(t, err)
+	assert.False(t, retried)
+	assert.Equal(t, orchestrator_scheduler.DiggerJobCancelled, job.Status)
+}
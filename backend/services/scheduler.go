@@ -1,15 +1,22 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/diggerhq/digger/backend/ci_backends"
-	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
+	"github.com/diggerhq/digger/libs/digger_config"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/diggerhq/digger/libs/tracing"
 	"github.com/google/go-github/v61/github"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"log"
+	"time"
 )
 
 func DiggerJobCompleted(client *github.Client, batchId *uuid.UUID, parentJob *models.DiggerJob, repoFullName string, repoOwner string, repoName string, workflowFileName string, gh utils.GithubClientProvider) error {
@@ -45,6 +52,10 @@ func DiggerJobCompleted(client *github.Client, batchId *uuid.UUID, parentJob *mo
 			if err != nil {
 				return err
 			}
+			if job.Status != orchestrator_scheduler.DiggerJobCreated {
+				// the job was cancelled or already scheduled
+				continue
+			}
 			ciBackend := ci_backends.GithubActionCi{Client: client}
 			ScheduleJob(ciBackend, repoFullName, repoOwner, repoName, batchId, job, gh)
 		}
@@ -53,46 +64,71 @@ func DiggerJobCompleted(client *github.Client, batchId *uuid.UUID, parentJob *mo
 	return nil
 }
 
+// concurrencyLockKey is the advisory lock held while checking the concurrency limits and claiming a job, so
+// that backend instances scheduling jobs at the same time don't both take the last slot
+const concurrencyLockKey = "digger_job_concurrency"
+
+// ScheduleJob triggers the job if it fits in the concurrency limits of its batch, CI backend, organisation
+// and repo, otherwise it queues it until a running job finishes
 func ScheduleJob(ciBackend ci_backends.CiBackend, repoFullname string, repoOwner string, repoName string, batchId *uuid.UUID, job *models.DiggerJob, gh utils.GithubClientProvider) error {
-	maxConcurrencyForBatch := config.DiggerConfig.GetInt("max_concurrency_per_batch")
-	if maxConcurrencyForBatch == 0 {
-		// concurrency limits not set
-		err := TriggerJob(gh, ciBackend, repoFullname, repoOwner, repoName, batchId, job)
+	batch, err := models.DB.GetDiggerBatch(batchId)
+	if err != nil {
+		log.Printf("could not get batch %v: %v", batchId, err)
+		return err
+	}
+	previousStatus := job.Status
+	claimed := false
+	err = models.DB.WithAdvisoryLock(concurrencyLockKey, func() error {
+		limit, err := reachedConcurrencyLimit(batch)
 		if err != nil {
-			log.Printf("Could not trigger job: %v", err)
+			log.Printf("could not check concurrency limits: %v", err)
 			return err
 		}
-	} else {
-		// concurrency limits set
-		log.Printf("Scheduling job with concurrency limit: %v per batch", maxConcurrencyForBatch)
-		jobs, err := models.DB.GetDiggerJobsForBatchWithStatus(*batchId, []orchestrator_scheduler.DiggerJobStatus{
-			orchestrator_scheduler.DiggerJobTriggered,
-			orchestrator_scheduler.DiggerJobStarted,
-		})
+		if limit != "" {
+			log.Printf("max concurrency per %v reached, queuing job %v until more jobs finish", limit, job.DiggerJobID)
+			job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
+			return models.DB.UpdateDiggerJob(job)
+		}
+
+		// the job takes its slot once it is marked as triggered, the CI backend is called after the lock is released
+		job.Status = orchestrator_scheduler.DiggerJobTriggered
+		job.StatusUpdatedAt = time.Now()
+		err = models.DB.UpdateDiggerJob(job)
 		if err != nil {
-			log.Printf("GetDiggerJobsForBatchWithStatus err: %v\n", err)
+			log.Printf("could not claim job %v: %v", job.DiggerJobID, err)
 			return err
 		}
-		log.Printf("Length of jobs: %v", len(jobs))
-		if len(jobs) >= maxConcurrencyForBatch {
-			log.Printf("max concurrency for jobs reached: %v, queuing until more jobs succeed", len(jobs))
-			job.Status = orchestrator_scheduler.DiggerJobQueuedForRun
-			models.DB.UpdateDiggerJob(job)
-			return nil
-		} else {
-			err := TriggerJob(gh, ciBackend, repoFullname, repoOwner, repoName, batchId, job)
-			if err != nil {
-				log.Printf("Could not trigger job: %v", err)
-				return err
-			}
+		claimed = true
+		return nil
+	})
+	if err != nil || !claimed {
+		return err
+	}
+
+	err = TriggerJob(gh, ciBackend, repoFullname, repoOwner, repoName, batchId, job)
+	if err != nil {
+		log.Printf("Could not trigger job: %v", err)
+		// give the slot back, the job is scheduled again like before it was claimed
+		job.Status = previousStatus
+		if updateErr := models.DB.UpdateDiggerJob(job); updateErr != nil {
+			log.Printf("could not release the slot of job %v: %v", job.DiggerJobID, updateErr)
 		}
+		return err
 	}
 	return nil
 }
 
-func TriggerJob(gh utils.GithubClientProvider, ciBackend ci_backends.CiBackend, repoFullname string, repoOwner string, repoName string, batchId *uuid.UUID, job *models.DiggerJob) error {
+func TriggerJob(gh utils.GithubClientProvider, ciBackend ci_backends.CiBackend, repoFullname string, repoOwner string, repoName string, batchId *uuid.UUID, job *models.DiggerJob) (err error) {
 	log.Printf("TriggerJob jobId: %v", job.DiggerJobID)
 
+	// queued jobs are triggered later by the worker, the span joins the trace of the batch rather than the caller's
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), batchTraceContext(job, batchId)), "trigger CI job",
+		trace.WithAttributes(
+			attribute.String("digger.job_id", job.DiggerJobID),
+			attribute.String("digger.repo", repoFullname),
+		))
+	defer func() { tracing.End(span, err) }()
+
 	if job.SerializedJobSpec == nil {
 		log.Printf("Jobspec can't be nil")
 		return fmt.Errorf("JobSpec is nil, skipping")
@@ -106,11 +142,18 @@ func TriggerJob(gh utils.GithubClientProvider, ciBackend ci_backends.CiBackend,
 		return fmt.Errorf("could not get run name %v", err)
 	}
 
+	err = injectProjectInputs(job)
+	if err != nil {
+		log.Printf("could not inject project inputs: %v", err)
+		return fmt.Errorf("could not inject project inputs: %v", err)
+	}
+
 	spec, err := GetSpecFromJob(*job)
 	if err != nil {
 		log.Printf("could not get spec: %v", err)
 		return fmt.Errorf("could not get spec %v", err)
 	}
+	spec.TraceContext = tracing.Inject(ctx)
 
 	vcsToken, err := GetVCSTokenFromJob(*job, gh)
 	if err != nil {
@@ -120,11 +163,13 @@ func TriggerJob(gh utils.GithubClientProvider, ciBackend ci_backends.CiBackend,
 
 	err = ciBackend.TriggerWorkflow(*spec, *runName, *vcsToken)
 	if err != nil {
+		metrics.CiTriggerFailed(repoFullname)
 		log.Printf("TriggerJob err: %v\n", err)
 		return err
 	}
 
 	job.Status = orchestrator_scheduler.DiggerJobTriggered
+	job.StatusUpdatedAt = time.Now()
 	err = models.DB.UpdateDiggerJob(job)
 	if err != nil {
 		log.Printf("failed to Update digger job state: %v\n", err)
@@ -133,3 +178,70 @@ func TriggerJob(gh utils.GithubClientProvider, ciBackend ci_backends.CiBackend,
 
 	return nil
 }
+
+// batchTraceContext returns the trace context saved on the batch of the job, nil if it can't be read
+func batchTraceContext(job *models.DiggerJob, batchId *uuid.UUID) map[string]string {
+	if job.Batch != nil {
+		return job.Batch.TraceContext
+	}
+	batch, err := models.DB.GetDiggerBatch(batchId)
+	if err != nil {
+		log.Printf("could not get batch %v: %v", batchId, err)
+		return nil
+	}
+	return batch.TraceContext
+}
+
+// injectProjectInputs sets the outputs of the parent jobs that the job's project declares as inputs as
+// TF_VAR_ command env vars of its spec. Outputs are only available when the parent project was applied
+// in the same batch, missing ones are skipped.
+func injectProjectInputs(job *models.DiggerJob) error {
+	var jobSpec orchestrator_scheduler.JobJson
+	err := json.Unmarshal(job.SerializedJobSpec, &jobSpec)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal job spec: %v", err)
+	}
+	if len(jobSpec.ProjectInputs) == 0 {
+		return nil
+	}
+
+	parentLinks, err := models.DB.GetDiggerJobParentLinksChildId(&job.DiggerJobID)
+	if err != nil {
+		return err
+	}
+	outputsByProject := make(map[string]map[string]string)
+	for _, link := range parentLinks {
+		parentJob, err := models.DB.GetDiggerJob(link.ParentDiggerJobId)
+		if err != nil {
+			return err
+		}
+		var parentSpec orchestrator_scheduler.JobJson
+		err = json.Unmarshal(parentJob.SerializedJobSpec, &parentSpec)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal spec of parent job %v: %v", parentJob.DiggerJobID, err)
+		}
+		outputsByProject[parentSpec.ProjectName] = parentJob.Outputs
+	}
+
+	if jobSpec.CommandEnvVars == nil {
+		jobSpec.CommandEnvVars = make(map[string]string)
+	}
+	for variable, input := range jobSpec.ProjectInputs {
+		project, output, err := digger_config.ParseProjectInput(input)
+		if err != nil {
+			return fmt.Errorf("invalid input %v: %v", variable, err)
+		}
+		value, ok := outputsByProject[project][output]
+		if !ok {
+			log.Printf("output %v of project %v is not available for job %v, skipping input %v", output, project, job.DiggerJobID, variable)
+			continue
+		}
+		jobSpec.CommandEnvVars["TF_VAR_"+variable] = value
+	}
+
+	job.SerializedJobSpec, err = json.Marshal(jobSpec)
+	if err != nil {
+		return fmt.Errorf("could not marshal job spec: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"log"
+	"time"
+)
+
+const listenerReconnectDelay = 5 * time.Second
+
+// wakeUp signals the scheduler without blocking, a wake up already pending covers this one too
+func wakeUp(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// listenForNotifications wakes the scheduler up on every notification sent on channel until ctx is
+// cancelled, reconnecting to the database whenever the connection is lost
+func listenForNotifications(ctx context.Context, databaseUrl string, channel string, wake chan<- struct{}) {
+	for {
+		err := listen(ctx, databaseUrl, channel, wake)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Lost connection listening for scheduler notifications, reconnecting in %v: %v", listenerReconnectDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenerReconnectDelay):
+		}
+	}
+}
+
+func listen(ctx context.Context, databaseUrl string, channel string, wake chan<- struct{}) error {
+	conn, err := pgx.Connect(ctx, databaseUrl)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+	log.Printf("Listening for scheduler notifications on %v", channel)
+	// work might have been queued while the listener was not connected
+	wakeUp(wake)
+
+	for {
+		_, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		wakeUp(wake)
+	}
+}
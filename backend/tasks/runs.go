@@ -1,162 +1,248 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/diggerhq/digger/backend/ci_backends"
+	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/services"
 	"github.com/diggerhq/digger/backend/utils"
-	"github.com/diggerhq/digger/libs/ci"
-	"github.com/diggerhq/digger/libs/ci/github"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/diggerhq/digger/libs/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"log"
+	"time"
 )
 
-func RunQueuesStateMachine(queueItem *models.DiggerRunQueueItem, service ci.PullRequestService, gh utils.GithubClientProvider) {
-	dr := queueItem.DiggerRun
-	switch queueItem.DiggerRun.Status {
-	case models.RunQueued:
-		// trigger plan workflow (trigger the batch)
-		job, err := models.DB.GetDiggerJobFromRunStage(dr.PlanStage)
-		client := service.(*github.GithubService).Client
-		ciBackend := ci_backends.GithubActionCi{Client: client}
-		runName, err := services.GetRunNameFromJob(*job)
+// runStageTimeout returns how long a run can stay in a status before it is considered stuck, 0 if it can
+// stay in it indefinitely
+func runStageTimeout(status models.DiggerRunStatus) time.Duration {
+	switch status {
+	case models.RunPlanning:
+		return config.DiggerConfig.GetDuration("run_planning_timeout")
+	case models.RunPendingApproval:
+		return config.DiggerConfig.GetDuration("run_approval_timeout")
+	case models.RunApplying:
+		return config.DiggerConfig.GetDuration("run_applying_timeout")
+	default:
+		return 0
+	}
+}
+
+func runStatusUpdatedAt(dr *models.DiggerRun) time.Time {
+	if dr.StatusUpdatedAt.IsZero() {
+		return dr.UpdatedAt
+	}
+	return dr.StatusUpdatedAt
+}
+
+func setRunStatus(dr *models.DiggerRun, status models.DiggerRunStatus) error {
+	log.Printf("Updating run %v (%v) from %v to %v", dr.ID, dr.ProjectName, dr.Status, status)
+	dr.Status = status
+	dr.StatusUpdatedAt = time.Now()
+	err := models.DB.UpdateDiggerRun(dr)
+	if err != nil {
+		return err
+	}
+	// the next transition of the run can happen right away
+	models.DB.NotifyScheduler()
+	return nil
+}
+
+// finishRun moves the run to a final status and removes it from the queue so that the next run of the
+// project can start
+func finishRun(queueItem *models.DiggerRunQueueItem, dr *models.DiggerRun, status models.DiggerRunStatus) {
+	if dr.Status != status {
+		err := setRunStatus(dr, status)
 		if err != nil {
-			log.Printf("could not get run name: %v", err)
+			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
 			return
 		}
+	}
+	err := models.DB.DequeueRunItem(queueItem)
+	if err != nil {
+		log.Printf("ERROR: Failed to delete queueItem item: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
+	}
+}
 
-		spec, err := services.GetSpecFromJob(*job)
-		if err != nil {
-			log.Printf("could not get spec: %v", err)
-			return
+func getRunStageBatch(stage models.DiggerRunStage) (*models.DiggerBatch, error) {
+	if stage.BatchID == nil {
+		return nil, fmt.Errorf("run stage %v has no batch", stage.ID)
+	}
+	batchId, err := uuid.Parse(*stage.BatchID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch id of run stage %v: %v", stage.ID, err)
+	}
+	return models.DB.GetDiggerBatch(&batchId)
+}
+
+// cancelRunStage cancels the batch of a stage that timed out along with its CI runs, so that they don't keep
+// running once the next run of the project starts
+func cancelRunStage(stage models.DiggerRunStage, ciBackend ci_backends.CiBackend) {
+	batch, err := getRunStageBatch(stage)
+	if err != nil {
+		log.Printf("could not get batch of run stage %v: %v", stage.ID, err)
+		return
+	}
+	_, err = services.CancelBatch(ciBackend, batch, orchestrator_scheduler.BatchJobCancelled)
+	if err != nil {
+		log.Printf("could not cancel batch %v of run stage %v: %v", batch.ID, stage.ID, err)
+	}
+}
+
+func triggerRunStage(stage models.DiggerRunStage, ciBackend ci_backends.CiBackend, gh utils.GithubClientProvider) (err error) {
+	job, err := models.DB.GetDiggerJobFromRunStage(stage)
+	if err != nil {
+		return fmt.Errorf("could not get job: %v", err)
+	}
+	ctx := context.Background()
+	if job.Batch != nil {
+		ctx = tracing.Extract(ctx, job.Batch.TraceContext)
+	}
+	ctx, span := tracing.Start(ctx, "trigger run stage", trace.WithAttributes(attribute.String("digger.job_id", job.DiggerJobID)))
+	defer func() { tracing.End(span, err) }()
+	runName, err := services.GetRunNameFromJob(*job)
+	if err != nil {
+		return fmt.Errorf("could not get run name: %v", err)
+	}
+
+	spec, err := services.GetSpecFromJob(*job)
+	if err != nil {
+		return fmt.Errorf("could not get spec: %v", err)
+	}
+	spec.TraceContext = tracing.Inject(ctx)
+
+	vcsToken, err := services.GetVCSTokenFromJob(*job, gh)
+	if err != nil {
+		return fmt.Errorf("could not get vcs token: %v", err)
+	}
+
+	err = ciBackend.TriggerWorkflow(*spec, *runName, *vcsToken)
+	if err != nil {
+		metrics.CiTriggerFailed(spec.VCS.RepoFullname)
+	}
+	return err
+}
+
+// runApprovalRequired returns whether a run waits for approval before apply, which is the case when its
+// project requires the approval of its owners or its organisation requires approval for every run
+func runApprovalRequired(queueItem *models.DiggerRunQueueItem) (bool, error) {
+	project, err := models.DB.GetProject(queueItem.ProjectId)
+	if err != nil {
+		return false, fmt.Errorf("could not get project: %v", err)
+	}
+	if project.RequireOwnerApproval {
+		return true, nil
+	}
+	return project.Organisation != nil && project.Organisation.RequireRunApproval, nil
+}
+
+func RunQueuesStateMachine(queueItem *models.DiggerRunQueueItem, ciBackend ci_backends.CiBackend, gh utils.GithubClientProvider) {
+	// the run is reloaded so that its stages and approval reflect the latest state
+	dr, err := models.DB.GetDiggerRun(queueItem.DiggerRunId)
+	if err != nil {
+		log.Printf("ERROR: Failed to get Digger Run for queueID: %v [%v]: %v", queueItem.ID, queueItem.DiggerRunId, err)
+		return
+	}
+
+	if timeout := runStageTimeout(dr.Status); timeout > 0 && time.Since(runStatusUpdatedAt(dr)) > timeout {
+		log.Printf("Run %v (%v) has been in status %v for more than %v, failing it", dr.ID, dr.ProjectName, dr.Status, timeout)
+		switch dr.Status {
+		case models.RunPlanning:
+			cancelRunStage(dr.PlanStage, ciBackend)
+		case models.RunApplying:
+			cancelRunStage(dr.ApplyStage, ciBackend)
 		}
+		finishRun(queueItem, dr, models.RunFailed)
+		return
+	}
 
-		vcsToken, err := services.GetVCSTokenFromJob(*job, gh)
+	switch dr.Status {
+	case models.RunQueued:
+		// trigger plan workflow (trigger the batch)
+		err := triggerRunStage(dr.PlanStage, ciBackend, gh)
 		if err != nil {
-			log.Printf("could not get vcs token: %v", err)
+			log.Printf("could not trigger plan of run %v: %v", dr.ID, err)
 			return
 		}
 
-		ciBackend.TriggerWorkflow(*spec, *runName, *vcsToken)
-
-		// change status to RunPendingPlan
-		log.Printf("Updating run queueItem item to planning state")
-		dr.Status = models.RunPlanning
-		err = models.DB.UpdateDiggerRun(&dr)
+		err = setRunStatus(dr, models.RunPlanning)
 		if err != nil {
-			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
 		}
 	case models.RunPlanning:
-		// Check the status of the batch
-		batchStatus := orchestrator_scheduler.BatchJobSucceeded //dr.PlanStage.Batch.Status
-		approvalRequired := true
-
-		// if failed then go straight to failed
-		if batchStatus == orchestrator_scheduler.BatchJobFailed {
-			dr.Status = models.RunFailed
-			err := models.DB.UpdateDiggerRun(&dr)
-			if err != nil {
-				log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+		batch, err := getRunStageBatch(dr.PlanStage)
+		if err != nil {
+			log.Printf("could not get plan batch of run %v: %v", dr.ID, err)
+			return
+		}
+
+		switch batch.Status {
+		case orchestrator_scheduler.BatchJobFailed:
+			finishRun(queueItem, dr, models.RunFailed)
+		case orchestrator_scheduler.BatchJobInvalidated, orchestrator_scheduler.BatchJobSuperseded, orchestrator_scheduler.BatchJobCancelled:
+			finishRun(queueItem, dr, models.RunCancelled)
+		case orchestrator_scheduler.BatchJobSucceeded:
+			if dr.RunType == models.PlanOnly {
+				finishRun(queueItem, dr, models.RunSucceeded)
+				return
 			}
-			err = models.DB.DequeueRunItem(queueItem)
+			approvalRequired, err := runApprovalRequired(queueItem)
 			if err != nil {
-				log.Printf("ERROR: Failed to delete queueItem item: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+				log.Printf("could not check if run %v requires approval: %v", dr.ID, err)
+				return
 			}
-		}
-
-		// if successful then
-		if batchStatus == orchestrator_scheduler.BatchJobSucceeded {
-			if approvalRequired {
-				dr.Status = models.RunPendingApproval
-				err := models.DB.UpdateDiggerRun(&dr)
-				if err != nil {
-					log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
-				}
-			} else {
-				dr.Status = models.RunApproved
-				err := models.DB.UpdateDiggerRun(&dr)
-				if err != nil {
-					log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
-				}
+			nextStatus := models.RunApproved
+			if approvalRequired && !dr.IsApproved {
+				nextStatus = models.RunPendingApproval
+			}
+			err = setRunStatus(dr, nextStatus)
+			if err != nil {
+				log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
 			}
 		}
-
 	case models.RunPendingApproval:
-		// do nothing
-	case models.RunApproved:
-		// trigger apply stage workflow
-		job, err := models.DB.GetDiggerJobFromRunStage(dr.ApplyStage)
-		client := service.(*github.GithubService).Client
-		ciBackend := ci_backends.GithubActionCi{Client: client}
-		if err != nil {
-			log.Printf("could not get run name: %v", err)
+		if !dr.IsApproved {
 			return
 		}
-		runName, err := services.GetRunNameFromJob(*job)
+		err := setRunStatus(dr, models.RunApproved)
 		if err != nil {
-			log.Printf("could not get run name: %v", err)
-			return
+			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
 		}
-
-		spec, err := services.GetSpecFromJob(*job)
-		if err != nil {
-			log.Printf("could not get spec: %v", err)
-			return
-		}
-
-		vcsToken, err := services.GetVCSTokenFromJob(*job, gh)
+	case models.RunApproved:
+		// trigger apply stage workflow
+		err := triggerRunStage(dr.ApplyStage, ciBackend, gh)
 		if err != nil {
-			log.Printf("could not get vcs token: %v", err)
+			log.Printf("could not trigger apply of run %v: %v", dr.ID, err)
 			return
 		}
 
-		ciBackend.TriggerWorkflow(*spec, *runName, *vcsToken)
-
-		dr.Status = models.RunApplying
-		err = models.DB.UpdateDiggerRun(&dr)
+		err = setRunStatus(dr, models.RunApplying)
 		if err != nil {
-			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+			log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, dr.ProjectName)
 		}
-
 	case models.RunApplying:
-		// Check the status of the batch
-		batchStatus := dr.PlanStage.Batch.Status
-
-		// if failed then go straight to failed
-		if batchStatus == orchestrator_scheduler.BatchJobFailed {
-			dr.Status = models.RunFailed
-			err := models.DB.UpdateDiggerRun(&dr)
-			if err != nil {
-				log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
-			}
-			err = models.DB.DequeueRunItem(queueItem)
-			if err != nil {
-				log.Printf("ERROR: Failed to delete queueItem item: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
-			}
-		}
-
-		// if successful then
-		if batchStatus == orchestrator_scheduler.BatchJobSucceeded {
-			dr.Status = models.RunSucceeded
-			err := models.DB.UpdateDiggerRun(&dr)
-			if err != nil {
-				log.Printf("ERROR: Failed to update Digger Run for queueID: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
-			}
-		}
-
-	case models.RunSucceeded:
-		// dequeue
-		err := models.DB.DequeueRunItem(queueItem)
+		batch, err := getRunStageBatch(dr.ApplyStage)
 		if err != nil {
-			log.Printf("ERROR: Failed to delete queueItem item: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+			log.Printf("could not get apply batch of run %v: %v", dr.ID, err)
+			return
 		}
-	case models.RunFailed:
-		// dequeue
-		err := models.DB.DequeueRunItem(queueItem)
-		if err != nil {
-			log.Printf("ERROR: Failed to delete queueItem item: %v [%v %v]", queueItem.ID, queueItem.DiggerRunId, queueItem.DiggerRun.ProjectName)
+
+		switch batch.Status {
+		case orchestrator_scheduler.BatchJobFailed:
+			finishRun(queueItem, dr, models.RunFailed)
+		case orchestrator_scheduler.BatchJobInvalidated, orchestrator_scheduler.BatchJobSuperseded, orchestrator_scheduler.BatchJobCancelled:
+			finishRun(queueItem, dr, models.RunCancelled)
+		case orchestrator_scheduler.BatchJobSucceeded:
+			finishRun(queueItem, dr, models.RunSucceeded)
 		}
+	case models.RunSucceeded, models.RunFailed, models.RunCancelled:
+		finishRun(queueItem, dr, dr.Status)
 	default:
-		log.Printf("WARN: Recieived unknown DiggerRunStatus: %v", queueItem.DiggerRun.Status)
+		log.Printf("WARN: Recieived unknown DiggerRunStatus: %v", dr.Status)
 	}
 }
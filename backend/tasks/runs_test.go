@@ -1,10 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
-	github2 "github.com/diggerhq/digger/libs/ci/github"
 	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/spec"
 	"github.com/stretchr/testify/assert"
@@ -14,6 +14,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -22,9 +23,22 @@ func init() {
 }
 
 type MockCiBackend struct {
+	TriggeredRuns []string
+	CancelledJobs []string
+	RunningJobs   map[string]bool
 }
 
-func (m MockCiBackend) TriggerWorkflow(spec spec.Spec, runName string, vcsToken string) error {
+func (m *MockCiBackend) CancelWorkflow(spec spec.Spec, workflowRunUrl string) error {
+	m.CancelledJobs = append(m.CancelledJobs, spec.JobId)
+	return nil
+}
+
+func (m *MockCiBackend) IsWorkflowRunning(spec spec.Spec, workflowRunUrl string) (bool, error) {
+	return m.RunningJobs[spec.JobId], nil
+}
+
+func (m *MockCiBackend) TriggerWorkflow(spec spec.Spec, runName string, vcsToken string) error {
+	m.TriggeredRuns = append(m.TriggeredRuns, runName)
 	return nil
 }
 
@@ -51,7 +65,8 @@ func setupSuite(tb testing.TB) (func(tb testing.TB), *models.Database) {
 	// migrate tables
 	err = gdb.AutoMigrate(&models.Policy{}, &models.Organisation{}, &models.Repo{}, &models.Project{}, &models.Token{},
 		&models.User{}, &models.ProjectRun{}, &models.GithubAppInstallation{}, &models.GithubApp{}, &models.GithubAppInstallationLink{},
-		&models.GithubDiggerJobLink{}, &models.DiggerJob{}, &models.DiggerJobParentLink{}, &models.DiggerRun{}, &models.DiggerRunQueueItem{})
+		&models.GithubDiggerJobLink{}, &models.DiggerJob{}, &models.DiggerJobParentLink{}, &models.DiggerRun{}, &models.DiggerRunQueueItem{},
+		&models.DiggerBatch{}, &models.DiggerRunStage{}, &models.DiggerJobSummary{}, &models.DiggerLock{})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -93,69 +108,132 @@ func setupSuite(tb testing.TB) (func(tb testing.TB), *models.Database) {
 	}, database
 }
 
-func TestThatRunQueueItemMovesFromQueuedToPlanningAfterPickup(t *testing.T) {
-	// TODO: Fix the failing tests and unskip
-	t.Skip()
-
-	teardownSuite, _ := setupSuite(t)
+func TestRunQueuesStateMachineTransitions(t *testing.T) {
+	teardownSuite, database := setupSuite(t)
 	defer teardownSuite(t)
 
+	org, err := database.GetOrganisation("11111111-1111-1111-1111-111111111111")
+	assert.NoError(t, err)
+
 	type params struct {
-		BatchStatus        orchestrator_scheduler.DiggerBatchStatus
+		Name               string
 		InitialStatus      models.DiggerRunStatus
+		PlanBatchStatus    orchestrator_scheduler.DiggerBatchStatus
+		ApplyBatchStatus   orchestrator_scheduler.DiggerBatchStatus
+		RunType            models.RunType
+		RequireApproval    bool
+		IsApproved         bool
+		StatusAge          time.Duration
 		NextExpectedStatus models.DiggerRunStatus
+		ExpectTrigger      bool
+		ExpectDequeued     bool
+		ExpectCancelled    bool
 	}
 
 	testParameters := []params{
-		{
-			BatchStatus:        orchestrator_scheduler.BatchJobSucceeded,
-			InitialStatus:      models.RunQueued,
-			NextExpectedStatus: models.RunPlanning,
-		},
-		// TODO: Uncomment when functionality working
-		//{
-		//	BatchStatus:        orchestrator_scheduler.BatchJobFailed,
-		//	InitialStatus:      models.RunPlanning,
-		//	NextExpectedStatus: models.RunFailed,
-		//},
-		{
-			BatchStatus:        orchestrator_scheduler.BatchJobSucceeded,
-			InitialStatus:      models.RunPlanning,
-			NextExpectedStatus: models.RunPendingApproval,
-		},
-		{
-			BatchStatus:        orchestrator_scheduler.BatchJobSucceeded,
-			InitialStatus:      models.RunApproved,
-			NextExpectedStatus: models.RunApplying,
-		},
-		// TODO: uncomment when functional
-		//{
-		//	BatchStatus:        orchestrator_scheduler.BatchJobFailed,
-		//	InitialStatus:      models.RunApplying,
-		//	NextExpectedStatus: models.RunFailed,
-		//},
-		{
-			BatchStatus:        orchestrator_scheduler.BatchJobSucceeded,
-			InitialStatus:      models.RunApplying,
-			NextExpectedStatus: models.RunSucceeded,
-		},
+		{Name: "queued triggers plan", InitialStatus: models.RunQueued, NextExpectedStatus: models.RunPlanning, ExpectTrigger: true},
+		{Name: "plan still running", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobStarted, NextExpectedStatus: models.RunPlanning},
+		{Name: "plan failed", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobFailed, NextExpectedStatus: models.RunFailed, ExpectDequeued: true},
+		{Name: "plan cancelled", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobInvalidated, NextExpectedStatus: models.RunCancelled, ExpectDequeued: true},
+		{Name: "plan succeeded, approval required", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, RequireApproval: true, NextExpectedStatus: models.RunPendingApproval},
+		{Name: "plan succeeded, no approval required", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, NextExpectedStatus: models.RunApproved},
+		{Name: "plan only run succeeded", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, RunType: models.PlanOnly, RequireApproval: true, NextExpectedStatus: models.RunSucceeded, ExpectDequeued: true},
+		{Name: "plan superseded", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobSuperseded, NextExpectedStatus: models.RunCancelled, ExpectDequeued: true},
+		{Name: "apply cancelled with a comment", InitialStatus: models.RunApplying, ApplyBatchStatus: orchestrator_scheduler.BatchJobCancelled, NextExpectedStatus: models.RunCancelled, ExpectDequeued: true},
+		{Name: "plan timed out", InitialStatus: models.RunPlanning, PlanBatchStatus: orchestrator_scheduler.BatchJobStarted, StatusAge: 3 * time.Hour, NextExpectedStatus: models.RunFailed, ExpectDequeued: true, ExpectCancelled: true},
+		{Name: "apply timed out", InitialStatus: models.RunApplying, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, ApplyBatchStatus: orchestrator_scheduler.BatchJobStarted, StatusAge: 3 * time.Hour, NextExpectedStatus: models.RunFailed, ExpectDequeued: true, ExpectCancelled: true},
+		{Name: "waiting for approval", InitialStatus: models.RunPendingApproval, RequireApproval: true, NextExpectedStatus: models.RunPendingApproval},
+		{Name: "approved", InitialStatus: models.RunPendingApproval, RequireApproval: true, IsApproved: true, NextExpectedStatus: models.RunApproved},
+		{Name: "approval timed out", InitialStatus: models.RunPendingApproval, RequireApproval: true, StatusAge: 200 * time.Hour, NextExpectedStatus: models.RunFailed, ExpectDequeued: true},
+		{Name: "approved triggers apply", InitialStatus: models.RunApproved, NextExpectedStatus: models.RunApplying, ExpectTrigger: true},
+		{Name: "apply still running", InitialStatus: models.RunApplying, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, ApplyBatchStatus: orchestrator_scheduler.BatchJobStarted, NextExpectedStatus: models.RunApplying},
+		{Name: "apply failed", InitialStatus: models.RunApplying, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, ApplyBatchStatus: orchestrator_scheduler.BatchJobFailed, NextExpectedStatus: models.RunFailed, ExpectDequeued: true},
+		{Name: "apply cancelled", InitialStatus: models.RunApplying, ApplyBatchStatus: orchestrator_scheduler.BatchJobInvalidated, NextExpectedStatus: models.RunCancelled, ExpectDequeued: true},
+		{Name: "apply succeeded", InitialStatus: models.RunApplying, PlanBatchStatus: orchestrator_scheduler.BatchJobSucceeded, ApplyBatchStatus: orchestrator_scheduler.BatchJobSucceeded, NextExpectedStatus: models.RunSucceeded, ExpectDequeued: true},
+		{Name: "cancelled run is dequeued", InitialStatus: models.RunCancelled, NextExpectedStatus: models.RunCancelled, ExpectDequeued: true},
 	}
 
+	commentId := int64(1)
+	prNumber := 123
+	jobSpec, err := json.Marshal(orchestrator_scheduler.JobJson{ProjectName: "test", PullRequestNumber: &prNumber})
+	assert.NoError(t, err)
+
 	for i, testParam := range testParameters {
-		ciService := github2.MockCiService{}
-		batch, _ := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 123, "", "", "", 22, "", "", "", nil, 0)
-		project, _ := models.DB.CreateProject(fmt.Sprintf("test%v", i), nil, nil, false, false)
-		planStage, _ := models.DB.CreateDiggerRunStage(batch.ID.String())
-		applyStage, _ := models.DB.CreateDiggerRunStage(batch.ID.String())
-		diggerRun, _ := models.DB.CreateDiggerRun("", 1, testParam.InitialStatus, "sha", "", 123, 1, project.Name, "apply", &planStage.ID, &applyStage.ID)
-		queueItem, _ := models.DB.CreateDiggerRunQueueItem(project.ID, diggerRun.ID)
-		batch.Status = testParam.BatchStatus
-		models.DB.UpdateDiggerBatch(batch)
-		queueItem, _ = models.DB.GetDiggerRunQueueItem(queueItem.ID)
-
-		RunQueuesStateMachine(queueItem, ciService, utils.DiggerGithubClientMockProvider{})
-		diggerRunRefreshed, _ := models.DB.GetDiggerRun(diggerRun.ID)
-		assert.Equal(t, testParam.NextExpectedStatus, diggerRunRefreshed.Status)
+		t.Run(testParam.Name, func(t *testing.T) {
+			ciBackend := &MockCiBackend{}
+			project, err := models.DB.CreateProject(fmt.Sprintf("test%v", i), org, nil, false, false)
+			assert.NoError(t, err)
+			project.RequireOwnerApproval = testParam.RequireApproval
+			assert.NoError(t, models.DB.UpdateProject(project))
+
+			planBatch, _ := models.DB.CreateDiggerBatch(models.DiggerVCSGitlab, 123, "", "", "", 22, "", "", orchestrator_scheduler.DiggerCommandPlan, &commentId, 0)
+			planBatch.Status = testParam.PlanBatchStatus
+			assert.NoError(t, models.DB.UpdateDiggerBatch(planBatch))
+			applyBatch, _ := models.DB.CreateDiggerBatch(models.DiggerVCSGitlab, 123, "", "", "", 22, "", "", orchestrator_scheduler.DiggerCommandApply, &commentId, 0)
+			applyBatch.Status = testParam.ApplyBatchStatus
+			assert.NoError(t, models.DB.UpdateDiggerBatch(applyBatch))
+			for _, batch := range []*models.DiggerBatch{planBatch, applyBatch} {
+				job, err := models.DB.CreateDiggerJob(batch.ID, jobSpec, "digger_workflow.yml")
+				assert.NoError(t, err)
+				job.Status = orchestrator_scheduler.DiggerJobTriggered
+				assert.NoError(t, models.DB.UpdateDiggerJob(job))
+			}
+
+			planStage, _ := models.DB.CreateDiggerRunStage(planBatch.ID.String())
+			applyStage, _ := models.DB.CreateDiggerRunStage(applyBatch.ID.String())
+			runType := testParam.RunType
+			if runType == "" {
+				runType = models.PlanAndApply
+			}
+			diggerRun, _ := models.DB.CreateDiggerRun("", 1, testParam.InitialStatus, "sha", "", 123, 1, project.Name, runType, &planStage.ID, &applyStage.ID)
+			diggerRun.IsApproved = testParam.IsApproved
+			diggerRun.StatusUpdatedAt = time.Now().Add(-testParam.StatusAge)
+			assert.NoError(t, models.DB.UpdateDiggerRun(diggerRun))
+			queueItem, _ := models.DB.CreateDiggerRunQueueItem(diggerRun.ID, project.ID)
+			queueItem, _ = models.DB.GetDiggerRunQueueItem(queueItem.ID)
+
+			RunQueuesStateMachine(queueItem, ciBackend, utils.DiggerGithubClientMockProvider{})
+
+			diggerRunRefreshed, _ := models.DB.GetDiggerRun(diggerRun.ID)
+			assert.Equal(t, testParam.NextExpectedStatus, diggerRunRefreshed.Status)
+			if testParam.ExpectTrigger {
+				assert.Equal(t, 1, len(ciBackend.TriggeredRuns))
+			} else {
+				assert.Empty(t, ciBackend.TriggeredRuns)
+			}
+			if testParam.ExpectCancelled {
+				assert.Equal(t, 1, len(ciBackend.CancelledJobs))
+			} else {
+				assert.Empty(t, ciBackend.CancelledJobs)
+			}
+			var queueItems int64
+			models.DB.GormDB.Model(&models.DiggerRunQueueItem{}).Where("id = ?", queueItem.ID).Count(&queueItems)
+			if testParam.ExpectDequeued {
+				assert.Equal(t, int64(0), queueItems)
+			} else {
+				assert.Equal(t, int64(1), queueItems)
+			}
+		})
 	}
+}
+
+func TestRunApprovalRequiredByOrganisation(t *testing.T) {
+	teardownSuite, database := setupSuite(t)
+	defer teardownSuite(t)
 
+	org, err := database.GetOrganisation("11111111-1111-1111-1111-111111111111")
+	assert.NoError(t, err)
+	project, err := models.DB.CreateProject("approval", org, nil, false, false)
+	assert.NoError(t, err)
+	queueItem := &models.DiggerRunQueueItem{ProjectId: project.ID}
+
+	required, err := runApprovalRequired(queueItem)
+	assert.NoError(t, err)
+	assert.False(t, required)
+
+	org.RequireRunApproval = true
+	assert.NoError(t, database.GormDB.Save(org).Error)
+	required, err = runApprovalRequired(queueItem)
+	assert.NoError(t, err)
+	assert.True(t, required)
 }
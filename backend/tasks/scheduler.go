@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/diggerhq/digger/backend/ci_backends"
+	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/backend/services"
+	"github.com/diggerhq/digger/backend/utils"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"log"
+	"time"
+)
+
+// maximum number of queued jobs claimed by a worker in a single pass
+const queuedJobsClaimLimit = 100
+
+// runScheduler processes the run queues and the jobs queued for run every time it is woken up and at least
+// every pollInterval, until ctx is cancelled. A pass in progress is completed before returning so that no
+// claimed work is left half done.
+func runScheduler(ctx context.Context, wake <-chan struct{}, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		processRunQueues(ctx)
+		processLostJobs(ctx)
+		processSilentJobs(ctx)
+		processQueuedJobs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processRunQueues moves the run at the front of the queue of every project forward
+func processRunQueues(ctx context.Context) {
+	runQueues, err := models.DB.GetFirstRunQueueForEveryProject()
+	if err != nil {
+		log.Printf("Error fetching Latest queueItem runs: %v", err)
+		return
+	}
+
+	lease := config.DiggerConfig.GetDuration("scheduler_claim_lease")
+	for _, queueItem := range runQueues {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, err := models.DB.ClaimRunQueueItem(&queueItem, lease)
+		if err != nil {
+			log.Printf("failed to claim queue item %v: %v", queueItem.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		dr := queueItem.DiggerRun
+		repo := dr.Repo
+		service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, dr.GithubInstallationId, repo.RepoFullName, repo.RepoOrganisation, repo.RepoName)
+		if err != nil {
+			log.Printf("failed to get github service for DiggerRun ID: %v: %v", dr.ID, err)
+		} else {
+			ciBackend := ci_backends.GithubActionCi{Client: service.Client}
+			RunQueuesStateMachine(&queueItem, ciBackend, &utils.DiggerGithubRealClientProvider{})
+		}
+
+		err = models.DB.ReleaseRunQueueItem(&queueItem)
+		if err != nil {
+			log.Printf("failed to release queue item %v: %v", queueItem.ID, err)
+		}
+	}
+}
+
+// processQueuedJobs triggers the jobs waiting for a concurrency slot of their batch. Jobs still not able to
+// run are left queued until the next pass.
+func processQueuedJobs(ctx context.Context) {
+	jobs, err := models.DB.ClaimQueuedDiggerJobs(queuedJobsClaimLimit, config.DiggerConfig.GetDuration("scheduler_claim_lease"))
+	if err != nil {
+		log.Printf("Failed to get Jobs %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() == nil {
+			scheduleQueuedJob(&job)
+		}
+		err = models.DB.ReleaseDiggerJob(&job)
+		if err != nil {
+			log.Printf("failed to release job %v: %v", job.DiggerJobID, err)
+		}
+	}
+}
+
+func scheduleQueuedJob(job *models.DiggerJob) {
+	batch := job.Batch
+	service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, batch.GithubInstallationId, batch.RepoFullName, batch.RepoOwner, batch.RepoName)
+	if err != nil {
+		log.Printf("Failed to get github service: %v", err)
+		return
+	}
+
+	ciBackend := ci_backends.GithubActionCi{Client: service.Client}
+	services.ScheduleJob(ciBackend, batch.RepoFullName, batch.RepoOwner, batch.RepoName, &batch.ID, job, &utils.DiggerGithubRealClientProvider{})
+}
+
+// processLostJobs retries or fails the jobs whose CI run didn't start within job_start_timeout, for instance
+// because their runner was evicted
+func processLostJobs(ctx context.Context) {
+	timeout := config.DiggerConfig.GetDuration("job_start_timeout")
+	jobs, err := models.DB.GetDiggerJobsTriggeredBefore(time.Now().Add(-timeout))
+	if err != nil {
+		log.Printf("Failed to get jobs that didn't start: %v", err)
+		return
+	}
+
+	lease := config.DiggerConfig.GetDuration("scheduler_claim_lease")
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		claimed, err := models.DB.ClaimDiggerJob(&job, lease)
+		if err != nil {
+			log.Printf("failed to claim job %v: %v", job.DiggerJobID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		handleLostJob(&job, timeout)
+		err = models.DB.ReleaseDiggerJob(&job)
+		if err != nil {
+			log.Printf("failed to release job %v: %v", job.DiggerJobID, err)
+		}
+	}
+}
+
+func handleLostJob(job *models.DiggerJob, timeout time.Duration) {
+	log.Printf("job %v didn't start within %v of being triggered", job.DiggerJobID, timeout)
+	ciBackend, err := ciBackendForJob(job)
+	if err != nil {
+		log.Printf("Failed to get CI backend: %v", err)
+	} else {
+		// a run starting late must not run the job a second time
+		err = services.CancelJobWorkflow(ciBackend, *job)
+		if err != nil {
+			log.Printf("could not cancel workflow of job %v: %v", job.DiggerJobID, err)
+		}
+	}
+
+	err = services.FailLostJob(job)
+	if err != nil {
+		log.Printf("could not handle lost job %v: %v", job.DiggerJobID, err)
+	}
+}
+
+// errUnknownCiBackend is returned for the jobs whose CI backend the scheduler can't resolve
+var errUnknownCiBackend = errors.New("unknown CI backend")
+
+// ciBackendForJob returns the CI backend running the job, from the VCS of its batch. Only the batches of github
+// run on a CI backend that the scheduler can resolve, the other ones return errUnknownCiBackend.
+func ciBackendForJob(job *models.DiggerJob) (ci_backends.CiBackend, error) {
+	batch := job.Batch
+	if batch.VCS != models.DiggerVCSGithub {
+		return nil, fmt.Errorf("%w for batches of %v", errUnknownCiBackend, batch.VCS)
+	}
+	service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, batch.GithubInstallationId, batch.RepoFullName, batch.RepoOwner, batch.RepoName)
+	if err != nil {
+		return nil, err
+	}
+	return ci_backends.GithubActionCi{Client: service.Client}, nil
+}
+
+// jobHeartbeatTimeout returns how long a started job of a batch of batchType can go without a heartbeat
+func jobHeartbeatTimeout(batchType orchestrator_scheduler.DiggerCommand) time.Duration {
+	if batchType == orchestrator_scheduler.DiggerCommandApply {
+		return config.DiggerConfig.GetDuration("job_apply_heartbeat_timeout")
+	}
+	return config.DiggerConfig.GetDuration("job_plan_heartbeat_timeout")
+}
+
+// processSilentJobs reaps the started jobs that stopped sending heartbeats, for instance because their runner
+// died, so that they don't hold concurrency slots forever
+func processSilentJobs(ctx context.Context) {
+	minTimeout := min(config.DiggerConfig.GetDuration("job_plan_heartbeat_timeout"), config.DiggerConfig.GetDuration("job_apply_heartbeat_timeout"))
+	jobs, err := models.DB.GetDiggerJobsSilentSince(time.Now().Add(-minTimeout))
+	if err != nil {
+		log.Printf("Failed to get silent jobs: %v", err)
+		return
+	}
+
+	lease := config.DiggerConfig.GetDuration("scheduler_claim_lease")
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if job.Batch == nil || jobLastSeenAt(&job).After(time.Now().Add(-jobHeartbeatTimeout(job.Batch.BatchType))) {
+			continue
+		}
+		claimed, err := models.DB.ClaimDiggerJob(&job, lease)
+		if err != nil {
+			log.Printf("failed to claim job %v: %v", job.DiggerJobID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		ciBackend, err := ciBackendForJob(&job)
+		if errors.Is(err, errUnknownCiBackend) {
+			// the run can't be checked, the heartbeat timeout is all there is to go by
+			reapSilentJob(&job, nil)
+		} else if err != nil {
+			log.Printf("Failed to get CI backend: %v", err)
+		} else {
+			reapSilentJob(&job, ciBackend)
+		}
+		err = models.DB.ReleaseDiggerJob(&job)
+		if err != nil {
+			log.Printf("failed to release job %v: %v", job.DiggerJobID, err)
+		}
+	}
+}
+
+// jobLastSeenAt returns the last time a started job reported a status or a heartbeat
+func jobLastSeenAt(job *models.DiggerJob) time.Time {
+	if job.HeartbeatAt != nil && job.HeartbeatAt.After(job.StatusUpdatedAt) {
+		return *job.HeartbeatAt
+	}
+	return job.StatusUpdatedAt
+}
+
+// reapSilentJob fails a silent job unless the CI backend reports its run as still running, which is the case
+// for clis that don't send heartbeats. A job without a CI backend to check is reaped.
+func reapSilentJob(job *models.DiggerJob, ciBackend ci_backends.CiBackend) {
+	if ciBackend != nil {
+		spec, err := services.GetSpecFromJob(*job)
+		if err != nil {
+			log.Printf("could not get spec of job %v: %v", job.DiggerJobID, err)
+			return
+		}
+		workflowRunUrl := "#"
+		if job.WorkflowRunUrl != nil {
+			workflowRunUrl = *job.WorkflowRunUrl
+		}
+		running, err := ciBackend.IsWorkflowRunning(*spec, workflowRunUrl)
+		if err != nil {
+			// the CI backend can't be reached, the job is checked again on the next pass
+			log.Printf("could not get workflow status of job %v: %v", job.DiggerJobID, err)
+			return
+		}
+		if running {
+			log.Printf("job %v didn't send a heartbeat since %v but its workflow is still running", job.DiggerJobID, jobLastSeenAt(job))
+			return
+		}
+	}
+
+	log.Printf("job %v didn't send a heartbeat since %v and its workflow isn't known to be running, reaping it", job.DiggerJobID, jobLastSeenAt(job))
+	err := services.FailLostJob(job)
+	if err != nil {
+		log.Printf("could not reap job %v: %v", job.DiggerJobID, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/digger_config"
+	orchestrator_scheduler "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestReapSilentJob(t *testing.T) {
+	teardownSuite, _ := setupSuite(t)
+	defer teardownSuite(t)
+
+	commentId := int64(1)
+	prNumber := 22
+	jobSpec, err := json.Marshal(orchestrator_scheduler.JobJson{ProjectName: "test", PullRequestNumber: &prNumber})
+	assert.NoError(t, err)
+
+	createStartedJob := func() *models.DiggerJob {
+		batch, err := models.DB.CreateDiggerBatch(models.DiggerVCSGithub, 123, "owner", "repo", "owner/repo", prNumber, "", "main", orchestrator_scheduler.DiggerCommandApply, &commentId, 0)
+		assert.NoError(t, err)
+		job, err := models.DB.CreateDiggerJob(batch.ID, jobSpec, "digger_workflow.yml")
+		assert.NoError(t, err)
+		job.Status = orchestrator_scheduler.DiggerJobStarted
+		job.StatusUpdatedAt = time.Now().Add(-time.Hour)
+		assert.NoError(t, models.DB.UpdateDiggerJob(job))
+		job, err = models.DB.GetDiggerJob(job.DiggerJobID)
+		assert.NoError(t, err)
+		return job
+	}
+
+	lock := locking.BackendDBLock{}
+	_, err = lock.Lock(prNumber, "owner/repo#test")
+	assert.NoError(t, err)
+
+	// the workflow of a cli not sending heartbeats is still running
+	job := createStartedJob()
+	ciBackend := &MockCiBackend{RunningJobs: map[string]bool{job.DiggerJobID: true}}
+	reapSilentJob(job, ciBackend)
+	job, err = models.DB.GetDiggerJob(job.DiggerJobID)
+	assert.NoError(t, err)
+	assert.Equal(t, orchestrator_scheduler.DiggerJobStarted, job.Status)
+	lockId, err := lock.GetLock("owner/repo#test")
+	assert.NoError(t, err)
+	assert.NotNil(t, lockId)
+
+	// the runner died
+	job = createStartedJob()
+	reapSilentJob(job, &MockCiBackend{})
+	job, err = models.DB.GetDiggerJob(job.DiggerJobID)
+	assert.NoError(t, err)
+	assert.Equal(t, orchestrator_scheduler.DiggerJobFailed, job.Status)
+	assert.Equal(t, digger_config.FailureClassRunnerLost, job.FailureClass)
+	lockId, err = lock.GetLock("owner/repo#test")
+	assert.NoError(t, err)
+	assert.Nil(t, lockId)
+
+	// the CI backend of the job can't be checked
+	job = createStartedJob()
+	reapSilentJob(job, nil)
+	job, err = models.DB.GetDiggerJob(job.DiggerJobID)
+	assert.NoError(t, err)
+	assert.Equal(t, orchestrator_scheduler.DiggerJobFailed, job.Status)
+}
+
+func TestCiBackendForJobOfOtherVCS(t *testing.T) {
+	job := &models.DiggerJob{Batch: &models.DiggerBatch{VCS: models.DiggerVCSGitlab}}
+	_, err := ciBackendForJob(job)
+	assert.ErrorIs(t, err, errUnknownCiBackend)
+}
+
+func TestJobLastSeenAt(t *testing.T) {
+	statusUpdatedAt := time.Now().Add(-time.Hour)
+	job := models.DiggerJob{StatusUpdatedAt: statusUpdatedAt}
+	assert.Equal(t, statusUpdatedAt, jobLastSeenAt(&job))
+
+	heartbeatAt := time.Now().Add(-time.Minute)
+	job.HeartbeatAt = &heartbeatAt
+	assert.Equal(t, heartbeatAt, jobLastSeenAt(&job))
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
-	"github.com/diggerhq/digger/backend/ci_backends"
+	"context"
+	"github.com/diggerhq/digger/backend/config"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/models"
-	"github.com/diggerhq/digger/backend/services"
-	"github.com/diggerhq/digger/backend/utils"
-	"github.com/diggerhq/digger/libs/scheduler"
-	"github.com/robfig/cron"
+	"github.com/diggerhq/digger/libs/tracing"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 func initLogging() {
@@ -21,57 +22,19 @@ func main() {
 	initLogging()
 	models.ConnectDatabase()
 
-	c := cron.New()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// RunQueues state machine
-	c.AddFunc("0 * * * * *", func() {
-		runQueues, err := models.DB.GetFirstRunQueueForEveryProject()
-		if err != nil {
-			log.Printf("Error fetching Latest queueItem runs: %v", err)
-			return
-		}
+	go metrics.Serve(config.DiggerConfig.GetString("worker_metrics_address"))
 
-		for _, queueItem := range runQueues {
-			dr := queueItem.DiggerRun
-			repo := dr.Repo
-			repoFullName := repo.RepoFullName
-			repoOwner := repo.RepoOrganisation
-			repoName := repo.RepoName
-			service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, dr.GithubInstallationId, repoFullName, repoOwner, repoName)
-			if err != nil {
-				log.Printf("failed to get github service for DiggerRun ID: %v: %v", dr.ID, err)
-				continue
-			}
-			RunQueuesStateMachine(&queueItem, service, &utils.DiggerGithubRealClientProvider{})
-		}
-	})
-
-	// Triggered queued jobs for a batch
-	c.AddFunc("30 * * * * *", func() {
-		jobs, err := models.DB.GetDiggerJobsWithStatus(scheduler.DiggerJobQueuedForRun)
-		if err != nil {
-			log.Printf("Failed to get Jobs %v", err)
-		}
-		for _, job := range jobs {
-			batch := job.Batch
-			repoFullName := batch.RepoFullName
-			repoName := batch.RepoName
-			repoOwner := batch.RepoOwner
-			githubInstallationid := batch.GithubInstallationId
-			service, _, err := utils.GetGithubService(&utils.DiggerGithubRealClientProvider{}, githubInstallationid, repoFullName, repoOwner, repoName)
-			if err != nil {
-				log.Printf("Failed to get github service: %v", err)
-			}
-
-			ciBackend := ci_backends.GithubActionCi{Client: service.Client}
-			services.ScheduleJob(ciBackend, repoFullName, repoOwner, repoName, &batch.ID, &job, &utils.DiggerGithubRealClientProvider{})
-		}
-	})
-
-	// Start the Cron job scheduler
-	c.Start()
-
-	for {
+	if err := tracing.Init(ctx, "digger-scheduler"); err != nil {
+		log.Printf("Tracing initialization failed: %v", err)
 	}
+	defer tracing.Shutdown(context.Background())
+
+	wake := make(chan struct{}, 1)
+	go listenForNotifications(ctx, os.Getenv("DATABASE_URL"), models.SchedulerChannel, wake)
 
+	runScheduler(ctx, wake, config.DiggerConfig.GetDuration("scheduler_poll_interval"))
+	log.Println("Scheduler stopped")
 }
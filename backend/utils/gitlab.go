@@ -8,7 +8,6 @@ import (
 	"github.com/xanzy/go-gitlab"
 	"log"
 	"os"
-	"path"
 )
 
 type GitlabProvider interface {
@@ -63,13 +62,18 @@ func GetDiggerConfigForBranch(gh GitlabProvider, projectId int, repoFullName str
 		return "", nil, nil, fmt.Errorf("error getting changed files")
 	}
 	err = CloneGitRepoAndDoAction(cloneUrl, branch, token, func(dir string) error {
-		diggerYmlBytes, err := os.ReadFile(path.Join(dir, "digger.yml"))
-		diggerYmlStr = string(diggerYmlBytes)
+		var err error
 		config, _, dependencyGraph, err = dg_configuration.LoadDiggerConfig(dir, true, changedFiles)
 		if err != nil {
 			log.Printf("Error loading digger config: %v", err)
 			return err
 		}
+		// the stored config is loaded later without the repository, so includes and project files are merged in
+		diggerYmlStr, err = dg_configuration.LoadMergedDiggerConfigString(dir)
+		if err != nil {
+			log.Printf("Error merging digger config: %v", err)
+			return err
+		}
 		return nil
 	})
 	if err != nil {
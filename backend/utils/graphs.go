@@ -140,6 +140,17 @@ func TraverseGraphVisitAllParentsFirst(g graph.Graph[string, configuration.Proje
 	return graph.BFS(g, dummyParent.Name, visitIgnoringDummyParent)
 }
 
+// ProjectsToRunMap returns by name the projects a comment runs jobs for, the requested projects if it selected
+// some and the impacted projects otherwise
+func ProjectsToRunMap(impactedProjects []configuration.Project, requestedProjects []configuration.Project) map[string]configuration.Project {
+	projects, _ := scheduler.ProjectsToRun(impactedProjects, requestedProjects)
+	projectMap := make(map[string]configuration.Project)
+	for _, p := range projects {
+		projectMap[p.Name] = p
+	}
+	return projectMap
+}
+
 func ImpactedProjectsOnlyGraph(projectsGraph graph.Graph[string, configuration.Project], impactedProjectMap map[string]configuration.Project) (graph.Graph[string, configuration.Project], error) {
 	adjacencyMap, err := projectsGraph.AdjacencyMap()
 	if err != nil {
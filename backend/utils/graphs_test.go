@@ -1,7 +1,15 @@
 package utils
 
 import (
+	"encoding/json"
+	"github.com/diggerhq/digger/backend/models"
+	"github.com/diggerhq/digger/libs/ci/generic"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/scheduler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"sort"
 	"testing"
 )
 
@@ -215,3 +223,60 @@ func TestTraverseGraphVisitAllParentsFirst(t *testing.T) {
 		t.Errorf("Expected root4 to be visited before child4")
 	}
 }
+
+func TestConvertJobsToDiggerJobsForComment(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	err = gdb.AutoMigrate(&models.Organisation{}, &models.JobToken{}, &models.DiggerBatch{}, &models.DiggerJob{},
+		&models.DiggerJobSummary{}, &models.GithubDiggerJobLink{})
+	assert.NoError(t, err)
+	defer func(previous *models.Database) { models.DB = previous }(models.DB)
+	models.DB = &models.Database{GormDB: gdb}
+
+	org, err := models.DB.CreateOrganisation("org", "test", "11111111-1111-1111-1111-111111111111")
+	assert.NoError(t, err)
+
+	dev := configuration.Project{Name: "dev", Dir: "dev", Workflow: "default"}
+	staging := configuration.Project{Name: "staging", Dir: "staging", Workflow: "default"}
+	prod := configuration.Project{Name: "prod", Dir: "prod", Workflow: "default"}
+	allProjects := []configuration.Project{dev, staging, prod}
+	workflows := map[string]configuration.Workflow{"default": configuration.Workflow{}}
+	projectsGraph, err := configuration.CreateProjectDependencyGraph(allProjects)
+	assert.NoError(t, err)
+
+	// prod is not impacted by the changes of the pull request
+	impactedProjects := []configuration.Project{dev, staging}
+
+	testCases := []struct {
+		comment  string
+		projects []string
+	}{
+		{comment: "digger plan --all", projects: []string{"dev", "prod", "staging"}},
+		{comment: "digger plan -p staging", projects: []string{"staging"}},
+		{comment: "digger plan", projects: []string{"dev", "staging"}},
+	}
+	for _, tc := range testCases {
+		requestedProjects, err := scheduler.SelectRequestedProjects(tc.comment, impactedProjects, allProjects)
+		assert.NoError(t, err)
+		jobs, _, err := generic.ConvertIssueCommentEventToJobs("acme/infra", "alice", 7, tc.comment, impactedProjects, requestedProjects, workflows, "feature", "main")
+		assert.NoError(t, err)
+		jobsMap := make(map[string]scheduler.Job)
+		for _, j := range jobs {
+			jobsMap[j.ProjectName] = j
+		}
+
+		batchId, _, err := ConvertJobsToDiggerJobs(scheduler.DiggerCommandPlan, models.DiggerVCSGithub, org.ID, jobsMap, ProjectsToRunMap(impactedProjects, requestedProjects), projectsGraph, 1, "feature", 7, "acme", "infra", "acme/infra", "sha", 1, "", 0)
+		assert.NoError(t, err)
+
+		diggerJobs, err := models.DB.GetDiggerJobsForBatch(*batchId)
+		assert.NoError(t, err)
+		projects := make([]string, 0)
+		for _, j := range diggerJobs {
+			var jobSpec scheduler.JobJson
+			assert.NoError(t, json.Unmarshal(j.SerializedJobSpec, &jobSpec), tc.comment)
+			projects = append(projects, jobSpec.ProjectName)
+		}
+		sort.Strings(projects)
+		assert.Equal(t, tc.projects, projects, tc.comment)
+	}
+}
@@ -38,11 +38,22 @@ func ReportInitialJobsStatus(cr *CommentReporter, jobs []scheduler.Job) error {
 	if len(jobs) == 0 {
 		message = message + ":construction_worker: No projects impacted"
 	} else {
-		message = message + fmt.Sprintf("| Project | Status |\n")
-		message = message + fmt.Sprintf("|---------|--------|\n")
-		for _, job := range jobs {
-			message = message + fmt.Sprintf(""+
-				"|:clock11: **%v**|pending...|\n", job.ProjectName)
+		environments := make([]string, len(jobs))
+		for i, job := range jobs {
+			environments[i] = job.ProjectEnvironment
+		}
+		order, groups := scheduler.GroupByEnvironment(environments)
+		for _, environment := range order {
+			if len(order) > 1 || environment != "" {
+				message = message + scheduler.EnvironmentHeading(environment)
+			}
+			message = message + fmt.Sprintf("| Project | Status |\n")
+			message = message + fmt.Sprintf("|---------|--------|\n")
+			for _, i := range groups[environment] {
+				message = message + fmt.Sprintf(""+
+					"|:clock11: **%v**|pending...|\n", jobs[i].ProjectName)
+			}
+			message = message + "\n"
 		}
 	}
 	err := prService.EditComment(prNumber, commentId, message)
@@ -49,8 +49,8 @@ func exec(actor string, projectName string, repoNamespace string, command string
 		usage.ReportErrorAndExit(actor, fmt.Sprintf("Failed to convert impacted projects to commands. %s", err), 4)
 	}
 
-	jobs = digger.SortedCommandsByDependency(jobs, &dependencyGraph)
-	_, _, err = digger.RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, "123", currentDir)
+	layers := digger.SortedCommandLayersByDependency(jobs, &dependencyGraph)
+	_, _, err = digger.RunJobLayers(layers, diggerConfig.MaxParallelism, prService, orgService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, "123", currentDir)
 }
 
 /*
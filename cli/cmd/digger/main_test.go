@@ -1020,10 +1020,10 @@ func TestGitHubTestPRCommandCaseInsensitivity(t *testing.T) {
 	var impactedProjects []configuration.Project
 	impactedProjects = make([]configuration.Project, 1)
 	impactedProjects[0] = project
-	var requestedProject = project
+	requestedProjects := []configuration.Project{project}
 	workflows := make(map[string]configuration.Workflow, 1)
 	workflows["default"] = configuration.Workflow{}
-	jobs, _, err := generic.ConvertIssueCommentEventToJobs("", "", 0, "digger plan", impactedProjects, &requestedProject, workflows, "prbranch", "main")
+	jobs, _, err := generic.ConvertIssueCommentEventToJobs("", "", 0, "digger plan", impactedProjects, requestedProjects, workflows, "prbranch", "main")
 
 	assert.Equal(t, 1, len(jobs))
 	assert.Equal(t, "digger plan", jobs[0].Commands[0])
@@ -10,8 +10,10 @@ import (
 	"github.com/diggerhq/digger/libs/execution"
 	locking2 "github.com/diggerhq/digger/libs/locking"
 	"github.com/diggerhq/digger/libs/policy"
+	"github.com/diggerhq/digger/libs/redact"
 	orchestrator "github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/storage"
+	"io"
 	"log"
 	"os"
 	"path"
@@ -26,6 +28,7 @@ import (
 	"github.com/diggerhq/digger/libs/terraform_utils"
 
 	"github.com/dominikbraun/graph"
+	"github.com/samber/lo"
 )
 
 type CIName string
@@ -65,55 +68,76 @@ func DetectCI() CIName {
 }
 
 func RunJobs(jobs []orchestrator.Job, prService ci.PullRequestService, orgService ci.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backendapi.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId string, workingDir string) (bool, bool, error) {
+	layers := make([][]orchestrator.Job, 0, len(jobs))
+	for _, job := range jobs {
+		layers = append(layers, []orchestrator.Job{job})
+	}
+	return RunJobLayers(layers, 1, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, jobId, reportFinalStatusToBackend, reportTerraformOutput, prCommentId, workingDir)
+}
+
+// RunJobsWithFailureOutput runs the jobs like RunJobs, it also returns the error and the end of the output of the
+// command that failed, if any, for the backend to tell transient failures apart
+func RunJobsWithFailureOutput(jobs []orchestrator.Job, prService ci.PullRequestService, orgService ci.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backendapi.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId string, workingDir string) (bool, bool, string, error) {
+	layers := make([][]orchestrator.Job, 0, len(jobs))
+	for _, job := range jobs {
+		layers = append(layers, []orchestrator.Job{job})
+	}
+	return runJobLayers(layers, 1, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, jobId, reportFinalStatusToBackend, reportTerraformOutput, prCommentId, workingDir)
+}
+
+// RunJobLayers runs the jobs of a layer, up to maxParallelism of them at the same time, and waits for them to
+// complete before starting the next layer
+func RunJobLayers(layers [][]orchestrator.Job, maxParallelism int, prService ci.PullRequestService, orgService ci.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backendapi.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId string, workingDir string) (bool, bool, error) {
+	allAppliesSuccess, atLeastOneApply, _, err := runJobLayers(layers, maxParallelism, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, jobId, reportFinalStatusToBackend, reportTerraformOutput, prCommentId, workingDir)
+	return allAppliesSuccess, atLeastOneApply, err
+}
+
+func runJobLayers(layers [][]orchestrator.Job, maxParallelism int, prService ci.PullRequestService, orgService ci.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, commentUpdater comment_updater.CommentUpdater, backendApi backendapi.Api, jobId string, reportFinalStatusToBackend bool, reportTerraformOutput bool, prCommentId string, workingDir string) (bool, bool, string, error) {
 
 	defer reporter.Flush()
+	if maxParallelism > 1 {
+		reporter = reporting.NewSynchronizedReporter(reporter)
+	}
 
 	runStartedAt := time.Now()
 
+	jobs := lo.Flatten(layers)
 	exectorResults := make([]execution.DiggerExecutorResult, len(jobs))
 	appliesPerProject := make(map[string]bool)
-
-	for i, job := range jobs {
-		splits := strings.Split(job.Namespace, "/")
-		SCMOrganisation := splits[0]
-		SCMrepository := splits[1]
-
-		for _, command := range job.Commands {
-			allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, job.RequestedBy, []string{})
-
-			if err != nil {
-				return false, false, fmt.Errorf("error checking policy: %v", err)
+	failureOutput := ""
+
+	offset := 0
+	for _, layer := range layers {
+		results, err := runJobLayer(layer, maxParallelism, workingDir, func(job orchestrator.Job, jobWorkingDir string, outputPrefix string) jobResult {
+			var logWriter io.Writer
+			if reportFinalStatusToBackend {
+				// the output is streamed to the live logs of the job while it runs
+				streamer := backendapi.NewJobLogStreamer(backendApi, strings.ReplaceAll(job.Namespace, "/", "-"), job.ProjectName, jobId)
+				defer streamer.Close()
+				logWriter = streamer
 			}
-
-			if !allowedToPerformCommand {
-				msg := reportPolicyError(job.ProjectName, command, job.RequestedBy, reporter)
-				log.Printf("Skipping command ... %v for project %v", command, job.ProjectName)
-				log.Println(msg)
-				appliesPerProject[job.ProjectName] = false
-				continue
+			return runJob(job, prService, orgService, lock, reporter, planStorage, policyChecker, backendApi, jobWorkingDir, outputPrefix, logWriter, runStartedAt)
+		})
+		if err != nil {
+			return false, false, "", err
+		}
+		for i, result := range results {
+			if result.executorResult != nil {
+				exectorResults[offset+i] = *result.executorResult
 			}
-
-			executorResult, output, err := run(command, job, policyChecker, orgService, SCMOrganisation, SCMrepository, job.PullRequestNumber, job.RequestedBy, reporter, lock, prService, job.Namespace, workingDir, planStorage, appliesPerProject)
-			if err != nil {
-				log.Printf("error while running command %v for project %v: %v", command, job.ProjectName, err)
-				reportErr := backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "FAILED", command, output)
-				if reportErr != nil {
-					log.Printf("error reporting project Run err: %v.\n", reportErr)
-				}
-				appliesPerProject[job.ProjectName] = false
-				if executorResult != nil {
-					exectorResults[i] = *executorResult
-				}
-				log.Printf("Project %v command %v failed, skipping job", job.ProjectName, command)
-				break
+			for project, success := range result.applies {
+				appliesPerProject[project] = success
 			}
-			exectorResults[i] = *executorResult
-
-			err = backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "SUCCESS", command, output)
-			if err != nil {
-				log.Printf("Error reporting project Run: %v", err)
+			if failureOutput == "" {
+				failureOutput = result.failure
+			}
+		}
+		for _, result := range results {
+			if result.err != nil {
+				return false, false, "", result.err
 			}
 		}
+		offset += len(layer)
 	}
 
 	allAppliesSuccess := true
@@ -127,7 +151,7 @@ func RunJobs(jobs []orchestrator.Job, prService ci.PullRequestService, orgServic
 		_, jobPrCommentUrl, err := reporter.Flush()
 		if err != nil {
 			log.Printf("error while sending job comments %v", err)
-			return false, false, fmt.Errorf("error while sending job comments %v", err)
+			return false, false, "", fmt.Errorf("error while sending job comments %v", err)
 		}
 
 		currentJob := jobs[0]
@@ -140,29 +164,106 @@ func RunJobs(jobs []orchestrator.Job, prService ci.PullRequestService, orgServic
 		if reportTerraformOutput {
 			terraformOutput = exectorResults[0].TerraformOutput
 		}
+		var projectOutputs map[string]string
+		if exectorResults[0].ApplyResult != nil {
+			projectOutputs = exectorResults[0].ApplyResult.Outputs
+		}
 		prNumber := *currentJob.PullRequestNumber
-		batchResult, err := backendApi.ReportProjectJobStatus(repoNameForBackendReporting, projectNameForBackendReporting, jobId, "succeeded", time.Now(), &summary, "", jobPrCommentUrl, terraformOutput)
+		batchResult, err := backendApi.ReportProjectJobStatus(repoNameForBackendReporting, projectNameForBackendReporting, jobId, "succeeded", time.Now(), &summary, "", jobPrCommentUrl, terraformOutput, projectOutputs)
 		if err != nil {
 			log.Printf("error reporting Job status: %v.\n", err)
-			return false, false, fmt.Errorf("error while running command: %v", err)
+			return false, false, "", fmt.Errorf("error while running command: %v", err)
 		}
 
 		err = commentUpdater.UpdateComment(batchResult.Jobs, prNumber, prService, prCommentId)
 		if err != nil {
 			log.Printf("error Updating status comment: %v.\n", err)
-			return false, false, err
+			return false, false, "", err
 		}
 		err = UpdateAggregateStatus(batchResult, prService)
 		if err != nil {
 			log.Printf("error udpating aggregate status check: %v.\n", err)
-			return false, false, err
+			return false, false, "", err
 		}
 
 	}
 
 	atLeastOneApply := len(appliesPerProject) > 0
 
-	return allAppliesSuccess, atLeastOneApply, nil
+	return allAppliesSuccess, atLeastOneApply, failureOutput, nil
+}
+
+type jobResult struct {
+	executorResult *execution.DiggerExecutorResult
+	// applies records per project whether its applies succeeded
+	applies map[string]bool
+	// failure describes the command that failed the job, if any
+	failure string
+	err     error
+}
+
+// runJob runs the commands of a job until one of them fails
+func runJob(job orchestrator.Job, prService ci.PullRequestService, orgService ci.OrgService, lock locking2.Lock, reporter reporting.Reporter, planStorage storage.PlanStorage, policyChecker policy.Checker, backendApi backendapi.Api, workingDir string, outputPrefix string, logWriter io.Writer, runStartedAt time.Time) jobResult {
+	splits := strings.Split(job.Namespace, "/")
+	SCMOrganisation := splits[0]
+	SCMrepository := splits[1]
+
+	result := jobResult{applies: make(map[string]bool)}
+	for _, command := range job.Commands {
+		allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, projectMetadata(job), command, job.PullRequestNumber, job.RequestedBy, []string{})
+
+		if err != nil {
+			result.err = fmt.Errorf("error checking policy: %v", err)
+			return result
+		}
+
+		if !allowedToPerformCommand {
+			msg := reportPolicyError(job.ProjectName, command, job.RequestedBy, reporter)
+			log.Printf("Skipping command ... %v for project %v", command, job.ProjectName)
+			log.Println(msg)
+			result.applies[job.ProjectName] = false
+			continue
+		}
+
+		executorResult, output, err := run(command, job, policyChecker, orgService, SCMOrganisation, SCMrepository, job.PullRequestNumber, job.RequestedBy, reporter, lock, prService, job.Namespace, workingDir, outputPrefix, logWriter, planStorage, result.applies)
+		if err != nil {
+			log.Printf("error while running command %v for project %v: %v", command, job.ProjectName, err)
+			reportErr := backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "FAILED", command, output)
+			if reportErr != nil {
+				log.Printf("error reporting project Run err: %v.\n", reportErr)
+			}
+			result.applies[job.ProjectName] = false
+			if executorResult != nil {
+				result.executorResult = executorResult
+			}
+			result.failure = commandFailure(job.ProjectName, command, err, output)
+			log.Printf("Project %v command %v failed, skipping job", job.ProjectName, command)
+			break
+		}
+		result.executorResult = executorResult
+
+		err = backendApi.ReportProjectRun(SCMOrganisation+"-"+SCMrepository, job.ProjectName, runStartedAt, time.Now(), "SUCCESS", command, output)
+		if err != nil {
+			log.Printf("Error reporting project Run: %v", err)
+		}
+	}
+	return result
+}
+
+// number of lines of the output of a failed command reported to the backend
+const failureOutputLines = 50
+
+// commandFailure describes a failed command with its error and the end of its output
+func commandFailure(projectName string, command string, err error, output string) string {
+	details := err.Error()
+	if output != "" && !strings.Contains(details, output) {
+		details = details + "\n" + output
+	}
+	lines := strings.Split(strings.TrimSpace(details), "\n")
+	if len(lines) > failureOutputLines {
+		lines = lines[len(lines)-failureOutputLines:]
+	}
+	return fmt.Sprintf("%v failed for project %v:\n%v", command, projectName, strings.Join(lines, "\n"))
 }
 
 func reportPolicyError(projectName string, command string, requestedBy string, reporter reporting.Reporter) string {
@@ -181,10 +282,10 @@ func reportPolicyError(projectName string, command string, requestedBy string, r
 	return msg
 }
 
-func run(command string, job orchestrator.Job, policyChecker policy.Checker, orgService ci.OrgService, SCMOrganisation string, SCMrepository string, PRNumber *int, requestedBy string, reporter reporting.Reporter, lock locking2.Lock, prService ci.PullRequestService, projectNamespace string, workingDir string, planStorage storage.PlanStorage, appliesPerProject map[string]bool) (*execution.DiggerExecutorResult, string, error) {
+func run(command string, job orchestrator.Job, policyChecker policy.Checker, orgService ci.OrgService, SCMOrganisation string, SCMrepository string, PRNumber *int, requestedBy string, reporter reporting.Reporter, lock locking2.Lock, prService ci.PullRequestService, projectNamespace string, workingDir string, outputPrefix string, logWriter io.Writer, planStorage storage.PlanStorage, appliesPerProject map[string]bool) (*execution.DiggerExecutorResult, string, error) {
 	log.Printf("Running '%s' for project '%s' (workflow: %s)\n", command, job.ProjectName, job.ProjectWorkflow)
 
-	allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, requestedBy, []string{})
+	allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, projectMetadata(job), command, job.PullRequestNumber, requestedBy, []string{})
 
 	if err != nil {
 		return nil, "error checking policy", fmt.Errorf("error checking policy: %v", err)
@@ -213,14 +314,14 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 	var terraformExecutor execution.TerraformExecutor
 	projectPath := path.Join(workingDir, job.ProjectDir)
 	if job.Terragrunt {
-		terraformExecutor = execution.Terragrunt{WorkingDir: projectPath}
+		terraformExecutor = execution.Terragrunt{WorkingDir: projectPath, OutputPrefix: outputPrefix, LogWriter: logWriter}
 	} else if job.OpenTofu {
-		terraformExecutor = execution.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+		terraformExecutor = execution.OpenTofu{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, OutputPrefix: outputPrefix, LogWriter: logWriter}
 	} else {
-		terraformExecutor = execution.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace}
+		terraformExecutor = execution.Terraform{WorkingDir: projectPath, Workspace: job.ProjectWorkspace, OutputPrefix: outputPrefix, LogWriter: logWriter}
 	}
 
-	commandRunner := execution.CommandRunner{}
+	commandRunner := execution.CommandRunner{OutputPrefix: outputPrefix, LogWriter: logWriter}
 	planPathProvider := execution.ProjectPathProvider{
 		ProjectPath:      projectPath,
 		ProjectNamespace: projectNamespace,
@@ -275,7 +376,7 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 		} else if planPerformed {
 			if isNonEmptyPlan {
 				reportTerraformPlanOutput(reporter, projectLock.LockId(), plan)
-				planIsAllowed, messages, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
+				planIsAllowed, violations, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, projectMetadata(job), planJsonOutput)
 				if err != nil {
 					msg := fmt.Sprintf("Failed to validate plan. %v", err)
 					log.Printf(msg)
@@ -294,13 +395,16 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 					log.Printf("Failed to summarize plan. %v", err)
 				}
 
+				warnings := policy.Warnings(violations)
+				// denies of policies in audit mode are only informational
+				for _, audited := range policy.Audited(violations) {
+					warnings = append(warnings, fmt.Sprintf(":information_source: (audit mode, not enforced) %v", audited))
+				}
 				if !planIsAllowed {
-					planReportMessage := "Terraform plan failed validation checks :x:<br>"
-					preformattedMessaged := make([]string, 0)
-					for _, message := range messages {
-						preformattedMessaged = append(preformattedMessaged, fmt.Sprintf("    %v", message))
+					planReportMessage := "Terraform plan failed validation checks :x:<br>" + formatPolicyMessages(policy.Denies(violations))
+					if len(warnings) > 0 {
+						planReportMessage = planReportMessage + "<br>Warnings :warning:<br>" + formatPolicyMessages(warnings)
 					}
-					planReportMessage = planReportMessage + strings.Join(preformattedMessaged, "<br>")
 					_, _, err = reporter.Report(planReportMessage, planPolicyFormatter)
 
 					if err != nil {
@@ -309,6 +413,13 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 					msg := fmt.Sprintf("Plan is not allowed")
 					log.Printf(msg)
 					return nil, msg, fmt.Errorf(msg)
+				} else if len(warnings) > 0 {
+					planReportMessage := "Terraform plan validation checks succeeded with warnings :warning:<br>" + formatPolicyMessages(warnings)
+					_, _, err := reporter.Report(planReportMessage, planPolicyFormatter)
+					if err != nil {
+						log.Printf("Failed to report plan. %v", err)
+					}
+					reportPlanSummary(reporter, planSummary)
 				} else {
 					_, _, err := reporter.Report("Terraform plan validation checks succeeded :white_check_mark:", planPolicyFormatter)
 					if err != nil {
@@ -316,6 +427,7 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 					}
 					reportPlanSummary(reporter, planSummary)
 				}
+				reportProjectOwners(reporter, job)
 			} else {
 				reportEmptyPlanOutput(reporter, projectLock.LockId())
 			}
@@ -377,19 +489,19 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 					return nil, msg, fmt.Errorf(msg)
 				}
 
-				_, violations, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, terraformPlanJsonStr)
+				_, violations, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, projectMetadata(job), terraformPlanJsonStr)
 				if err != nil {
 					msg := fmt.Sprintf("Failed to check plan policy. %v", err)
 					log.Printf(msg)
 					return nil, msg, fmt.Errorf(msg)
 				}
-				planPolicyViolations = violations
+				planPolicyViolations = policy.Denies(violations)
 			} else {
 				log.Printf("Skipping plan policy checks because plan storage is not configured.")
 				planPolicyViolations = []string{}
 			}
 
-			allowedToApply, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, job.PullRequestNumber, requestedBy, planPolicyViolations)
+			allowedToApply, err := policyChecker.CheckAccessPolicy(orgService, &prService, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, projectMetadata(job), command, job.PullRequestNumber, requestedBy, planPolicyViolations)
 			if err != nil {
 				msg := fmt.Sprintf("Failed to run plan policy check before apply. %v", err)
 				log.Printf(msg)
@@ -401,6 +513,21 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 				return nil, msg, errors.New(msg)
 			}
 
+			if job.RequireOwnerApproval && len(job.ProjectOwners) > 0 {
+				ownerApprovals, err := ci.OwnerApprovals(orgService, prService, *job.PullRequestNumber, job.ProjectOwners)
+				if err != nil {
+					msg := fmt.Sprintf("Failed to check owner approvals. %v", err)
+					log.Printf(msg)
+					return nil, msg, fmt.Errorf(msg)
+				}
+				if len(ownerApprovals) == 0 {
+					msg := reportOwnerApprovalMissing(job.ProjectName, job.ProjectOwners, reporter)
+					prService.SetStatus(*job.PullRequestNumber, "failure", job.ProjectName+"/apply")
+					return nil, msg, errors.New(msg)
+				}
+				log.Printf("apply of %v approved by owners: %v", job.ProjectName, ownerApprovals)
+			}
+
 			// Running apply
 
 			applySummary, applyPerformed, output, err := diggerExecutor.Apply()
@@ -422,11 +549,22 @@ func run(command string, job orchestrator.Job, policyChecker policy.Checker, org
 				}
 				appliesPerProject[job.ProjectName] = true
 			}
+
+			// outputs are passed to the projects depending on this one, not being able to read them
+			// doesn't fail the apply
+			var outputs map[string]string
+			if applyPerformed {
+				outputs, err = executor.Outputs()
+				if err != nil {
+					log.Printf("Warning: failed to read outputs of %v: %v", job.ProjectName, err)
+				}
+			}
 			result := execution.DiggerExecutorResult{
 				OperationType:   execution.DiggerOparationTypeApply,
 				TerraformOutput: output,
 				ApplyResult: &execution.DiggerExecutorApplyResult{
 					ApplySummary: *applySummary,
+					Outputs:      outputs,
 				},
 			}
 			return &result, output, nil
@@ -499,6 +637,38 @@ func reportApplyMergeabilityError(reporter reporting.Reporter) string {
 	return comment
 }
 
+func reportOwnerApprovalMissing(projectName string, owners []string, reporter reporting.Reporter) string {
+	msg := fmt.Sprintf("Apply of %v requires an approval from one of its owners: %v :x:", projectName, ci.MentionOwners(owners))
+	log.Println(msg)
+	if reporter.SupportsMarkdown() {
+		_, _, err := reporter.Report(msg, coreutils.AsCollapsibleComment(fmt.Sprintf("Owner approval required for <b>%v</b>", projectName), false))
+		if err != nil {
+			log.Printf("error publishing comment: %v\n", err)
+		}
+	} else {
+		_, _, err := reporter.Report(msg, coreutils.AsComment(fmt.Sprintf("Owner approval required for %v", projectName)))
+		if err != nil {
+			log.Printf("error publishing comment: %v\n", err)
+		}
+	}
+	return msg
+}
+
+// reportProjectOwners mentions the owners of the project so that they get notified about the plan
+func reportProjectOwners(reporter reporting.Reporter, job orchestrator.Job) {
+	if len(job.ProjectOwners) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("Owners of %v: %v", job.ProjectName, ci.MentionOwners(job.ProjectOwners))
+	if job.RequireOwnerApproval {
+		msg = msg + " (approval from an owner is required before apply)"
+	}
+	_, _, err := reporter.Report(msg, coreutils.AsComment(""))
+	if err != nil {
+		log.Printf("Failed to report project owners. %v", err)
+	}
+}
+
 func reportTerraformPlanOutput(reporter reporting.Reporter, projectId string, plan string) {
 	var formatter func(string) string
 
@@ -514,6 +684,22 @@ func reportTerraformPlanOutput(reporter reporting.Reporter, projectId string, pl
 	}
 }
 
+func projectMetadata(job orchestrator.Job) policy.ProjectMetadata {
+	return policy.ProjectMetadata{
+		Environment: job.ProjectEnvironment,
+		Labels:      job.ProjectLabels,
+		Owners:      job.ProjectOwners,
+	}
+}
+
+func formatPolicyMessages(messages []string) string {
+	preformattedMessages := make([]string, 0)
+	for _, message := range messages {
+		preformattedMessages = append(preformattedMessages, fmt.Sprintf("    %v", message))
+	}
+	return strings.Join(preformattedMessages, "<br>")
+}
+
 func reportPlanSummary(reporter reporting.Reporter, summary string) {
 	var formatter func(string) string
 
@@ -558,7 +744,7 @@ func RunJob(
 
 	for _, command := range job.Commands {
 
-		allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, nil, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, command, nil, requestedBy, []string{})
+		allowedToPerformCommand, err := policyChecker.CheckAccessPolicy(orgService, nil, SCMOrganisation, SCMrepository, job.ProjectName, job.ProjectDir, projectMetadata(job), command, nil, requestedBy, []string{})
 
 		if err != nil {
 			return fmt.Errorf("error checking policy: %v", err)
@@ -633,8 +819,10 @@ func RunJob(
 				}
 				return fmt.Errorf(msg)
 			}
-			planIsAllowed, messages, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, planJsonOutput)
-			log.Print(strings.Join(messages, "\n"))
+			planIsAllowed, violations, err := policyChecker.CheckPlanPolicy(SCMrepository, SCMOrganisation, job.ProjectName, job.ProjectDir, projectMetadata(job), planJsonOutput)
+			for _, violation := range violations {
+				log.Printf("%v: %v", violation.Severity, violation)
+			}
 			if err != nil {
 				msg := fmt.Sprintf("Failed to validate plan %v", err)
 				log.Printf(msg)
@@ -690,7 +878,7 @@ func RunJob(
 			}
 
 		case "digger drift-detect":
-			output, err := runDriftDetection(policyChecker, SCMOrganisation, SCMrepository, job.ProjectName, requestedBy, job.EventName, diggerExecutor, driftNotification)
+			output, err := runDriftDetection(policyChecker, SCMOrganisation, SCMrepository, job.ProjectName, projectMetadata(job), requestedBy, job.EventName, diggerExecutor, driftNotification)
 			if err != nil {
 				return fmt.Errorf("failed to Run digger drift-detect command. %v", err)
 			}
@@ -704,7 +892,7 @@ func RunJob(
 	return nil
 }
 
-func runDriftDetection(policyChecker policy.Checker, SCMOrganisation string, SCMrepository string, projectName string, requestedBy string, eventName string, diggerExecutor execution.Executor, notification *core_drift.Notification) (string, error) {
+func runDriftDetection(policyChecker policy.Checker, SCMOrganisation string, SCMrepository string, projectName string, metadata policy.ProjectMetadata, requestedBy string, eventName string, diggerExecutor execution.Executor, notification *core_drift.Notification) (string, error) {
 	err := usage.SendUsageRecord(requestedBy, eventName, "drift-detect")
 	if err != nil {
 		log.Printf("Failed to send usage report. %v", err)
@@ -721,7 +909,7 @@ func runDriftDetection(policyChecker policy.Checker, SCMOrganisation string, SCM
 		log.Printf(msg)
 		return msg, nil
 	}
-	_, planPerformed, nonEmptyPlan, plan, _, err := diggerExecutor.Plan()
+	_, planPerformed, nonEmptyPlan, plan, planJsonOutput, err := diggerExecutor.Plan()
 	if err != nil {
 		msg := fmt.Sprintf("failed to Run digger plan command. %v", err)
 		log.Printf(msg)
@@ -729,11 +917,23 @@ func runDriftDetection(policyChecker policy.Checker, SCMOrganisation string, SCM
 	}
 
 	if planPerformed && nonEmptyPlan {
+		violations, err := policyChecker.CheckDriftPlanPolicy(SCMOrganisation, SCMrepository, projectName, metadata, planJsonOutput)
+		if err != nil {
+			log.Printf("Failed to check drift plan against policy: %v", err)
+		}
+		driftReport := redact.String(plan)
+		if warnings := policy.Warnings(violations); len(warnings) > 0 {
+			driftReport = "Drift policy warnings:\n" + strings.Join(warnings, "\n") + "\n\n" + driftReport
+		}
+		if denies := policy.Denies(violations); len(denies) > 0 {
+			driftReport = "Drift failed policy checks:\n" + strings.Join(denies, "\n") + "\n\n" + driftReport
+		}
+
 		if notification == nil {
 			log.Print("Warning: no notification configured, not sending any notifications")
-			return plan, nil
+			return driftReport, nil
 		}
-		err := (*notification).Send(projectName, plan)
+		err = (*notification).Send(projectName, driftReport)
 		if err != nil {
 			log.Printf("Error sending drift drift: %v", err)
 		}
@@ -2,19 +2,27 @@ package digger
 
 import (
 	"fmt"
+	"github.com/diggerhq/digger/libs/backendapi"
 	"github.com/diggerhq/digger/libs/ci"
+	comment_updater "github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/execution"
+	"github.com/diggerhq/digger/libs/locking"
+	"github.com/diggerhq/digger/libs/policy"
 	orchestrator "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/diggerhq/digger/libs/storage"
 	"os"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
 	"github.com/dominikbraun/graph"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -61,6 +69,11 @@ func (m *MockTerraformExecutor) Show(params []string, envs map[string]string) (s
 	return nonEmptyTerraformPlanJson, "", nil
 }
 
+func (m *MockTerraformExecutor) Output(params []string, envs map[string]string) (string, string, error) {
+	m.Commands = append(m.Commands, RunInfo{"Output", strings.Join(params, " "), time.Now()})
+	return "{}", "", nil
+}
+
 func (m *MockTerraformExecutor) Plan(params []string, envs map[string]string) (bool, string, string, error) {
 	m.Commands = append(m.Commands, RunInfo{"Plan", strings.Join(params, " "), time.Now()})
 	return true, "", "", nil
@@ -467,6 +480,89 @@ func TestSortedCommandByDependency(t *testing.T) {
 
 }
 
+func TestSortedCommandLayersByDependency(t *testing.T) {
+	jobs := []orchestrator.Job{
+		{ProjectName: "app"},
+		{ProjectName: "network"},
+		{ProjectName: "dns"},
+		{ProjectName: "monitoring"},
+	}
+
+	projectHash := func(p configuration.Project) string {
+		return p.Name
+	}
+	dependencyGraph := graph.New(projectHash, graph.PreventCycles(), graph.Directed())
+	for _, name := range []string{"app", "network", "dns", "monitoring", "database"} {
+		dependencyGraph.AddVertex(configuration.Project{Name: name})
+	}
+	// app depends on network through database, which isn't run
+	dependencyGraph.AddEdge("network", "database")
+	dependencyGraph.AddEdge("database", "app")
+	dependencyGraph.AddEdge("network", "monitoring")
+
+	layers := SortedCommandLayersByDependency(jobs, &dependencyGraph)
+
+	assert.Equal(t, [][]string{{"dns", "network"}, {"monitoring"}, {"app"}}, lo.Map(layers, func(layer []orchestrator.Job, _ int) []string {
+		return projectNames(layer)
+	}))
+}
+
+func TestRunJobLayerIsBoundedByMaxParallelism(t *testing.T) {
+	layer := []orchestrator.Job{
+		{ProjectName: "a", ProjectDir: "a"},
+		{ProjectName: "b", ProjectDir: "b"},
+		{ProjectName: "c", ProjectDir: "c"},
+		{ProjectName: "d", ProjectDir: "d"},
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	prefixes := make([]string, len(layer))
+	results, err := runJobLayer(layer, 2, "", func(job orchestrator.Job, workingDir string, outputPrefix string) jobResult {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		prefixes[strings.Index("abcd", job.ProjectName)] = outputPrefix
+		return jobResult{applies: map[string]bool{job.ProjectName: true}}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(results))
+	assert.Equal(t, 2, maxRunning)
+	assert.Equal(t, []string{"[a] ", "[b] ", "[c] ", "[d] "}, prefixes)
+}
+
+func TestRunJobLayerCopiesWorkingDirForSharedDirs(t *testing.T) {
+	workingDir := t.TempDir()
+	err := os.MkdirAll(path.Join(workingDir, "prod"), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(path.Join(workingDir, "prod", "main.tf"), []byte(""), 0644)
+	assert.NoError(t, err)
+
+	layer := []orchestrator.Job{
+		{ProjectName: "prod-a", ProjectDir: "prod", ProjectWorkspace: "a"},
+		{ProjectName: "prod-b", ProjectDir: "prod", ProjectWorkspace: "b"},
+	}
+	workingDirs := make([]string, len(layer))
+	_, err = runJobLayer(layer, 2, workingDir, func(job orchestrator.Job, jobWorkingDir string, outputPrefix string) jobResult {
+		if job.ProjectName == "prod-b" {
+			_, err := os.Stat(path.Join(jobWorkingDir, "prod", "main.tf"))
+			assert.NoError(t, err)
+		}
+		workingDirs[strings.Index("ab", job.ProjectWorkspace)] = jobWorkingDir
+		return jobResult{}
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, workingDir, workingDirs[0])
+	assert.NotEqual(t, workingDir, workingDirs[1])
+}
+
 func TestParseWorkspace(t *testing.T) {
 	var commentTests = []struct {
 		in  string
@@ -496,3 +592,37 @@ func TestParseWorkspace(t *testing.T) {
 	}
 
 }
+
+type allowingPolicyChecker struct {
+	policy.MockPolicyChecker
+}
+
+func (c allowingPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectMetadata policy.ProjectMetadata, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+	return true, nil
+}
+
+func TestRunJobsReportsTheOutputOfTheFailedCommand(t *testing.T) {
+	workingDir := t.TempDir()
+	err := os.MkdirAll(path.Join(workingDir, "prod"), os.ModePerm)
+	assert.NoError(t, err)
+
+	prNumber := 1
+	job := orchestrator.Job{
+		ProjectName:       "prod",
+		ProjectDir:        "prod",
+		Commands:          []string{"digger plan"},
+		PullRequestNumber: &prNumber,
+		Namespace:         "acme/infra",
+		RequestedBy:       "alice",
+		PlanStage: &orchestrator.Stage{Steps: []orchestrator.Step{
+			{Action: "run", Value: "echo 'Error: Error acquiring the state lock' >&2; exit 1", Shell: "bash"},
+		}},
+	}
+	prService := ci.MockPullRequestManager{}
+	allAppliesSuccess, _, failureOutput, err := RunJobsWithFailureOutput([]orchestrator.Job{job}, prService, prService, &locking.MockLock{}, &reporting.MockReporter{}, &storage.MockPlanStorage{}, allowingPolicyChecker{}, comment_updater.NoopCommentUpdater{}, backendapi.MockBackendApi{}, "", false, false, "", workingDir)
+
+	assert.NoError(t, err)
+	assert.False(t, allAppliesSuccess)
+	assert.Contains(t, failureOutput, "digger plan failed for project prod")
+	assert.Equal(t, configuration.FailureClassStateLock, configuration.ClassifyFailure(failureOutput))
+}
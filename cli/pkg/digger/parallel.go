@@ -0,0 +1,163 @@
+package digger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	config "github.com/diggerhq/digger/libs/digger_config"
+	orchestrator "github.com/diggerhq/digger/libs/scheduler"
+	"github.com/dominikbraun/graph"
+)
+
+// SortedCommandLayersByDependency groups jobs in layers whose jobs don't depend on each other, a job is in a
+// later layer than every project it depends on, directly or through projects that aren't run
+func SortedCommandLayersByDependency(jobs []orchestrator.Job, dependencyGraph *graph.Graph[string, config.Project]) [][]orchestrator.Job {
+	sortedGraph, err := graph.StableTopologicalSort(*dependencyGraph, func(s string, s2 string) bool {
+		return s < s2
+	})
+	if err != nil {
+		log.Printf("dependencyGraph: %v", dependencyGraph)
+		log.Fatalf("failed to sort commands by dependency, %v", err)
+	}
+	predecessors, err := (*dependencyGraph).PredecessorMap()
+	if err != nil {
+		log.Fatalf("failed to get project dependencies, %v", err)
+	}
+
+	depths := make(map[string]int)
+	for _, node := range sortedGraph {
+		for dependency := range predecessors[node] {
+			depths[node] = max(depths[node], depths[dependency]+1)
+		}
+	}
+
+	layersByDepth := make(map[int][]orchestrator.Job)
+	for _, node := range sortedGraph {
+		for _, job := range jobs {
+			if job.ProjectName == node {
+				layersByDepth[depths[node]] = append(layersByDepth[depths[node]], job)
+			}
+		}
+	}
+	depthsInUse := make([]int, 0, len(layersByDepth))
+	for depth := range layersByDepth {
+		depthsInUse = append(depthsInUse, depth)
+	}
+	sort.Ints(depthsInUse)
+
+	layers := make([][]orchestrator.Job, 0, len(depthsInUse))
+	for _, depth := range depthsInUse {
+		layers = append(layers, layersByDepth[depth])
+	}
+	return layers
+}
+
+// runJobLayer runs the jobs of a layer with at most maxParallelism of them at the same time. When jobs run in
+// parallel their output is prefixed with the project name, and jobs sharing a dir with another job of the
+// layer run in their own copy of the working dir so that their terraform state and workspace don't clash.
+func runJobLayer(layer []orchestrator.Job, maxParallelism int, workingDir string, runJob func(job orchestrator.Job, workingDir string, outputPrefix string) jobResult) ([]jobResult, error) {
+	results := make([]jobResult, len(layer))
+	if maxParallelism <= 1 || len(layer) == 1 {
+		for i, job := range layer {
+			results[i] = runJob(job, workingDir, "")
+		}
+		return results, nil
+	}
+
+	workingDirs := make([]string, len(layer))
+	usedDirs := make(map[string]bool)
+	for i, job := range layer {
+		dir := path.Clean(job.ProjectDir)
+		if !usedDirs[dir] {
+			usedDirs[dir] = true
+			workingDirs[i] = workingDir
+			continue
+		}
+		copyDir, err := os.MkdirTemp("", "digger-"+job.ProjectName+"-")
+		if err != nil {
+			return nil, fmt.Errorf("could not create working copy for project %v: %v", job.ProjectName, err)
+		}
+		defer os.RemoveAll(copyDir)
+		log.Printf("project %v shares dir %v with another project, running it in a copy of the working dir: %v", job.ProjectName, dir, copyDir)
+		err = copyWorkingDir(workingDir, copyDir)
+		if err != nil {
+			return nil, fmt.Errorf("could not create working copy for project %v: %v", job.ProjectName, err)
+		}
+		workingDirs[i] = copyDir
+	}
+
+	log.Printf("running projects %v with max parallelism %v", projectNames(layer), maxParallelism)
+	semaphore := make(chan struct{}, maxParallelism)
+	var wg sync.WaitGroup
+	for i, job := range layer {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, job orchestrator.Job) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = runJob(job, workingDirs[i], fmt.Sprintf("[%v] ", job.ProjectName))
+		}(i, job)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func projectNames(jobs []orchestrator.Job) []string {
+	names := make([]string, len(jobs))
+	for i, job := range jobs {
+		names[i] = job.ProjectName
+	}
+	return names
+}
+
+// copyWorkingDir copies the files of the working dir to dst, leaving out git metadata and terraform caches
+func copyWorkingDir(src string, dst string) error {
+	return filepath.Walk(src, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, filePath)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		switch {
+		case info.IsDir():
+			if name := info.Name(); name == ".git" || name == ".terraform" || name == ".terragrunt-cache" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(filePath, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
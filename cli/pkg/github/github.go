@@ -9,6 +9,7 @@ import (
 	"github.com/diggerhq/digger/cli/pkg/usage"
 	"github.com/diggerhq/digger/cli/pkg/utils"
 	core_backend "github.com/diggerhq/digger/libs/backendapi"
+	"github.com/diggerhq/digger/libs/ci"
 	"github.com/diggerhq/digger/libs/ci/generic"
 	dg_github "github.com/diggerhq/digger/libs/ci/github"
 	"github.com/diggerhq/digger/libs/comment_utils/reporting"
@@ -135,7 +136,10 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		}
 		workflow := diggerConfig.Workflows[projectConfig.Workflow]
 
-		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+		stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+		if err != nil {
+			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to collect env vars of project %v. %s", project, err), 4)
+		}
 
 		planStorage, err := storage.NewPlanStorage(ghToken, repoOwner, repositoryName, nil)
 		if err != nil {
@@ -143,20 +147,25 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		}
 
 		jobs := scheduler.Job{
-			ProjectName:       project,
-			ProjectDir:        projectConfig.Dir,
-			ProjectWorkspace:  projectConfig.Workspace,
-			Terragrunt:        projectConfig.Terragrunt,
-			OpenTofu:          projectConfig.OpenTofu,
-			Commands:          []string{command},
-			ApplyStage:        scheduler.ToConfigStage(workflow.Apply),
-			PlanStage:         scheduler.ToConfigStage(workflow.Plan),
-			PullRequestNumber: nil,
-			EventName:         "manual_invocation",
-			RequestedBy:       githubActor,
-			Namespace:         ghRepository,
-			StateEnvVars:      stateEnvVars,
-			CommandEnvVars:    commandEnvVars,
+			ProjectName:          project,
+			ProjectDir:           projectConfig.Dir,
+			ProjectLabels:        projectConfig.Labels,
+			ProjectEnvironment:   projectConfig.Environment,
+			ProjectOwners:        projectConfig.Owners,
+			RequireOwnerApproval: projectConfig.RequireOwnerApproval,
+			ProjectInputs:        projectConfig.Inputs,
+			ProjectWorkspace:     projectConfig.Workspace,
+			Terragrunt:           projectConfig.Terragrunt,
+			OpenTofu:             projectConfig.OpenTofu,
+			Commands:             []string{command},
+			ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+			PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+			PullRequestNumber:    nil,
+			EventName:            "manual_invocation",
+			RequestedBy:          githubActor,
+			Namespace:            ghRepository,
+			StateEnvVars:         stateEnvVars,
+			CommandEnvVars:       commandEnvVars,
 		}
 		err = digger.RunJob(jobs, ghRepository, githubActor, &githubPrService, policyChecker, planStorage, backendApi, nil, currentDir)
 		if err != nil {
@@ -170,26 +179,34 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			}
 			workflow := diggerConfig.Workflows[projectConfig.Workflow]
 
-			stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			if err != nil {
+				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to collect env vars of project %v. %s", projectConfig.Name, err), 4)
+			}
 
 			StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(projectConfig)
 
 			job := scheduler.Job{
-				ProjectName:        projectConfig.Name,
-				ProjectDir:         projectConfig.Dir,
-				ProjectWorkspace:   projectConfig.Workspace,
-				Terragrunt:         projectConfig.Terragrunt,
-				OpenTofu:           projectConfig.OpenTofu,
-				Commands:           []string{"digger drift-detect"},
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				RequestedBy:        githubActor,
-				Namespace:          ghRepository,
-				EventName:          "drift-detect",
-				StateEnvProvider:   StateEnvProvider,
-				CommandEnvProvider: CommandEnvProvider,
+				ProjectName:          projectConfig.Name,
+				ProjectDir:           projectConfig.Dir,
+				ProjectLabels:        projectConfig.Labels,
+				ProjectEnvironment:   projectConfig.Environment,
+				ProjectOwners:        projectConfig.Owners,
+				RequireOwnerApproval: projectConfig.RequireOwnerApproval,
+				ProjectInputs:        projectConfig.Inputs,
+				ProjectWorkspace:     projectConfig.Workspace,
+				Terragrunt:           projectConfig.Terragrunt,
+				OpenTofu:             projectConfig.OpenTofu,
+				Commands:             []string{"digger drift-detect"},
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				RequestedBy:          githubActor,
+				Namespace:            ghRepository,
+				EventName:            "drift-detect",
+				StateEnvProvider:     StateEnvProvider,
+				CommandEnvProvider:   CommandEnvProvider,
 			}
 
 			notification, err := driftNotifcationProvider.Get(githubPrService)
@@ -204,7 +221,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		}
 	} else {
 
-		impactedProjects, requestedProject, prNumber, err := dg_github.ProcessGitHubEvent(ghEvent, diggerConfig, &githubPrService)
+		impactedProjects, requestedProjects, prNumber, err := dg_github.ProcessGitHubEvent(ghEvent, diggerConfig, &githubPrService)
 		if err != nil {
 			if errors.Is(err, dg_github.UnhandledMergeGroupEventError) {
 				usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Graceful handling of GitHub event. %s", err), 0)
@@ -226,7 +243,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			}
 		}
 
-		if len(impactedProjects) == 0 {
+		if len(impactedProjects) == 0 && len(requestedProjects) == 0 {
 			usage.ReportErrorAndExit(githubActor, "No projects impacted", 0)
 		}
 
@@ -234,7 +251,17 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 		coversAllImpactedProjects := false
 		err = nil
 		if prEvent, ok := ghEvent.(github.PullRequestEvent); ok {
-			jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubPullRequestEventToJobs(&prEvent, impactedProjects, requestedProject, *diggerConfig, true)
+			if action := prEvent.GetAction(); action == "opened" || action == "reopened" || action == "synchronize" || action == "ready_for_review" {
+				owners := make([]string, 0)
+				for _, project := range impactedProjects {
+					owners = append(owners, project.Owners...)
+				}
+				err := ci.RequestOwnerReviews(&githubPrService, prNumber, owners, prEvent.GetPullRequest().GetUser().GetLogin())
+				if err != nil {
+					log.Printf("could not request reviews from project owners: %v", err)
+				}
+			}
+			jobs, coversAllImpactedProjects, err = dg_github.ConvertGithubPullRequestEventToJobs(&prEvent, impactedProjects, requestedProjects, *diggerConfig, true)
 		} else if commentEvent, ok := ghEvent.(github.IssueCommentEvent); ok {
 			prBranchName, _, err := githubPrService.GetBranchName(*commentEvent.Issue.Number)
 
@@ -245,7 +272,7 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			repoFullName := *commentEvent.Repo.FullName
 			requestedBy := *commentEvent.Sender.Login
 			commentBody := *commentEvent.Comment.Body
-			jobs, coversAllImpactedProjects, err = generic.ConvertIssueCommentEventToJobs(repoFullName, requestedBy, prNumber, commentBody, impactedProjects, requestedProject, diggerConfig.Workflows, prBranchName, defaultBranch)
+			jobs, coversAllImpactedProjects, err = generic.ConvertIssueCommentEventToJobs(repoFullName, requestedBy, prNumber, commentBody, impactedProjects, requestedProjects, diggerConfig.Workflows, prBranchName, defaultBranch)
 		} else {
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Unsupported GitHub event type. %s", err), 6)
 		}
@@ -273,9 +300,9 @@ func GitHubCI(lock core_locking.Lock, policyCheckerProvider core_policy.PolicyCh
 			IsSupportMarkdown: true,
 		}
 
-		jobs = digger.SortedCommandsByDependency(jobs, &dependencyGraph)
+		layers := digger.SortedCommandLayersByDependency(jobs, &dependencyGraph)
 
-		allAppliesSuccessful, atLeastOneApply, err := digger.RunJobs(jobs, &githubPrService, &githubPrService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, "0", currentDir)
+		allAppliesSuccessful, atLeastOneApply, err := digger.RunJobLayers(layers, diggerConfig.MaxParallelism, &githubPrService, &githubPrService, lock, reporter, planStorage, policyChecker, comment_updater.NoopCommentUpdater{}, backendApi, "", false, false, "0", currentDir)
 		if err != nil {
 			usage.ReportErrorAndExit(githubActor, fmt.Sprintf("Failed to run commands. %s", err), 8)
 			// aggregate status checks: failure
@@ -1,6 +1,7 @@
 package spec
 
 import (
+	"context"
 	"fmt"
 	"github.com/diggerhq/digger/cli/pkg/digger"
 	"github.com/diggerhq/digger/cli/pkg/usage"
@@ -8,7 +9,10 @@ import (
 	comment_summary "github.com/diggerhq/digger/libs/comment_utils/summary"
 	"github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/spec"
+	"github.com/diggerhq/digger/libs/tracing"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"log"
 	"os"
 	"os/exec"
@@ -17,13 +21,39 @@ import (
 
 func reportError(spec spec.Spec, backendApi backend2.Api, message string, err error) {
 	log.Printf(message)
-	_, reportingError := backendApi.ReportProjectJobStatus(spec.VCS.RepoName, spec.Job.ProjectName, spec.JobId, "failed", time.Now(), nil, "", "", "")
+	_, reportingError := backendApi.ReportProjectJobStatus(spec.VCS.RepoName, spec.Job.ProjectName, spec.JobId, "failed", time.Now(), nil, "", "", "", nil)
 	if reportingError != nil {
 		usage.ReportErrorAndExit(spec.VCS.RepoOwner, fmt.Sprintf("Failed to run commands. %v", err), 5)
 	}
 	usage.ReportErrorAndExit(spec.VCS.Actor, message, 1)
 }
 
+// interval at which a running job tells the backend that it is alive, it has to be well below the heartbeat
+// timeouts of the backend
+const jobHeartbeatInterval = time.Minute
+
+// startHeartbeat reports heartbeats of the job to the backend until the returned function is called, so that
+// the backend can tell a long plan or apply from a job whose runner died
+func startHeartbeat(backendApi backend2.Api, repo string, projectName string, jobId string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := backendApi.ReportJobHeartbeat(repo, projectName, jobId)
+				if err != nil {
+					log.Printf("could not report heartbeat of job %v: %v", jobId, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
 func RunSpec(
 	spec spec.Spec,
 	vcsProvider spec.VCSProvider,
@@ -36,6 +66,19 @@ func RunSpec(
 	variablesProvider spec.VariablesProvider,
 	commentUpdaterProvider comment_summary.CommentUpdaterProvider,
 ) error {
+	ctx := context.Background()
+	err := tracing.Init(ctx, "digger-cli")
+	if err != nil {
+		log.Printf("could not initialise tracing: %v", err)
+	}
+	// the spans of the job and of the requests it sends to the backend join the trace of the backend
+	ctx = tracing.Extract(ctx, spec.TraceContext)
+	spec.Backend.TraceContext = spec.TraceContext
+	jobAttributes := trace.WithAttributes(
+		attribute.String("digger.job_id", spec.JobId),
+		attribute.String("digger.project", spec.Job.ProjectName),
+		attribute.String("digger.job_type", spec.Job.JobType),
+	)
 
 	backendApi, err := backedProvider.GetBackendApi(spec.Backend)
 	if err != nil {
@@ -45,12 +88,14 @@ func RunSpec(
 
 	if spec.Job.Commit != "" {
 		// checking out to the commit ID
+		_, checkoutSpan := tracing.Start(ctx, "checkout commit", jobAttributes)
 		log.Printf("fetching commit ID %v", spec.Job.Commit)
 		fetchCmd := exec.Command("git", "fetch", "origin", spec.Job.Commit)
 		fetchCmd.Stdout = os.Stdout
 		fetchCmd.Stderr = os.Stderr
 		err = fetchCmd.Run()
 		if err != nil {
+			tracing.End(checkoutSpan, err)
 			msg := fmt.Sprintf("error while fetching commit SHA: %v", err)
 			reportError(spec, backendApi, msg, err)
 			usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("error while checking out to commit sha: %v", err), 1)
@@ -61,6 +106,7 @@ func RunSpec(
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		err = cmd.Run()
+		tracing.End(checkoutSpan, err)
 		if err != nil {
 			msg := fmt.Sprintf("error while checking out to commit SHA: %v", err)
 			reportError(spec, backendApi, msg, err)
@@ -131,13 +177,20 @@ func RunSpec(
 	jobs := []scheduler.Job{job}
 
 	fullRepoName := fmt.Sprintf("%v-%v", spec.VCS.RepoOwner, spec.VCS.RepoName)
-	_, err = backendApi.ReportProjectJobStatus(fullRepoName, spec.Job.ProjectName, spec.JobId, "started", time.Now(), nil, "", "", "")
+	startedBatch, err := backendApi.ReportProjectJobStatus(fullRepoName, spec.Job.ProjectName, spec.JobId, "started", time.Now(), nil, "", "", "", nil)
 	if err != nil {
 		message := fmt.Sprintf("Failed to report jobSpec status to backend. Exiting. %v", err)
 		reportError(spec, backendApi, message, err)
 	}
 
 	commentId := spec.CommentId
+	if startedBatch != nil && len(startedBatch.Jobs) > 0 {
+		// shows the job as running, with a link to its live logs
+		err = commentUpdater.UpdateComment(startedBatch.Jobs, startedBatch.PrNumber, prService, commentId)
+		if err != nil {
+			log.Printf("could not update summary comment: %v", err)
+		}
+	}
 	if err != nil {
 		message := fmt.Sprintf("failed to get comment ID: %v", err)
 		reportError(spec, backendApi, message, err)
@@ -150,16 +203,29 @@ func RunSpec(
 	}
 
 	reportTerraformOutput := spec.Reporter.ReportTerraformOutput
-	allAppliesSuccess, _, err := digger.RunJobs(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, spec.JobId, true, reportTerraformOutput, commentId, currentDir)
+	stopHeartbeat := startHeartbeat(backendApi, fullRepoName, spec.Job.ProjectName, spec.JobId)
+	_, runSpan := tracing.Start(ctx, "run job", jobAttributes)
+	allAppliesSuccess, _, failureOutput, err := digger.RunJobsWithFailureOutput(jobs, prService, orgService, lock, reporter, planStorage, policyChecker, commentUpdater, backendApi, spec.JobId, true, reportTerraformOutput, commentId, currentDir)
+	runErr := err
+	if runErr == nil && !allAppliesSuccess {
+		runErr = fmt.Errorf("job did not succeed")
+	}
+	tracing.End(runSpan, runErr)
+	stopHeartbeat()
 	if !allAppliesSuccess || err != nil {
-		serializedBatch, reportingError := backendApi.ReportProjectJobStatus(spec.VCS.RepoName, spec.Job.ProjectName, spec.JobId, "failed", time.Now(), nil, "", "", "")
+		// the failure is reported as output so that the backend can retry transient failures
+		if err != nil {
+			failureOutput = err.Error()
+		}
+		serializedBatch, reportingError := backendApi.ReportProjectJobStatus(spec.VCS.RepoName, spec.Job.ProjectName, spec.JobId, "failed", time.Now(), nil, "", "", failureOutput, nil)
 		if reportingError != nil {
 			message := fmt.Sprintf("Failed run commands. %s", err)
 			reportError(spec, backendApi, message, err)
 		}
 		commentUpdater.UpdateComment(serializedBatch.Jobs, serializedBatch.PrNumber, prService, commentId)
 		digger.UpdateAggregateStatus(serializedBatch, prService)
-		reportError(spec, backendApi, fmt.Sprintf("Failed to run commands. %s", err), err)
+		// the failure is already reported, reporting it again without output would undo a retry of the job
+		usage.ReportErrorAndExit(spec.VCS.Actor, fmt.Sprintf("Failed to run commands. %v", runErr), 1)
 	}
 	usage.ReportErrorAndExit(spec.VCS.RepoOwner, "Digger finished successfully", 0)
 
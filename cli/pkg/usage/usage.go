@@ -2,10 +2,12 @@ package usage
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	configuration "github.com/diggerhq/digger/libs/digger_config"
+	"github.com/diggerhq/digger/libs/tracing"
 	"log"
 	"net/http"
 	"os"
@@ -114,5 +116,10 @@ func ReportErrorAndExit(repoOwner string, message string, exitCode int) {
 	if err != nil {
 		log.Printf("Failed to send log record. %s\n", err)
 	}
+	// spans not exported yet would be lost on exit
+	err = tracing.Shutdown(context.Background())
+	if err != nil {
+		log.Printf("Failed to export traces. %s\n", err)
+	}
 	os.Exit(exitCode)
 }
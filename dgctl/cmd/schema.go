@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/spf13/cobra"
+)
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of digger.yml",
+	Long:  `Print the JSON Schema of digger.yml, editors can use it to validate and autocomplete the config.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := digger_config.GenerateJsonSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(schema))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -8,49 +8,172 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/exec"
+	"strings"
 
+	"github.com/diggerhq/digger/libs/ci/generic"
 	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/dominikbraun/graph"
 	"github.com/spf13/cobra"
 )
 
+// changedFilesSince returns the files changed on the current branch since it diverged from base
+func changedFilesSince(dir string, base string) ([]string, error) {
+	cmd := exec.Command("git", "diff", "--name-only", base+"...HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not get changed files since %v: %v", base, err)
+	}
+	files := make([]string, 0)
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// printImpactedProjects prints the projects a pull request changing changedFiles would run, the same
+// way they are selected for pull request events
+func printImpactedProjects(config *digger_config.DiggerConfig, dependencyGraph graph.Graph[string, digger_config.Project], changedFiles []string) error {
+	fmt.Printf("\nChanged files (%v):\n", len(changedFiles))
+	for _, f := range changedFiles {
+		fmt.Printf("  %v\n", f)
+	}
+
+	impactedProjects, sourceMapping := config.GetModifiedProjects(changedFiles)
+	fmt.Printf("\nImpacted projects (%v):\n", len(impactedProjects))
+	for _, p := range impactedProjects {
+		fmt.Printf("  %v (dir: %v, changed: %v)\n", p.Name, p.Dir, strings.Join(sourceMapping[p.Name].ImpactingLocations, ", "))
+	}
+
+	projectsWithDependants, err := generic.FindAllProjectsDependantOnImpactedProjects(impactedProjects, dependencyGraph)
+	if err != nil {
+		return fmt.Errorf("could not find dependant projects: %v", err)
+	}
+	impacted := make(map[string]bool)
+	for _, p := range impactedProjects {
+		impacted[p.Name] = true
+	}
+	fmt.Println("\nProjects added because they depend on impacted projects:")
+	added := 0
+	for _, p := range projectsWithDependants {
+		if impacted[p.Name] {
+			continue
+		}
+		added++
+		fmt.Printf("  %v (depends on: %v)\n", p.Name, strings.Join(p.DependencyProjects, ", "))
+	}
+	if added == 0 {
+		fmt.Println("  none")
+	}
+
+	// projects run after the projects they depend on, the same order jobs are run in
+	sortedProjects, err := graph.StableTopologicalSort(dependencyGraph, func(a string, b string) bool {
+		return a < b
+	})
+	if err != nil {
+		return fmt.Errorf("could not sort projects by dependency: %v", err)
+	}
+	toRun := make(map[string]bool)
+	for _, p := range projectsWithDependants {
+		toRun[p.Name] = true
+	}
+	fmt.Println("\nProjects that would run, in order:")
+	for _, name := range sortedProjects {
+		if toRun[name] {
+			fmt.Printf("  %v\n", name)
+		}
+	}
+	return nil
+}
+
 // validateCmd represents the validate command
 var validateCmd = &cobra.Command{
 	Use:   "validate",
 	Short: "Validate a digger.yml file",
-	Long:  `Validate the structure and contents of a digger.yml file.`,
+	Long: `Validate the structure and contents of a digger.yml file and report lints such as unused workflows,
+overlapping project dirs, include patterns that match nothing, dependency cycles and missing dirs.
+
+With --changed-files or --base the projects impacted by those changes are printed, to debug which
+projects a pull request would plan.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		configPath := "./"
 		log.Printf("Starting validation of digger.yml in path: %s", configPath)
 
-		// Load the Digger config file
-		_, configYaml, _, err := digger_config.LoadDiggerConfig(configPath, true, nil)
+		changedFiles, _ := cmd.Flags().GetStringSlice("changed-files")
+		base, _ := cmd.Flags().GetString("base")
+		if base != "" {
+			if len(changedFiles) > 0 {
+				fmt.Fprintf(os.Stderr, "--changed-files and --base can't be used together\n")
+				os.Exit(1)
+			}
+			var err error
+			changedFiles, err = changedFilesSince(configPath, base)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(1)
+			}
+		}
+		simulate := base != "" || cmd.Flags().Changed("changed-files")
+
+		configYaml, err := digger_config.LoadDiggerConfigYaml(configPath, true, changedFiles)
 		if err != nil {
 			log.Printf("Error loading digger.yml: %v", err)
 			fmt.Fprintf(os.Stderr, "Invalid digger config file: %v\n", err)
 			os.Exit(1)
 		}
 
-		// Log success message
-		log.Printf("digger.yml loaded successfully.")
+		lints, err := digger_config.LintDiggerConfigYaml(configPath, configYaml)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid digger config file: %v\n", err)
+			os.Exit(1)
+		}
+		for _, l := range lints {
+			fmt.Println(l)
+		}
+		if digger_config.HasLintErrors(lints) {
+			fmt.Fprintf(os.Stderr, "Invalid digger config file: %v problems found\n", len(lints))
+			os.Exit(1)
+		}
 
-		// Display the configuration in a pretty JSON format
-		prettyConfig, err := json.MarshalIndent(configYaml, "", "\t")
+		config, dependencyGraph, err := digger_config.ConvertDiggerYamlToConfig(configYaml)
+		if err == nil {
+			err = digger_config.ValidateDiggerConfig(config)
+		}
 		if err != nil {
-			log.Printf("Error formatting digger.yml: %v", err)
-			fmt.Fprintf(os.Stderr, "Error formatting digger config file: %v\n", err)
+			log.Printf("Error loading digger.yml: %v", err)
+			fmt.Fprintf(os.Stderr, "Invalid digger config file: %v\n", err)
 			os.Exit(1)
 		}
+		fmt.Printf("digger.yml is valid: %v projects, %v workflows, %v warnings\n", len(config.Projects), len(config.Workflows), len(lints))
 
-		// Print the formatted configuration
-		fmt.Println("Configuration:")
-		fmt.Println(string(prettyConfig))
+		if printConfig, _ := cmd.Flags().GetBool("print-config"); printConfig {
+			// Display the configuration in a pretty JSON format
+			prettyConfig, err := json.MarshalIndent(configYaml, "", "\t")
+			if err != nil {
+				log.Printf("Error formatting digger.yml: %v", err)
+				fmt.Fprintf(os.Stderr, "Error formatting digger config file: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Configuration:")
+			fmt.Println(string(prettyConfig))
+		}
+
+		if simulate {
+			if err := printImpactedProjects(config, dependencyGraph, changedFiles); err != nil {
+				fmt.Fprintf(os.Stderr, "Error finding impacted projects: %v\n", err)
+				os.Exit(1)
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(validateCmd)
 
-	// Optionally define any flags that you want to use with the command here
-	// e.g.:
-	// validateCmd.Flags().StringP("config", "c", "", "Specify custom config file path")
+	validateCmd.Flags().StringSlice("changed-files", nil, "Comma separated files to print the impacted projects for")
+	validateCmd.Flags().String("base", "", "Git ref to diff the current branch against to print the impacted projects, e.g. origin/main")
+	validateCmd.Flags().Bool("print-config", false, "Print the loaded configuration as JSON")
 }
@@ -2,10 +2,15 @@ package ci_backends
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/buildkite/go-buildkite/v3/buildkite"
 	"github.com/diggerhq/digger/libs/spec"
+	"strconv"
 )
 
+// build meta data key identifying the digger job a build runs
+const buildkiteJobIdMetaData = "digger_job_id"
+
 type BuildkiteCi struct {
 	Client   buildkite.Client
 	Org      string
@@ -26,8 +31,48 @@ func (b BuildkiteCi) TriggerWorkflow(spec spec.Spec, runName string, vcsToken st
 			"GITHUB_TOKEN": vcsToken,
 		},
 		PullRequestID: int64(*spec.Job.PullRequestNumber),
+		MetaData: map[string]string{
+			buildkiteJobIdMetaData: spec.JobId,
+		},
 	})
 
 	return err
 
 }
+
+// activeBuildsOfJob returns the scheduled and running builds of the job of spec
+func (b BuildkiteCi) activeBuildsOfJob(spec spec.Spec) ([]buildkite.Build, error) {
+	builds, _, err := b.Client.Builds.ListByPipeline(b.Org, b.Pipeline, &buildkite.BuildsListOptions{
+		State:    []string{"scheduled", "running"},
+		MetaData: buildkite.MetaDataFilters{MetaData: map[string]string{buildkiteJobIdMetaData: spec.JobId}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list builds of job %v: %v", spec.JobId, err)
+	}
+	return builds, nil
+}
+
+func (b BuildkiteCi) CancelWorkflow(spec spec.Spec, workflowRunUrl string) error {
+	builds, err := b.activeBuildsOfJob(spec)
+	if err != nil {
+		return err
+	}
+	for _, build := range builds {
+		if build.Number == nil {
+			continue
+		}
+		_, err := b.Client.Builds.Cancel(b.Org, b.Pipeline, strconv.Itoa(*build.Number))
+		if err != nil {
+			return fmt.Errorf("could not cancel build %v: %v", *build.Number, err)
+		}
+	}
+	return nil
+}
+
+func (b BuildkiteCi) IsWorkflowRunning(spec spec.Spec, workflowRunUrl string) (bool, error) {
+	builds, err := b.activeBuildsOfJob(spec)
+	if err != nil {
+		return false, err
+	}
+	return len(builds) > 0, nil
+}
@@ -59,3 +59,58 @@ func (gl GitlabPipelineCI) TriggerWorkflow(spec spec.Spec, runName string, vcsTo
 
 	return err
 }
+
+// activePipelinesOfJob returns the created, pending and running pipelines of the job of spec
+func (gl GitlabPipelineCI) activePipelinesOfJob(spec spec.Spec) ([]*gitlab.PipelineInfo, error) {
+	pipelines, _, err := gl.Client.Pipelines.ListProjectPipelines(spec.VCS.RepoFullname, &gitlab.ListProjectPipelinesOptions{
+		Ref: &spec.Job.Branch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list pipelines of job %v: %v", spec.JobId, err)
+	}
+	active := make([]*gitlab.PipelineInfo, 0)
+	for _, pipeline := range pipelines {
+		if pipeline.Status != "created" && pipeline.Status != "pending" && pipeline.Status != "running" {
+			continue
+		}
+		variables, _, err := gl.Client.Pipelines.GetPipelineVariables(spec.VCS.RepoFullname, pipeline.ID)
+		if err != nil {
+			return nil, fmt.Errorf("could not get variables of pipeline %v: %v", pipeline.ID, err)
+		}
+		for _, variable := range variables {
+			if variable.Key != "DIGGER_RUN_SPEC" {
+				continue
+			}
+			var pipelineSpec struct {
+				JobId string `json:"job_id"`
+			}
+			if json.Unmarshal([]byte(variable.Value), &pipelineSpec) != nil || pipelineSpec.JobId != spec.JobId {
+				continue
+			}
+			active = append(active, pipeline)
+		}
+	}
+	return active, nil
+}
+
+func (gl GitlabPipelineCI) CancelWorkflow(spec spec.Spec, workflowRunUrl string) error {
+	pipelines, err := gl.activePipelinesOfJob(spec)
+	if err != nil {
+		return err
+	}
+	for _, pipeline := range pipelines {
+		_, _, err := gl.Client.Pipelines.CancelPipelineBuild(spec.VCS.RepoFullname, pipeline.ID)
+		if err != nil {
+			return fmt.Errorf("could not cancel pipeline %v: %v", pipeline.ID, err)
+		}
+	}
+	return nil
+}
+
+func (gl GitlabPipelineCI) IsWorkflowRunning(spec spec.Spec, workflowRunUrl string) (bool, error) {
+	pipelines, err := gl.activePipelinesOfJob(spec)
+	if err != nil {
+		return false, err
+	}
+	return len(pipelines) > 0, nil
+}
@@ -1,13 +1,16 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/diggerhq/digger/backend/ci_backends"
 	"github.com/diggerhq/digger/backend/controllers"
 	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/segment"
+	"github.com/diggerhq/digger/backend/services"
 	"github.com/diggerhq/digger/backend/utils"
 	"github.com/diggerhq/digger/libs/ci/generic"
 	dg_github "github.com/diggerhq/digger/libs/ci/github"
@@ -58,6 +61,7 @@ func (d DiggerEEController) GitlabWebHookHandler(c *gin.Context) {
 		return
 	}
 	webhookType := gitlab.WebhookEventType(c.Request)
+	metrics.WebhookReceived("gitlab", string(webhookType))
 	event, err := gitlab.ParseHook(webhookType, body)
 	if err != nil {
 		log.Printf("Failed to parse gitlab Event. :%v\n", err)
@@ -77,7 +81,7 @@ func (d DiggerEEController) GitlabWebHookHandler(c *gin.Context) {
 	case *gitlab.MergeCommentEvent:
 		log.Printf("IssueCommentEvent, action: %v \n", event.ObjectAttributes.Description)
 		c.String(http.StatusOK, "OK2")
-		err := handleIssueCommentEvent(d.GitlabProvider, event, d.CiBackendProvider, organisationId)
+		err := handleIssueCommentEvent(c.Request.Context(), d.GitlabProvider, event, d.CiBackendProvider, organisationId)
 		if err != nil {
 			log.Printf("handleIssueCommentEvent error: %v", err)
 			c.String(http.StatusInternalServerError, err.Error())
@@ -85,7 +89,7 @@ func (d DiggerEEController) GitlabWebHookHandler(c *gin.Context) {
 		}
 	case *gitlab.MergeEvent:
 		log.Printf("Got pull request event for %d", event.Project.ID)
-		err := handlePullRequestEvent(d.GitlabProvider, event, d.CiBackendProvider, organisationId)
+		err := handlePullRequestEvent(c.Request.Context(), d.GitlabProvider, event, d.CiBackendProvider, organisationId)
 		if err != nil {
 			log.Printf("handlePullRequestEvent error: %v", err)
 			c.String(http.StatusInternalServerError, err.Error())
@@ -186,7 +190,7 @@ func GetGitlabRepoUrl(event interface{}) string {
 	return repoUrl
 }
 
-func handlePullRequestEvent(gitlabProvider utils.GitlabProvider, payload *gitlab.MergeEvent, ciBackendProvider ci_backends.CiBackendProvider, organisationId uint) error {
+func handlePullRequestEvent(ctx context.Context, gitlabProvider utils.GitlabProvider, payload *gitlab.MergeEvent, ciBackendProvider ci_backends.CiBackendProvider, organisationId uint) error {
 	projectId := payload.Project.ID
 	repoFullName := payload.Project.PathWithNamespace
 	repoOwner, repoName, _ := strings.Cut(repoFullName, "/")
@@ -236,6 +240,40 @@ func handlePullRequestEvent(gitlabProvider utils.GitlabProvider, payload *gitlab
 		return fmt.Errorf("error processing event")
 	}
 
+	ciBackend, err := ciBackendProvider.GetCiBackend(
+		ci_backends.CiBackendOptions{
+			RepoName:                 repoName,
+			RepoOwner:                repoOwner,
+			RepoFullName:             repoFullName,
+			GitlabProjectId:          projectId,
+			GitlabCIMergeRequestID:   payload.ObjectAttributes.ID,
+			GitlabCIMergeRequestIID:  payload.ObjectAttributes.IID,
+			GitlabciprojectId:        payload.Project.ID,
+			GitlabciprojectNamespace: payload.Project.Namespace,
+			//GitlabciprojectNamespaceId: 0,
+			GitlabmergeRequestEventName: payload.EventType,
+			//GitlabCIPipelineID: ,
+			//GitlabCIPipelineIID: "",
+			GitlabCIProjectName: payload.Project.Name,
+			GitlabDiscussionId:  discussionId,
+		},
+	)
+	if err != nil {
+		log.Printf("GetCiBackend error: %v", err)
+		utils.InitCommentReporter(glService, prNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
+		return fmt.Errorf("error fetching ci backed %v", err)
+	}
+
+	if payload.ObjectAttributes.Action == "update" && payload.ObjectAttributes.OldRev != "" {
+		// plans of the previous commit are stale, apply batches are left running so that an apply isn't interrupted
+		cancelled, err := services.CancelActiveBatchesForPr(ciBackend, models.DiggerVCSGitlab, repoFullName, prNumber, scheduler.BatchJobSuperseded, true)
+		if err != nil {
+			log.Printf("could not supersede batches of MR %v: %v", prNumber, err)
+		} else if cancelled > 0 {
+			log.Printf("superseded %v jobs of MR %v", cancelled, prNumber)
+		}
+	}
+
 	jobsForImpactedProjects, _, err := gitlab2.ConvertGithubPullRequestEventToJobs(payload, impactedProjects, nil, *config)
 	if err != nil {
 		log.Printf("Error converting event to jobsForImpactedProjects: %v", err)
@@ -279,6 +317,7 @@ func handlePullRequestEvent(gitlabProvider utils.GitlabProvider, payload *gitlab
 			}
 			err = dg_locking.PerformLockingActionFromCommand(prLock, *diggerCommand)
 			if err != nil {
+				metrics.LockFailed(prLock.LockId(), prNumber)
 				utils.InitCommentReporter(glService, prNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
 				return fmt.Errorf("failed to perform lock action on project: %v, %v", project.Name, err)
 			}
@@ -341,31 +380,7 @@ func handlePullRequestEvent(gitlabProvider utils.GitlabProvider, payload *gitlab
 
 	segment.Track(strconv.Itoa(int(organisationId)), "backend_trigger_job")
 
-	ciBackend, err := ciBackendProvider.GetCiBackend(
-		ci_backends.CiBackendOptions{
-			RepoName:                 repoName,
-			RepoOwner:                repoOwner,
-			RepoFullName:             repoFullName,
-			GitlabProjectId:          projectId,
-			GitlabCIMergeRequestID:   payload.ObjectAttributes.ID,
-			GitlabCIMergeRequestIID:  payload.ObjectAttributes.IID,
-			GitlabciprojectId:        payload.Project.ID,
-			GitlabciprojectNamespace: payload.Project.Namespace,
-			//GitlabciprojectNamespaceId: 0,
-			GitlabmergeRequestEventName: payload.EventType,
-			//GitlabCIPipelineID: ,
-			//GitlabCIPipelineIID: "",
-			GitlabCIProjectName: payload.Project.Name,
-			GitlabDiscussionId:  discussionId,
-		},
-	)
-	if err != nil {
-		log.Printf("GetCiBackend error: %v", err)
-		utils.InitCommentReporter(glService, prNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
-		return fmt.Errorf("error fetching ci backed %v", err)
-	}
-
-	err = controllers.TriggerDiggerJobs(ciBackend, repoFullName, repoOwner, repoName, batchId, prNumber, glService, nil)
+	err = controllers.TriggerDiggerJobs(ctx, ciBackend, repoFullName, repoOwner, repoName, batchId, prNumber, glService, nil)
 	if err != nil {
 		log.Printf("TriggerDiggerJobs error: %v", err)
 		utils.InitCommentReporter(glService, prNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
@@ -375,7 +390,7 @@ func handlePullRequestEvent(gitlabProvider utils.GitlabProvider, payload *gitlab
 	return nil
 }
 
-func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitlab.MergeCommentEvent, ciBackendProvider ci_backends.CiBackendProvider, organisationId uint) error {
+func handleIssueCommentEvent(ctx context.Context, gitlabProvider utils.GitlabProvider, payload *gitlab.MergeCommentEvent, ciBackendProvider ci_backends.CiBackendProvider, organisationId uint) error {
 	projectId := payload.ProjectID
 	repoFullName := payload.Project.PathWithNamespace
 	repoOwner, repoName, _ := strings.Cut(repoFullName, "/")
@@ -436,6 +451,65 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 		return fmt.Errorf("unkown digger command in comment %v", err)
 	}
 
+	models.DB.RecordAuditEvent(models.AuditEvent{
+		OrganisationID: organisationId,
+		Type:           models.AuditCommandRequested,
+		Actor:          actor,
+		Repo:           repoFullName,
+		PrNumber:       issueNumber,
+		CommitSha:      commitSha,
+		CommentUrl:     payload.ObjectAttributes.URL,
+		Command:        string(*diggerCommand),
+		Details:        map[string]string{"comment": commentBody},
+	})
+
+	ciBackend, err := ciBackendProvider.GetCiBackend(
+		ci_backends.CiBackendOptions{
+			RepoName:                 repoName,
+			RepoOwner:                repoOwner,
+			RepoFullName:             repoFullName,
+			GitlabProjectId:          projectId,
+			GitlabCIMergeRequestID:   payload.MergeRequest.ID,
+			GitlabCIMergeRequestIID:  payload.MergeRequest.IID,
+			GitlabciprojectId:        payload.ProjectID,
+			GitlabciprojectNamespace: payload.Project.Namespace,
+			//GitlabciprojectNamespaceId:  payload.Project.Namespace,
+			GitlabmergeRequestEventName: payload.EventType,
+			//GitlabCIPipelineID: ,
+			//GitlabCIPipelineIID: "",
+			GitlabCIProjectName: payload.Project.Name,
+			GitlabDiscussionId:  discussionId,
+		},
+	)
+	if err != nil {
+		log.Printf("GetCiBackend error: %v", err)
+		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
+		return fmt.Errorf("error fetching ci backed %v", err)
+	}
+
+	if *diggerCommand == scheduler.DiggerCommandCancel {
+		// cancelling is subject to the access policy of the projects like plan and apply
+		deniedProjects, err := services.CancelDeniedProjects(organisationId, glService, glService, models.DiggerVCSGitlab, repoFullName, repoOwner, repoName, issueNumber, actor)
+		if err != nil {
+			log.Printf("CancelDeniedProjects error: %v", err)
+			utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: Could not check access policy: %v", err))
+			return fmt.Errorf("error checking access policy: %v", err)
+		}
+		if len(deniedProjects) > 0 {
+			log.Printf("%v is not allowed to cancel jobs of projects %v", actor, deniedProjects)
+			utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: %v is not allowed to perform action: cancel on projects: %v. Check your policies :x:", actor, strings.Join(deniedProjects, ", ")))
+			return nil
+		}
+		cancelled, err := services.CancelActiveBatchesForPr(ciBackend, models.DiggerVCSGitlab, repoFullName, issueNumber, scheduler.BatchJobCancelled, false)
+		if err != nil {
+			log.Printf("CancelActiveBatchesForPr error: %v", err)
+			utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: Could not cancel jobs: %v", err))
+			return fmt.Errorf("error cancelling jobs: %v", err)
+		}
+		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":white_check_mark: Cancelled %v jobs", cancelled))
+		return nil
+	}
+
 	prBranchName, _, err := glService.GetBranchName(issueNumber)
 	if err != nil {
 		log.Printf("GetBranchName error: %v", err)
@@ -443,7 +517,7 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 		return fmt.Errorf("error while fetching branch name")
 	}
 
-	impactedProjects, impactedProjectsSourceMapping, requestedProject, _, err := generic.ProcessIssueCommentEvent(issueNumber, commentBody, config, projectsGraph, glService)
+	impactedProjects, impactedProjectsSourceMapping, requestedProjects, _, err := generic.ProcessIssueCommentEvent(issueNumber, commentBody, config, projectsGraph, glService)
 	if err != nil {
 		log.Printf("Error processing event: %v", err)
 		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: Error processing event: %v", err))
@@ -453,7 +527,8 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 
 	// perform unlocking in backend
 	if config.PrLocks {
-		for _, project := range impactedProjects {
+		lockProjects, _ := scheduler.ProjectsToRun(impactedProjects, requestedProjects)
+		for _, project := range lockProjects {
 			prLock := dg_locking.PullRequestLock{
 				InternalLock: locking.BackendDBLock{
 					OrgId: organisationId,
@@ -466,6 +541,7 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 			}
 			err = dg_locking.PerformLockingActionFromCommand(prLock, *diggerCommand)
 			if err != nil {
+				metrics.LockFailed(prLock.LockId(), issueNumber)
 				utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", project.Name, err))
 				return fmt.Errorf("failed perform lock action on project: %v %v", project.Name, err)
 			}
@@ -479,7 +555,7 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 		return nil
 	}
 
-	jobs, _, err := generic.ConvertIssueCommentEventToJobs(repoFullName, actor, issueNumber, commentBody, impactedProjects, requestedProject, config.Workflows, prBranchName, defaultBranch)
+	jobs, _, err := generic.ConvertIssueCommentEventToJobs(repoFullName, actor, issueNumber, commentBody, impactedProjects, requestedProjects, config.Workflows, prBranchName, defaultBranch)
 	if err != nil {
 		log.Printf("Error converting event to jobs: %v", err)
 		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: Error converting event to jobs: %v", err))
@@ -508,10 +584,8 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 		fmt.Errorf("error setting status for PR: %v", err)
 	}
 
-	impactedProjectsMap := make(map[string]dg_configuration.Project)
-	for _, p := range impactedProjects {
-		impactedProjectsMap[p.Name] = p
-	}
+	// the jobs are created for the projects selected by the comment, which may not all be impacted
+	impactedProjectsMap := utils.ProjectsToRunMap(impactedProjects, requestedProjects)
 
 	impactedProjectsJobMap := make(map[string]scheduler.Job)
 	for _, j := range jobs {
@@ -562,30 +636,7 @@ func handleIssueCommentEvent(gitlabProvider utils.GitlabProvider, payload *gitla
 
 	segment.Track(strconv.Itoa(int(organisationId)), "backend_trigger_job")
 
-	ciBackend, err := ciBackendProvider.GetCiBackend(
-		ci_backends.CiBackendOptions{
-			RepoName:                 repoName,
-			RepoOwner:                repoOwner,
-			RepoFullName:             repoFullName,
-			GitlabProjectId:          projectId,
-			GitlabCIMergeRequestID:   payload.MergeRequest.ID,
-			GitlabCIMergeRequestIID:  payload.MergeRequest.IID,
-			GitlabciprojectId:        payload.ProjectID,
-			GitlabciprojectNamespace: payload.Project.Namespace,
-			//GitlabciprojectNamespaceId:  payload.Project.Namespace,
-			GitlabmergeRequestEventName: payload.EventType,
-			//GitlabCIPipelineID: ,
-			//GitlabCIPipelineIID: "",
-			GitlabCIProjectName: payload.Project.Name,
-			GitlabDiscussionId:  discussionId,
-		},
-	)
-	if err != nil {
-		log.Printf("GetCiBackend error: %v", err)
-		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: GetCiBackend error: %v", err))
-		return fmt.Errorf("error fetching ci backed %v", err)
-	}
-	err = controllers.TriggerDiggerJobs(ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, glService, nil)
+	err = controllers.TriggerDiggerJobs(ctx, ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, glService, nil)
 	if err != nil {
 		log.Printf("TriggerDiggerJobs error: %v", err)
 		utils.InitCommentReporter(glService, issueNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
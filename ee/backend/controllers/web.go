@@ -140,9 +140,12 @@ func (web *WebController) AddPolicyPage(c *gin.Context) {
 
 		log.Printf("repo: %v\n", project.Repo)
 
-		policy := models.Policy{Project: project, Policy: policyText, Type: policyType, Organisation: project.Organisation, Repo: project.Repo}
+		policy := models.Policy{Project: project, Type: policyType, Organisation: project.Organisation, Repo: project.Repo}
 
 		err = models.DB.GormDB.Create(&policy).Error
+		if err == nil {
+			_, err = models.DB.CreatePolicyVersion(&policy, policyText, nil, "web", "created from web ui")
+		}
 		if err != nil {
 			log.Printf("Failed to create a new policy, %v\n", err)
 			message := "Failed to create a policy"
@@ -262,8 +265,17 @@ func (web *WebController) PolicyDetailsUpdatePage(c *gin.Context) {
 	if policyText == "" {
 		services.AddWarning(c, "Policy can't be empty.")
 	} else if policyText != policy.Policy {
-		policy.Policy = policyText
-		models.DB.GormDB.Save(policy)
+		_, err := models.DB.CreatePolicyVersion(policy, policyText, nil, "web", "updated from web ui")
+		if err != nil {
+			log.Printf("Failed to update policy, %v\n", err)
+			services.AddError(c, "Failed to update policy")
+			pageContext := services.GetMessages(c)
+			maps.Copy(pageContext, gin.H{
+				"Policy": policy,
+			})
+			c.HTML(http.StatusOK, "policy_details.tmpl", pageContext)
+			return
+		}
 		log.Printf("Policy has been updated. policy id: %v\n", policy.ID)
 		services.AddMessage(c, "Policy has been updated successfully")
 		c.Redirect(http.StatusFound, "/policies")
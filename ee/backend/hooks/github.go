@@ -1,10 +1,12 @@
 package hooks
 
 import (
+	"context"
 	"fmt"
 	"github.com/diggerhq/digger/backend/ci_backends"
 	ce_controllers "github.com/diggerhq/digger/backend/controllers"
 	"github.com/diggerhq/digger/backend/locking"
+	"github.com/diggerhq/digger/backend/metrics"
 	"github.com/diggerhq/digger/backend/models"
 	"github.com/diggerhq/digger/backend/utils"
 	"github.com/diggerhq/digger/libs/ci/generic"
@@ -102,6 +104,7 @@ var DriftReconcilliationHook ce_controllers.IssueCommentHook = func(gh utils.Git
 	}
 	err = dg_locking.PerformLockingActionFromCommand(prLock, *diggerCommand)
 	if err != nil {
+		metrics.LockFailed(prLock.LockId(), issueNumber)
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: Failed perform lock action on project: %v %v", projectName, err))
 		return fmt.Errorf("failed perform lock action on project: %v %v", projectName, err)
 	}
@@ -173,7 +176,7 @@ var DriftReconcilliationHook ce_controllers.IssueCommentHook = func(gh utils.Git
 		return fmt.Errorf("error fetching ci backed %v", err)
 	}
 
-	err = ce_controllers.TriggerDiggerJobs(ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, ghService, gh)
+	err = ce_controllers.TriggerDiggerJobs(context.Background(), ciBackend, repoFullName, repoOwner, repoName, batchId, issueNumber, ghService, gh)
 	if err != nil {
 		log.Printf("TriggerDiggerJobs error: %v", err)
 		utils.InitCommentReporter(ghService, issueNumber, fmt.Sprintf(":x: TriggerDiggerJobs error: %v", err))
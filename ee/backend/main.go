@@ -2,7 +2,6 @@ package main
 
 import (
 	"embed"
-	"fmt"
 	"github.com/diggerhq/digger/backend/bootstrap"
 	"github.com/diggerhq/digger/backend/config"
 	ce_controllers "github.com/diggerhq/digger/backend/controllers"
@@ -95,8 +94,7 @@ func main() {
 	jobArtefactsGroup.PUT("/", controllers.SetJobArtefact)
 	jobArtefactsGroup.GET("/", controllers.DownloadJobArtefact)
 
-	port := config.GetPort()
-	r.Run(fmt.Sprintf(":%d", port))
+	bootstrap.Run(r, config.GetPort())
 }
 
 func init() {
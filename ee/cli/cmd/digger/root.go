@@ -130,7 +130,14 @@ func NewBackendApi(hostName string, authToken string) backendapi.Api {
 
 func NewPolicyChecker(hostname string, organisationName string, authToken string) policy.Checker {
 	var policyChecker policy.Checker
-	if os.Getenv("NO_BACKEND") == "true" {
+	if policyDir := policy.PolicyDirFromEnv(); policyDir != "" {
+		log.Printf("Loading policies from local directory %v", policyDir)
+		policyChecker = policy.DiggerPolicyChecker{
+			PolicyProvider: policy.DirPolicyProvider{
+				PolicyDir:          policyDir,
+				DiggerOrganisation: organisationName,
+			}}
+	} else if os.Getenv("NO_BACKEND") == "true" {
 		log.Println("WARNING: running in 'backendless' mode. Features that require backend will not be available.")
 		policyChecker = policy.NoOpPolicyChecker{}
 	} else {
@@ -140,6 +147,8 @@ func NewPolicyChecker(hostname string, organisationName string, authToken string
 				DiggerOrganisation: organisationName,
 				AuthToken:          authToken,
 				HttpClient:         http.DefaultClient,
+				PolicyVersions:     policy.PolicyVersionsFromEnv(),
+				Cache:              policy.DefaultPolicyCache,
 			}}
 	}
 	return policyChecker
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/diggerhq/digger/ee/drift/dbmodels"
+	"github.com/diggerhq/digger/ee/drift/metrics"
 	"github.com/diggerhq/digger/ee/drift/model"
 	"github.com/diggerhq/digger/libs/terraform_utils"
 	"github.com/gin-gonic/gin"
@@ -98,6 +99,10 @@ func (mc MainController) SetJobStatusForProject(c *gin.Context) {
 		return
 	}
 
+	if job.Status == string(dbmodels.DiggerJobSucceeded) || job.Status == string(dbmodels.DiggerJobFailed) {
+		metrics.DriftJobFinished(job.Status)
+	}
+
 	job.UpdatedAt = request.Timestamp
 	err = dbmodels.DB.GormDB.Save(job).Error
 	if err != nil {
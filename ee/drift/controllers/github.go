@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/diggerhq/digger/backend/utils"
 	"github.com/diggerhq/digger/ee/drift/dbmodels"
+	"github.com/diggerhq/digger/ee/drift/metrics"
 	"github.com/diggerhq/digger/ee/drift/middleware"
 	"github.com/diggerhq/digger/ee/drift/model"
 	"github.com/diggerhq/digger/ee/drift/tasks"
@@ -34,6 +35,7 @@ func (mc MainController) GithubAppWebHook(c *gin.Context) {
 	}
 
 	webhookType := github.WebHookType(c.Request)
+	metrics.WebhookReceived("github", webhookType)
 	event, err := github.ParseWebHook(webhookType, payload)
 	if err != nil {
 		log.Printf("Failed to parse Github Event. :%v\n", err)
@@ -88,3 +88,24 @@ func (db *Database) LoadProjectsForOrg(orgId string) ([]*model.Project, error) {
 	}
 	return orgProjects, nil
 }
+
+type ProjectDriftStatus struct {
+	RepoFullName string
+	ProjectName  string
+	DriftStatus  string
+}
+
+// GetProjectDriftStatuses returns the drift status of every project with drift detection enabled
+func (db *Database) GetProjectDriftStatuses() ([]ProjectDriftStatus, error) {
+	var statuses []ProjectDriftStatus
+	err := db.GormDB.Model(&model.Project{}).
+		Select("repos.repo_full_name, projects.name AS project_name, projects.drift_status").
+		Joins("INNER JOIN repos ON projects.repo_id = repos.id").
+		Where("projects.drift_enabled = ?", true).
+		Scan(&statuses).Error
+	if err != nil {
+		log.Printf("Unknown error occurred while fetching database, %v\n", err)
+		return nil, err
+	}
+	return statuses, nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/diggerhq/digger/backend/ci_backends"
 	"github.com/diggerhq/digger/ee/drift/controllers"
 	"github.com/diggerhq/digger/ee/drift/dbmodels"
+	"github.com/diggerhq/digger/ee/drift/metrics"
 	"github.com/diggerhq/digger/ee/drift/middleware"
 	next_utils "github.com/diggerhq/digger/next/utils"
 	"github.com/getsentry/sentry-go"
@@ -62,6 +63,14 @@ func main() {
 		CiBackendProvider:    ci_backends.DefaultBackendProvider{},
 	}
 
+	metrics.RegisterDriftStatusCollector()
+	// like the backend the metrics are served on their own address, empty to disable them
+	metricsAddress, ok := os.LookupEnv("DIGGER_METRICS_ADDRESS")
+	if !ok {
+		metricsAddress = ":9090"
+	}
+	go metrics.Serve(metricsAddress)
+
 	r.GET("/ping", controller.Ping)
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
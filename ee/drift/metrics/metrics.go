@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/diggerhq/digger/ee/drift/dbmodels"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var webhookEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_webhook_events_total",
+	Help: "Webhook events received, by VCS and event type.",
+}, []string{"vcs", "event"})
+
+var driftJobsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "digger_drift_jobs_finished_total",
+	Help: "Drift detection jobs that reached a final status, by status.",
+}, []string{"status"})
+
+var driftStatusDesc = prometheus.NewDesc("digger_drift_status", "Drift status of every project with drift detection enabled, 1 for the current status.", []string{"repo", "project", "status"}, nil)
+
+// Serve serves the prometheus metrics at /metrics on address, apart from the API so that they are only exposed
+// to the monitoring network. Metrics are not served when address is empty.
+func Serve(address string) {
+	if address == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(address, mux)
+	if err != nil {
+		log.Printf("could not serve metrics on %v: %v", address, err)
+	}
+}
+
+// WebhookReceived counts a webhook event received from a VCS
+func WebhookReceived(vcs string, event string) {
+	webhookEvents.WithLabelValues(vcs, event).Inc()
+}
+
+// DriftJobFinished counts a drift detection job that succeeded or failed
+func DriftJobFinished(status string) {
+	driftJobsFinished.WithLabelValues(status).Inc()
+}
+
+// driftStatusCollector reads the drift status of the projects from the database when metrics are scraped
+type driftStatusCollector struct{}
+
+func (driftStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- driftStatusDesc
+}
+
+func (driftStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	statuses, err := dbmodels.DB.GetProjectDriftStatuses()
+	if err != nil {
+		log.Printf("could not get drift status of projects: %v", err)
+		return
+	}
+	for _, s := range statuses {
+		ch <- prometheus.MustNewConstMetric(driftStatusDesc, prometheus.GaugeValue, 1, s.RepoFullName, s.ProjectName, s.DriftStatus)
+	}
+}
+
+// RegisterDriftStatusCollector adds the drift status of the projects to the metrics
+func RegisterDriftStatusCollector() {
+	prometheus.MustRegister(driftStatusCollector{})
+}
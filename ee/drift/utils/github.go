@@ -15,7 +15,6 @@ import (
 	"log"
 	net "net/http"
 	"os"
-	"path"
 	"strconv"
 )
 
@@ -104,13 +103,18 @@ func GetDiggerConfigForBranch(gh utils.GithubClientProvider, installationId int6
 	var changedFiles []string = nil
 
 	err = utils2.CloneGitRepoAndDoAction(cloneUrl, branch, *token, func(dir string) error {
-		diggerYmlBytes, err := os.ReadFile(path.Join(dir, "digger.yml"))
-		diggerYmlStr = string(diggerYmlBytes)
+		var err error
 		config, _, dependencyGraph, err = dg_configuration.LoadDiggerConfig(dir, true, changedFiles)
 		if err != nil {
 			log.Printf("Error loading digger config: %v", err)
 			return err
 		}
+		// the stored config is loaded later without the repository, so includes and project files are merged in
+		diggerYmlStr, err = dg_configuration.LoadMergedDiggerConfigString(dir)
+		if err != nil {
+			log.Printf("Error merging digger config: %v", err)
+			return err
+		}
 		return nil
 	})
 	if err != nil {
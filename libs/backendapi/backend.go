@@ -9,7 +9,11 @@ import (
 type Api interface {
 	ReportProject(repo string, projectName string, configuration string) error
 	ReportProjectRun(repo string, projectName string, startedAt time.Time, endedAt time.Time, status string, command string, output string) error
-	ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string) (*scheduler.SerializedBatch, error)
+	ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string, projectOutputs map[string]string) (*scheduler.SerializedBatch, error)
+	// ReportJobHeartbeat tells the backend that the job is still running
+	ReportJobHeartbeat(repo string, projectName string, jobId string) error
+	// ReportJobLogs appends a chunk of the output of the job to its live logs
+	ReportJobLogs(repo string, projectName string, jobId string, content string) error
 	UploadJobArtefact(zipLocation string) (*int, *string, error)
 	DownloadJobArtefact(downloadTo string) (*string, error)
 }
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/diggerhq/digger/libs/redact"
 	"github.com/diggerhq/digger/libs/scheduler"
 	"github.com/diggerhq/digger/libs/terraform_utils"
+	"github.com/diggerhq/digger/libs/tracing"
 	"io"
 	"log"
 	"mime"
@@ -29,10 +31,18 @@ func (n NoopApi) ReportProjectRun(namespace string, projectName string, startedA
 	return nil
 }
 
-func (n NoopApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string) (*scheduler.SerializedBatch, error) {
+func (n NoopApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string, projectOutputs map[string]string) (*scheduler.SerializedBatch, error) {
 	return nil, nil
 }
 
+func (n NoopApi) ReportJobHeartbeat(repo string, projectName string, jobId string) error {
+	return nil
+}
+
+func (n NoopApi) ReportJobLogs(repo string, projectName string, jobId string, content string) error {
+	return nil
+}
+
 func (n NoopApi) UploadJobArtefact(zipLocation string) (*int, *string, error) {
 	return nil, nil, nil
 }
@@ -45,6 +55,8 @@ type DiggerApi struct {
 	DiggerHost string
 	AuthToken  string
 	HttpClient *http.Client
+	// trace context of the job, sent with every request so that the spans of the backend join its trace
+	TraceContext map[string]string
 }
 
 func (d DiggerApi) ReportProject(namespace string, projectName string, configurationYaml string) error {
@@ -72,6 +84,7 @@ func (d DiggerApi) ReportProject(namespace string, projectName string, configura
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
 
 	resp, err := d.HttpClient.Do(req)
 
@@ -99,7 +112,7 @@ func (d DiggerApi) ReportProjectRun(namespace string, projectName string, starte
 		"endedAt":   endedAt,
 		"status":    status,
 		"command":   command,
-		"output":    output,
+		"output":    redact.String(output),
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -115,6 +128,7 @@ func (d DiggerApi) ReportProjectRun(namespace string, projectName string, starte
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
 
 	resp, err := d.HttpClient.Do(req)
 
@@ -129,7 +143,7 @@ func (d DiggerApi) ReportProjectRun(namespace string, projectName string, starte
 	return nil
 }
 
-func (d DiggerApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string) (*scheduler.SerializedBatch, error) {
+func (d DiggerApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string, projectOutputs map[string]string) (*scheduler.SerializedBatch, error) {
 	u, err := url.Parse(d.DiggerHost)
 	if err != nil {
 		log.Fatalf("Not able to parse digger cloud url: %v", err)
@@ -159,7 +173,8 @@ func (d DiggerApi) ReportProjectJobStatus(repo string, projectName string, jobId
 		"job_summary":        planSummaryJson,
 		"job_plan_footprint": planFootprint.ToJson(),
 		"pr_comment_url":     PrCommentUrl,
-		"terraform_output":   terraformOutput,
+		"terraform_output":   redact.String(terraformOutput),
+		"project_outputs":    projectOutputs,
 	}
 
 	jsonData, err := json.Marshal(request)
@@ -175,6 +190,7 @@ func (d DiggerApi) ReportProjectJobStatus(repo string, projectName string, jobId
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
 
 	resp, err := d.HttpClient.Do(req)
 
@@ -197,6 +213,66 @@ func (d DiggerApi) ReportProjectJobStatus(repo string, projectName string, jobId
 	return &response, nil
 }
 
+func (d DiggerApi) ReportJobHeartbeat(repo string, projectName string, jobId string) error {
+	u, err := url.Parse(d.DiggerHost)
+	if err != nil {
+		return fmt.Errorf("not able to parse digger cloud url: %v", err)
+	}
+	u.Path = filepath.Join(u.Path, "repos", repo, "projects", projectName, "jobs", jobId, "heartbeat")
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("error while creating request: %v", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
+
+	resp, err := d.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status when reporting a job heartbeat: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d DiggerApi) ReportJobLogs(repo string, projectName string, jobId string, content string) error {
+	u, err := url.Parse(d.DiggerHost)
+	if err != nil {
+		return fmt.Errorf("not able to parse digger cloud url: %v", err)
+	}
+	u.Path = filepath.Join(u.Path, "repos", repo, "projects", projectName, "jobs", jobId, "logs")
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"content": redact.String(content),
+	})
+	if err != nil {
+		return fmt.Errorf("not able to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("error while creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
+
+	resp, err := d.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status when reporting job logs: %v", resp.StatusCode)
+	}
+	return nil
+}
+
 func (d DiggerApi) UploadJobArtefact(zipLocation string) (*int, *string, error) {
 	u, err := url.Parse(d.DiggerHost)
 	if err != nil {
@@ -247,6 +323,7 @@ func (d DiggerApi) UploadJobArtefact(zipLocation string) (*int, *string, error)
 	// Set the content type header
 	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
 
 	// Send the request
 	client := &http.Client{}
@@ -298,6 +375,7 @@ func (d DiggerApi) DownloadJobArtefact(downloadTo string) (*string, error) {
 
 	// Set the content type header
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", d.AuthToken))
+	tracing.InjectHeaders(d.TraceContext, req.Header)
 
 	// Send the request
 	client := &http.Client{}
@@ -327,15 +405,21 @@ func (d DiggerApi) DownloadJobArtefact(downloadTo string) (*string, error) {
 }
 
 func NewBackendApi(hostName string, authToken string) Api {
+	return NewTracedBackendApi(hostName, authToken, nil)
+}
+
+// NewTracedBackendApi returns an api whose requests propagate traceContext, as returned by tracing.Inject
+func NewTracedBackendApi(hostName string, authToken string, traceContext map[string]string) Api {
 	var backendApi Api
 	if os.Getenv("NO_BACKEND") == "true" {
 		log.Println("WARNING: running in 'backendless' mode. Features that require backend will not be available.")
 		backendApi = NoopApi{}
 	} else {
 		backendApi = DiggerApi{
-			DiggerHost: hostName,
-			AuthToken:  authToken,
-			HttpClient: http.DefaultClient,
+			DiggerHost:   hostName,
+			AuthToken:    authToken,
+			HttpClient:   http.DefaultClient,
+			TraceContext: traceContext,
 		}
 	}
 	return backendApi
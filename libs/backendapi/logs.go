@@ -0,0 +1,100 @@
+package backendapi
+
+import (
+	"bytes"
+	"log"
+	"sync"
+	"time"
+)
+
+// interval at which buffered output is sent to the backend
+const jobLogsFlushInterval = 2 * time.Second
+
+// size of buffered output above which it is sent right away
+const jobLogsMaxChunkSize = 64 * 1024
+
+// JobLogStreamer is a writer sending the output written to it to the live logs of a job in chunks. Chunks are
+// sent in order from a single goroutine, so that slow requests to the backend don't slow the commands down.
+// Chunks end on a line break so that secrets aren't split across chunks, where they couldn't be redacted.
+type JobLogStreamer struct {
+	api         Api
+	repo        string
+	projectName string
+	jobId       string
+
+	mu     sync.Mutex
+	buffer bytes.Buffer
+	flush  chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+}
+
+func NewJobLogStreamer(api Api, repo string, projectName string, jobId string) *JobLogStreamer {
+	s := &JobLogStreamer{
+		api:         api,
+		repo:        repo,
+		projectName: projectName,
+		jobId:       jobId,
+		flush:       make(chan struct{}, 1),
+		done:        make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *JobLogStreamer) Write(data []byte) (int, error) {
+	s.mu.Lock()
+	s.buffer.Write(data)
+	full := s.buffer.Len() >= jobLogsMaxChunkSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(data), nil
+}
+
+// Close sends the output still buffered and stops the streamer
+func (s *JobLogStreamer) Close() error {
+	close(s.done)
+	<-s.closed
+	return nil
+}
+
+func (s *JobLogStreamer) run() {
+	defer close(s.closed)
+	ticker := time.NewTicker(jobLogsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			s.send(true)
+			return
+		case <-s.flush:
+		case <-ticker.C:
+		}
+		s.send(false)
+	}
+}
+
+// send sends the complete lines buffered, the incomplete last line as well when final is set
+func (s *JobLogStreamer) send(final bool) {
+	s.mu.Lock()
+	n := s.buffer.Len()
+	if !final {
+		n = bytes.LastIndexByte(s.buffer.Bytes(), '\n') + 1
+	}
+	content := string(s.buffer.Next(n))
+	s.mu.Unlock()
+	if content == "" {
+		return
+	}
+	err := s.api.ReportJobLogs(s.repo, s.projectName, s.jobId, content)
+	if err != nil {
+		// live logs are best effort, the output is still reported when the job completes
+		log.Printf("could not send logs of job %v: %v", s.jobId, err)
+	}
+}
@@ -0,0 +1,56 @@
+package backendapi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type recordingLogsApi struct {
+	MockBackendApi
+	mu     sync.Mutex
+	chunks []string
+}
+
+func (r *recordingLogsApi) ReportJobLogs(repo string, projectName string, jobId string, content string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = append(r.chunks, content)
+	return nil
+}
+
+func TestJobLogStreamerSendsAllOutputInOrder(t *testing.T) {
+	api := &recordingLogsApi{}
+	streamer := NewJobLogStreamer(api, "owner-repo", "project", "job")
+	expected := ""
+	for i := 0; i < 1000; i++ {
+		line := strings.Repeat("x", 100) + "\n"
+		expected += line
+		_, err := streamer.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, streamer.Close())
+
+	assert.Equal(t, expected, strings.Join(api.chunks, ""))
+	assert.Greater(t, len(api.chunks), 1)
+}
+
+func TestJobLogStreamerSendsCompleteLines(t *testing.T) {
+	api := &recordingLogsApi{}
+	streamer := NewJobLogStreamer(api, "owner-repo", "project", "job")
+	_, err := streamer.Write([]byte("password: hunter2\nto"))
+	assert.NoError(t, err)
+	_, err = streamer.Write([]byte(strings.Repeat("x", jobLogsMaxChunkSize)))
+	assert.NoError(t, err)
+	streamer.send(false)
+	assert.Equal(t, []string{"password: hunter2\n"}, api.chunks)
+
+	_, err = streamer.Write([]byte("ken\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, streamer.Close())
+	assert.Equal(t, "to"+strings.Repeat("x", jobLogsMaxChunkSize)+"ken\n", strings.Join(api.chunks[1:], ""))
+	for _, chunk := range api.chunks {
+		assert.True(t, strings.HasSuffix(chunk, "\n"))
+	}
+}
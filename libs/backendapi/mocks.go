@@ -17,10 +17,18 @@ func (t MockBackendApi) ReportProjectRun(repo string, projectName string, starte
 	return nil
 }
 
-func (t MockBackendApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string) (*scheduler.SerializedBatch, error) {
+func (t MockBackendApi) ReportProjectJobStatus(repo string, projectName string, jobId string, status string, timestamp time.Time, summary *terraform_utils.TerraformSummary, planJson string, PrCommentUrl string, terraformOutput string, projectOutputs map[string]string) (*scheduler.SerializedBatch, error) {
 	return nil, nil
 }
 
+func (t MockBackendApi) ReportJobHeartbeat(repo string, projectName string, jobId string) error {
+	return nil
+}
+
+func (t MockBackendApi) ReportJobLogs(repo string, projectName string, jobId string, content string) error {
+	return nil
+}
+
 func (t MockBackendApi) UploadJobArtefact(zipLocation string) (*int, *string, error) {
 	return nil, nil, nil
 }
@@ -388,7 +388,7 @@ func (svc *AzureReposService) GetApprovals(prNumber int) ([]string, error) {
 	return approvals, nil
 }
 
-func ProcessAzureReposEvent(azureEvent interface{}, diggerConfig *digger_config2.DiggerConfig, ciService ci.PullRequestService) ([]digger_config2.Project, *digger_config2.Project, int, error) {
+func ProcessAzureReposEvent(azureEvent interface{}, diggerConfig *digger_config2.DiggerConfig, ciService ci.PullRequestService) ([]digger_config2.Project, []digger_config2.Project, int, error) {
 	var impactedProjects []digger_config2.Project
 	var prNumber int
 
@@ -411,18 +411,11 @@ func ProcessAzureReposEvent(azureEvent interface{}, diggerConfig *digger_config2
 		}
 
 		impactedProjects, _ = diggerConfig.GetModifiedProjects(changedFiles)
-		requestedProject := ci.ParseProjectName(azureEvent.(AzureCommentEvent).Resource.Comment.Content)
-
-		if requestedProject == "" {
-			return impactedProjects, nil, prNumber, nil
-		}
-
-		for _, project := range impactedProjects {
-			if project.Name == requestedProject {
-				return impactedProjects, &project, prNumber, nil
-			}
+		requestedProjects, err := scheduler.SelectRequestedProjects(azureEvent.(AzureCommentEvent).Resource.Comment.Content, impactedProjects, diggerConfig.Projects)
+		if err != nil {
+			return nil, nil, 0, err
 		}
-		return nil, nil, 0, fmt.Errorf("requested project not found in modified projects")
+		return impactedProjects, requestedProjects, prNumber, nil
 
 	default:
 		return nil, nil, 0, fmt.Errorf("unsupported event type")
@@ -430,7 +423,7 @@ func ProcessAzureReposEvent(azureEvent interface{}, diggerConfig *digger_config2
 	return impactedProjects, nil, prNumber, nil
 }
 
-func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []digger_config2.Project, requestedProject *digger_config2.Project, workflows map[string]digger_config2.Workflow) ([]scheduler.Job, bool, error) {
+func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []digger_config2.Project, requestedProjects []digger_config2.Project, workflows map[string]digger_config2.Workflow) ([]scheduler.Job, bool, error) {
 	jobs := make([]scheduler.Job, 0)
 	//&dependencyGraph, diggerProjectNamespace, parsedAzureContext.BaseUrl, parsedAzureContext.EventType, prNumber,
 
@@ -452,26 +445,34 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 			}
 
 			prNumber := parseAzureContext.Event.(AzurePrEvent).Resource.PullRequestId
-			stateEnvVars, commandEnvVars := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			stateEnvVars, commandEnvVars, err := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+			}
 			StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestPushed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				PullRequestNumber:  &prNumber,
-				EventName:          parseAzureContext.EventType,
-				RequestedBy:        parseAzureContext.BaseUrl,
-				Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
-				StateEnvVars:       stateEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvProvider:   StateEnvProvider,
-				CommandEnvProvider: CommandEnvProvider,
-				SkipMergeCheck:     skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestPushed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				PullRequestNumber:    &prNumber,
+				EventName:            parseAzureContext.EventType,
+				RequestedBy:          parseAzureContext.BaseUrl,
+				Namespace:            parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
+				StateEnvVars:         stateEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvProvider:     StateEnvProvider,
+				CommandEnvProvider:   CommandEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		}
 		return jobs, true, nil
@@ -490,26 +491,34 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 			}
 
 			prNumber := parseAzureContext.Event.(AzurePrEvent).Resource.PullRequestId
-			stateEnvVars, commandEnvVars := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			stateEnvVars, commandEnvVars, err := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+			}
 			StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestClosed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				PullRequestNumber:  &prNumber,
-				EventName:          parseAzureContext.EventType,
-				RequestedBy:        parseAzureContext.BaseUrl,
-				Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
-				StateEnvVars:       stateEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvProvider:   StateEnvProvider,
-				CommandEnvProvider: CommandEnvProvider,
-				SkipMergeCheck:    skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestClosed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				PullRequestNumber:    &prNumber,
+				EventName:            parseAzureContext.EventType,
+				RequestedBy:          parseAzureContext.BaseUrl,
+				Namespace:            parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
+				StateEnvVars:         stateEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvProvider:     StateEnvProvider,
+				CommandEnvProvider:   CommandEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		}
 		return jobs, true, nil
@@ -529,26 +538,34 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 					skipMerge = false
 				}
 
-				stateEnvVars, commandEnvVars := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+				stateEnvVars, commandEnvVars, err := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+				if err != nil {
+					return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+				}
 				StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 				jobs = append(jobs, scheduler.Job{
-					ProjectName:        project.Name,
-					ProjectDir:         project.Dir,
-					ProjectWorkspace:   project.Workspace,
-					Terragrunt:         project.Terragrunt,
-					OpenTofu:           project.OpenTofu,
-					Commands:           workflow.Configuration.OnCommitToDefault,
-					ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-					PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-					PullRequestNumber:  &prNumber,
-					EventName:          parseAzureContext.EventType,
-					RequestedBy:        parseAzureContext.BaseUrl,
-					Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
-					StateEnvVars:       stateEnvVars,
-					CommandEnvVars:     commandEnvVars,
-					StateEnvProvider:   StateEnvProvider,
-					CommandEnvProvider: CommandEnvProvider,
-					SkipMergeCheck:     skipMerge,
+					ProjectName:          project.Name,
+					ProjectDir:           project.Dir,
+					ProjectLabels:        project.Labels,
+					ProjectEnvironment:   project.Environment,
+					ProjectOwners:        project.Owners,
+					RequireOwnerApproval: project.RequireOwnerApproval,
+					ProjectInputs:        project.Inputs,
+					ProjectWorkspace:     project.Workspace,
+					Terragrunt:           project.Terragrunt,
+					OpenTofu:             project.OpenTofu,
+					Commands:             workflow.Configuration.OnCommitToDefault,
+					ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+					PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+					PullRequestNumber:    &prNumber,
+					EventName:            parseAzureContext.EventType,
+					RequestedBy:          parseAzureContext.BaseUrl,
+					Namespace:            parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
+					StateEnvVars:         stateEnvVars,
+					CommandEnvVars:       commandEnvVars,
+					StateEnvProvider:     StateEnvProvider,
+					CommandEnvProvider:   CommandEnvProvider,
+					SkipMergeCheck:       skipMerge,
 				})
 			}
 			return jobs, true, nil
@@ -556,18 +573,9 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 		return jobs, true, nil
 	case AzurePrCommented:
 		diggerCommand := strings.ToLower(parseAzureContext.Event.(AzureCommentEvent).Resource.Comment.Content)
-		coversAllImpactedProjects := true
-		runForProjects := impactedProjects
+		runForProjects, coversAllImpactedProjects := scheduler.ProjectsToRun(impactedProjects, requestedProjects)
 
 		prNumber := parseAzureContext.Event.(AzureCommentEvent).Resource.PullRequest.PullRequestId
-		if requestedProject != nil {
-			if len(impactedProjects) > 1 {
-				coversAllImpactedProjects = false
-				runForProjects = []digger_config2.Project{*requestedProject}
-			} else if len(impactedProjects) == 1 && impactedProjects[0].Name != requestedProject.Name {
-				return jobs, false, fmt.Errorf("requested project %v is not impacted by this PR", requestedProject.Name)
-			}
-		}
 
 		supportedCommands := []string{"digger plan", "digger apply", "digger unlock", "digger lock"}
 		for _, command := range supportedCommands {
@@ -593,26 +601,34 @@ func ConvertAzureEventToCommands(parseAzureContext Azure, impactedProjects []dig
 						skipMerge = false
 					}
 		
-					stateEnvVars, commandEnvVars := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+					stateEnvVars, commandEnvVars, err := digger_config2.CollectTerraformEnvConfig(workflow.EnvVars, true)
+					if err != nil {
+						return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+					}
 					StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 					jobs = append(jobs, scheduler.Job{
-						ProjectName:        project.Name,
-						ProjectDir:         project.Dir,
-						ProjectWorkspace:   workspace,
-						Terragrunt:         project.Terragrunt,
-						OpenTofu:           project.OpenTofu,
-						Commands:           []string{command},
-						ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-						PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-						PullRequestNumber:  &prNumber,
-						EventName:          parseAzureContext.EventType,
-						RequestedBy:        parseAzureContext.BaseUrl,
-						Namespace:          parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
-						StateEnvVars:       stateEnvVars,
-						CommandEnvVars:     commandEnvVars,
-						StateEnvProvider:   StateEnvProvider,
-						CommandEnvProvider: CommandEnvProvider,
-						SkipMergeCheck:    	skipMerge,
+						ProjectName:          project.Name,
+						ProjectDir:           project.Dir,
+						ProjectLabels:        project.Labels,
+						ProjectEnvironment:   project.Environment,
+						ProjectOwners:        project.Owners,
+						RequireOwnerApproval: project.RequireOwnerApproval,
+						ProjectInputs:        project.Inputs,
+						ProjectWorkspace:     workspace,
+						Terragrunt:           project.Terragrunt,
+						OpenTofu:             project.OpenTofu,
+						Commands:             []string{command},
+						ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+						PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+						PullRequestNumber:    &prNumber,
+						EventName:            parseAzureContext.EventType,
+						RequestedBy:          parseAzureContext.BaseUrl,
+						Namespace:            parseAzureContext.BaseUrl + "/" + parseAzureContext.ProjectName,
+						StateEnvVars:         stateEnvVars,
+						CommandEnvVars:       commandEnvVars,
+						StateEnvProvider:     StateEnvProvider,
+						CommandEnvProvider:   CommandEnvProvider,
+						SkipMergeCheck:       skipMerge,
 					})
 				}
 			}
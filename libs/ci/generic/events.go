@@ -18,7 +18,9 @@ func GetRunEnvVars(defaultBranch string, prBranch string, projectName string, pr
 	}
 }
 
-func ProcessIssueCommentEvent(prNumber int, commentBody string, diggerConfig *digger_config.DiggerConfig, dependencyGraph graph.Graph[string, digger_config.Project], ciService ci.PullRequestService) ([]digger_config.Project, map[string]digger_config.ProjectToSourceMapping, *digger_config.Project, int, error) {
+// ProcessIssueCommentEvent returns the projects impacted by the PR and the projects requested by the selectors of
+// the comment, nil if it has none
+func ProcessIssueCommentEvent(prNumber int, commentBody string, diggerConfig *digger_config.DiggerConfig, dependencyGraph graph.Graph[string, digger_config.Project], ciService ci.PullRequestService) ([]digger_config.Project, map[string]digger_config.ProjectToSourceMapping, []digger_config.Project, int, error) {
 	var impactedProjects []digger_config.Project
 	changedFiles, err := ciService.GetChangedFiles(prNumber)
 
@@ -35,18 +37,11 @@ func ProcessIssueCommentEvent(prNumber int, commentBody string, diggerConfig *di
 		}
 	}
 
-	requestedProject := scheduler.ParseProjectName(commentBody)
-
-	if requestedProject == "" {
-		return impactedProjects, impactedProjectsSourceMapping, nil, prNumber, nil
-	}
-
-	for _, project := range impactedProjects {
-		if project.Name == requestedProject {
-			return impactedProjects, impactedProjectsSourceMapping, &project, prNumber, nil
-		}
+	requestedProjects, err := scheduler.SelectRequestedProjects(commentBody, impactedProjects, diggerConfig.Projects)
+	if err != nil {
+		return nil, nil, nil, 0, err
 	}
-	return nil, nil, nil, 0, fmt.Errorf("requested project not found in modified projects")
+	return impactedProjects, impactedProjectsSourceMapping, requestedProjects, prNumber, nil
 }
 
 func FindAllProjectsDependantOnImpactedProjects(impactedProjects []digger_config.Project, dependencyGraph graph.Graph[string, digger_config.Project]) ([]digger_config.Project, error) {
@@ -97,24 +92,12 @@ func FindAllProjectsDependantOnImpactedProjects(impactedProjects []digger_config
 	return impactedProjectsWithDependantProjects, nil
 }
 
-func ConvertIssueCommentEventToJobs(repoFullName string, requestedBy string, prNumber int, commentBody string, impactedProjects []digger_config.Project, requestedProject *digger_config.Project, workflows map[string]digger_config.Workflow, prBranchName string, defaultBranch string) ([]scheduler.Job, bool, error) {
-	jobs := make([]scheduler.Job, 0)
+func ConvertIssueCommentEventToJobs(repoFullName string, requestedBy string, prNumber int, commentBody string, impactedProjects []digger_config.Project, requestedProjects []digger_config.Project, workflows map[string]digger_config.Workflow, prBranchName string, defaultBranch string) ([]scheduler.Job, bool, error) {
 	prBranch := prBranchName
 
 	supportedCommands := []string{"digger plan", "digger apply", "digger unlock", "digger lock"}
 
-	coversAllImpactedProjects := true
-
-	runForProjects := impactedProjects
-
-	if requestedProject != nil {
-		if len(impactedProjects) > 1 {
-			coversAllImpactedProjects = false
-			runForProjects = []digger_config.Project{*requestedProject}
-		} else if len(impactedProjects) == 1 && impactedProjects[0].Name != requestedProject.Name {
-			return jobs, false, fmt.Errorf("requested project %v is not impacted by this PR", requestedProject.Name)
-		}
-	}
+	runForProjects, coversAllImpactedProjects := scheduler.ProjectsToRun(impactedProjects, requestedProjects)
 	diggerCommand := strings.ToLower(commentBody)
 	diggerCommand = strings.TrimSpace(diggerCommand)
 	var commandToRun string
@@ -155,29 +138,37 @@ func CreateJobsForProjects(projects []digger_config.Project, command string, eve
 		}
 
 		runEnvVars := GetRunEnvVars(defaultBranch, prBranch, project.Name, project.Dir)
-		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		if err != nil {
+			return nil, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+		}
 		StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 		workspace := project.Workspace
 		jobs = append(jobs, scheduler.Job{
-			ProjectName:        project.Name,
-			ProjectDir:         project.Dir,
-			ProjectWorkspace:   workspace,
-			ProjectWorkflow:    project.Workflow,
-			Terragrunt:         project.Terragrunt,
-			OpenTofu:           project.OpenTofu,
-			Commands:           []string{command},
-			ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-			PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-			RunEnvVars:         runEnvVars,
-			CommandEnvVars:     commandEnvVars,
-			StateEnvVars:       stateEnvVars,
-			PullRequestNumber:  issueNumber,
-			EventName:          event, //"issue_comment",
-			Namespace:          repoFullName,
-			RequestedBy:        requestedBy,
-			StateEnvProvider:   StateEnvProvider,
-			CommandEnvProvider: CommandEnvProvider,
-			SkipMergeCheck:     skipMerge,
+			ProjectName:          project.Name,
+			ProjectDir:           project.Dir,
+			ProjectLabels:        project.Labels,
+			ProjectEnvironment:   project.Environment,
+			ProjectOwners:        project.Owners,
+			RequireOwnerApproval: project.RequireOwnerApproval,
+			ProjectInputs:        project.Inputs,
+			ProjectWorkspace:     workspace,
+			ProjectWorkflow:      project.Workflow,
+			Terragrunt:           project.Terragrunt,
+			OpenTofu:             project.OpenTofu,
+			Commands:             []string{command},
+			ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+			PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+			RunEnvVars:           runEnvVars,
+			CommandEnvVars:       commandEnvVars,
+			StateEnvVars:         stateEnvVars,
+			PullRequestNumber:    issueNumber,
+			EventName:            event, //"issue_comment",
+			Namespace:            repoFullName,
+			RequestedBy:          requestedBy,
+			StateEnvProvider:     StateEnvProvider,
+			CommandEnvProvider:   CommandEnvProvider,
+			SkipMergeCheck:       skipMerge,
 		})
 	}
 	return jobs, nil
@@ -61,6 +61,26 @@ func (svc GithubService) GetUserTeams(organisation string, user string) ([]strin
 	return teams, nil
 }
 
+// GetUserTeamSlugs returns the slugs of the teams of the user, which is how teams are referred to in owners
+func (svc GithubService) GetUserTeamSlugs(organisation string, user string) ([]string, error) {
+	teamsResponse, _, err := svc.Client.Teams.ListTeams(context.Background(), organisation, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list github teams: %v", err)
+	}
+	var teams []string
+	for _, team := range teamsResponse {
+		teamMembers, _, _ := svc.Client.Teams.ListTeamMembersBySlug(context.Background(), organisation, *team.Slug, nil)
+		for _, member := range teamMembers {
+			if *member.Login == user {
+				teams = append(teams, *team.Slug)
+				break
+			}
+		}
+	}
+
+	return teams, nil
+}
+
 func (svc GithubService) GetChangedFiles(prNumber int) ([]string, error) {
 	var fileNames []string
 	opts := github.ListOptions{PerPage: 100}
@@ -191,6 +211,17 @@ func (svc GithubService) GetApprovals(prNumber int) ([]string, error) {
 	return approvals, err
 }
 
+func (svc GithubService) RequestReviewers(prNumber int, users []string, teams []string) error {
+	_, _, err := svc.Client.PullRequests.RequestReviewers(context.Background(), svc.Owner, svc.RepoName, prNumber, github.ReviewersRequest{
+		Reviewers:     users,
+		TeamReviewers: teams,
+	})
+	if err != nil {
+		return fmt.Errorf("could not request reviewers: %v", err)
+	}
+	return nil
+}
+
 func (svc GithubService) EditComment(prNumber int, id string, comment string) error {
 	commentId, err := strconv.ParseInt(id, 10, 64)
 	if err != nil {
@@ -432,7 +463,7 @@ func (svc GithubService) CheckBranchExists(branchName string) (bool, error) {
 	return true, nil
 }
 
-func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impactedProjects []digger_config.Project, requestedProject *digger_config.Project, config digger_config.DiggerConfig, performEnvVarInterpolation bool) ([]scheduler.Job, bool, error) {
+func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impactedProjects []digger_config.Project, requestedProjects []digger_config.Project, config digger_config.DiggerConfig, performEnvVarInterpolation bool) ([]scheduler.Job, bool, error) {
 	workflows := config.Workflows
 	jobs := make([]scheduler.Job, 0)
 
@@ -454,75 +485,93 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 
 		runEnvVars := generic.GetRunEnvVars(defaultBranch, prBranch, project.Name, project.Dir)
 
-		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, performEnvVarInterpolation)
+		stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, performEnvVarInterpolation)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+		}
 		pullRequestNumber := payload.PullRequest.Number
 
 		StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 		if *payload.Action == "closed" && *payload.PullRequest.Merged && *(payload.PullRequest.Base).Ref == *(payload.Repo).DefaultBranch {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnCommitToDefault,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          *payload.Repo.FullName,
-				RequestedBy:        *payload.Sender.Login,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck: 	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnCommitToDefault,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            *payload.Repo.FullName,
+				RequestedBy:          *payload.Sender.Login,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		} else if *payload.Action == "opened" || *payload.Action == "reopened" || *payload.Action == "synchronize" {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestPushed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          *payload.Repo.FullName,
-				RequestedBy:        *payload.Sender.Login,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck: 	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestPushed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            *payload.Repo.FullName,
+				RequestedBy:          *payload.Sender.Login,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		} else if *payload.Action == "closed" {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestClosed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          *payload.Repo.FullName,
-				RequestedBy:        *payload.Sender.Login,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck: 	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestClosed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            *payload.Repo.FullName,
+				RequestedBy:          *payload.Sender.Login,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		} else if *payload.Action == "converted_to_draft" {
 			var commands []string
@@ -533,25 +582,30 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 			}
 
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           commands,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  pullRequestNumber,
-				EventName:          "pull_request_converted_to_draft",
-				Namespace:          *payload.Repo.FullName,
-				RequestedBy:        *payload.Sender.Login,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck: 	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             commands,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    pullRequestNumber,
+				EventName:            "pull_request_converted_to_draft",
+				Namespace:            *payload.Repo.FullName,
+				RequestedBy:          *payload.Sender.Login,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		}
 
@@ -559,7 +613,9 @@ func ConvertGithubPullRequestEventToJobs(payload *github.PullRequestEvent, impac
 	return jobs, true, nil
 }
 
-func ProcessGitHubEvent(ghEvent interface{}, diggerConfig *digger_config.DiggerConfig, ciService ci.PullRequestService) ([]digger_config.Project, *digger_config.Project, int, error) {
+// ProcessGitHubEvent returns the projects impacted by the PR and, for comments, the projects requested by the
+// selectors of the comment, nil if it has none
+func ProcessGitHubEvent(ghEvent interface{}, diggerConfig *digger_config.DiggerConfig, ciService ci.PullRequestService) ([]digger_config.Project, []digger_config.Project, int, error) {
 	var impactedProjects []digger_config.Project
 	var prNumber int
 
@@ -582,18 +638,11 @@ func ProcessGitHubEvent(ghEvent interface{}, diggerConfig *digger_config.DiggerC
 		}
 
 		impactedProjects, _ = diggerConfig.GetModifiedProjects(changedFiles)
-		requestedProject := scheduler.ParseProjectName(*event.Comment.Body)
-
-		if requestedProject == "" {
-			return impactedProjects, nil, prNumber, nil
-		}
-
-		for _, project := range impactedProjects {
-			if project.Name == requestedProject {
-				return impactedProjects, &project, prNumber, nil
-			}
+		requestedProjects, err := scheduler.SelectRequestedProjects(*event.Comment.Body, impactedProjects, diggerConfig.Projects)
+		if err != nil {
+			return nil, nil, 0, err
 		}
-		return nil, nil, 0, fmt.Errorf("requested project not found in modified projects")
+		return impactedProjects, requestedProjects, prNumber, nil
 	case github.MergeGroupEvent:
 		return nil, nil, 0, UnhandledMergeGroupEventError
 	default:
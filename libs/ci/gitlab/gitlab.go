@@ -93,7 +93,7 @@ func NewGitLabService(token string, gitLabContext *GitLabContext, gitlabBaseUrl
 	}, nil
 }
 
-func ProcessGitLabEvent(gitlabContext *GitLabContext, diggerConfig *digger_config.DiggerConfig, service *GitLabService) ([]digger_config.Project, *digger_config.Project, error) {
+func ProcessGitLabEvent(gitlabContext *GitLabContext, diggerConfig *digger_config.DiggerConfig, service *GitLabService) ([]digger_config.Project, []digger_config.Project, error) {
 	var impactedProjects []digger_config.Project
 
 	if gitlabContext.MergeRequestIId == nil {
@@ -111,20 +111,11 @@ func ProcessGitLabEvent(gitlabContext *GitLabContext, diggerConfig *digger_confi
 
 	switch gitlabContext.EventType {
 	case MergeRequestComment:
-		diggerCommand := strings.ToLower(gitlabContext.DiggerCommand)
-		diggerCommand = strings.TrimSpace(diggerCommand)
-		requestedProject := ci.ParseProjectName(diggerCommand)
-
-		if requestedProject == "" {
-			return impactedProjects, nil, nil
-		}
-
-		for _, project := range impactedProjects {
-			if project.Name == requestedProject {
-				return impactedProjects, &project, nil
-			}
+		requestedProjects, err := scheduler.SelectRequestedProjects(gitlabContext.DiggerCommand, impactedProjects, diggerConfig.Projects)
+		if err != nil {
+			return nil, nil, err
 		}
-		return nil, nil, fmt.Errorf("requested project not found in modified projects")
+		return impactedProjects, requestedProjects, nil
 	default:
 		return impactedProjects, nil, nil
 
@@ -345,7 +336,7 @@ const (
 	MergeRequestComment = GitLabEventType("merge_request_commented")
 )
 
-func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContext, impactedProjects []digger_config.Project, requestedProject *digger_config.Project, workflows map[string]digger_config.Workflow) ([]scheduler.Job, bool, error) {
+func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContext, impactedProjects []digger_config.Project, requestedProjects []digger_config.Project, workflows map[string]digger_config.Workflow) ([]scheduler.Job, bool, error) {
 	jobs := make([]scheduler.Job, 0)
 
 	log.Printf("ConvertGitLabEventToCommands, event.EventType: %s\n", event.EventType)
@@ -364,26 +355,34 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				skipMerge = false
 			}
 
-			stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+			}
 			StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestPushed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				PullRequestNumber:  gitLabContext.MergeRequestIId,
-				EventName:          gitLabContext.EventType.String(),
-				RequestedBy:        gitLabContext.GitlabUserName,
-				Namespace:          gitLabContext.ProjectNamespace,
-				StateEnvVars:       stateEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvProvider:   StateEnvProvider,
-				CommandEnvProvider: CommandEnvProvider,
-				SkipMergeCheck: 	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestPushed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				PullRequestNumber:    gitLabContext.MergeRequestIId,
+				EventName:            gitLabContext.EventType.String(),
+				RequestedBy:          gitLabContext.GitlabUserName,
+				Namespace:            gitLabContext.ProjectNamespace,
+				StateEnvVars:         stateEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvProvider:     StateEnvProvider,
+				CommandEnvProvider:   CommandEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		}
 		return jobs, true, nil
@@ -393,7 +392,10 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 			if !ok {
 				return nil, true, fmt.Errorf("failed to find workflow digger_config '%s' for project '%s'", project.Workflow, project.Name)
 			}
-			stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+			if err != nil {
+				return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+			}
 			var StateEnvProvider *stscreds.WebIdentityRoleProvider
 			var CommandEnvProvider *stscreds.WebIdentityRoleProvider
 			if project.AwsRoleToAssume != nil {
@@ -411,40 +413,34 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 				}
 			}
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestClosed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				PullRequestNumber:  gitLabContext.MergeRequestIId,
-				EventName:          gitLabContext.EventType.String(),
-				RequestedBy:        gitLabContext.GitlabUserName,
-				Namespace:          gitLabContext.ProjectNamespace,
-				StateEnvVars:       stateEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvProvider:   StateEnvProvider,
-				CommandEnvProvider: CommandEnvProvider,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestClosed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				PullRequestNumber:    gitLabContext.MergeRequestIId,
+				EventName:            gitLabContext.EventType.String(),
+				RequestedBy:          gitLabContext.GitlabUserName,
+				Namespace:            gitLabContext.ProjectNamespace,
+				StateEnvVars:         stateEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvProvider:     StateEnvProvider,
+				CommandEnvProvider:   CommandEnvProvider,
 			})
 		}
 		return jobs, true, nil
 	case MergeRequestComment:
 		supportedCommands := []string{"digger plan", "digger apply", "digger unlock", "digger lock"}
 
-		coversAllImpactedProjects := true
-
-		runForProjects := impactedProjects
-
-		if requestedProject != nil {
-			if len(impactedProjects) > 1 {
-				coversAllImpactedProjects = false
-				runForProjects = []digger_config.Project{*requestedProject}
-			} else if len(impactedProjects) == 1 && impactedProjects[0].Name != requestedProject.Name {
-				return jobs, false, fmt.Errorf("requested project %v is not impacted by this PR", requestedProject.Name)
-			}
-		}
+		runForProjects, coversAllImpactedProjects := scheduler.ProjectsToRun(impactedProjects, requestedProjects)
 
 		diggerCommand := strings.ToLower(gitLabContext.DiggerCommand)
 		diggerCommand = strings.TrimSpace(diggerCommand)
@@ -463,25 +459,33 @@ func ConvertGitLabEventToCommands(event GitLabEvent, gitLabContext *GitLabContex
 					if workspaceOverride != "" {
 						workspace = workspaceOverride
 					}
-					stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+					stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, true)
+					if err != nil {
+						return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+					}
 					StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 					jobs = append(jobs, scheduler.Job{
-						ProjectName:        project.Name,
-						ProjectDir:         project.Dir,
-						ProjectWorkspace:   workspace,
-						Terragrunt:         project.Terragrunt,
-						OpenTofu:           project.OpenTofu,
-						Commands:           []string{command},
-						ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-						PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-						PullRequestNumber:  gitLabContext.MergeRequestIId,
-						EventName:          gitLabContext.EventType.String(),
-						RequestedBy:        gitLabContext.GitlabUserName,
-						Namespace:          gitLabContext.ProjectNamespace,
-						StateEnvVars:       stateEnvVars,
-						CommandEnvVars:     commandEnvVars,
-						StateEnvProvider:   StateEnvProvider,
-						CommandEnvProvider: CommandEnvProvider,
+						ProjectName:          project.Name,
+						ProjectDir:           project.Dir,
+						ProjectLabels:        project.Labels,
+						ProjectEnvironment:   project.Environment,
+						ProjectOwners:        project.Owners,
+						RequireOwnerApproval: project.RequireOwnerApproval,
+						ProjectInputs:        project.Inputs,
+						ProjectWorkspace:     workspace,
+						Terragrunt:           project.Terragrunt,
+						OpenTofu:             project.OpenTofu,
+						Commands:             []string{command},
+						ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+						PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+						PullRequestNumber:    gitLabContext.MergeRequestIId,
+						EventName:            gitLabContext.EventType.String(),
+						RequestedBy:          gitLabContext.GitlabUserName,
+						Namespace:            gitLabContext.ProjectNamespace,
+						StateEnvVars:         stateEnvVars,
+						CommandEnvVars:       commandEnvVars,
+						StateEnvProvider:     StateEnvProvider,
+						CommandEnvProvider:   CommandEnvProvider,
 					})
 				}
 			}
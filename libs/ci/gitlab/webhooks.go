@@ -46,7 +46,10 @@ func ConvertGithubPullRequestEventToJobs(payload *gitlab.MergeEvent, impactedPro
 
 		runEnvVars := generic.GetRunEnvVars(defaultBranch, prBranch, project.Name, project.Dir)
 
-		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+		}
 		pullRequestNumber := payload.ObjectAttributes.IID
 		namespace := payload.Project.PathWithNamespace
 		sender := payload.User.Username
@@ -62,68 +65,83 @@ func ConvertGithubPullRequestEventToJobs(payload *gitlab.MergeEvent, impactedPro
 		StateEnvProvider, CommandEnvProvider := scheduler.GetStateAndCommandProviders(project)
 		if payload.ObjectAttributes.Action == "merge" && payload.ObjectAttributes.TargetBranch == defaultBranch {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				Commands:           workflow.Configuration.OnCommitToDefault,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  &pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          namespace,
-				RequestedBy:        sender,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck:     skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				Commands:             workflow.Configuration.OnCommitToDefault,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    &pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            namespace,
+				RequestedBy:          sender,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		} else if payload.ObjectAttributes.Action == "open" || payload.ObjectAttributes.Action == "reopen" || payload.ObjectAttributes.Action == "synchronize" {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestPushed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  &pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          namespace,
-				RequestedBy:        sender,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck:    	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestPushed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    &pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            namespace,
+				RequestedBy:          sender,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		} else if payload.ObjectAttributes.Action == "close" {
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           workflow.Configuration.OnPullRequestClosed,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  &pullRequestNumber,
-				EventName:          "pull_request",
-				Namespace:          namespace,
-				RequestedBy:        sender,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck:    	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             workflow.Configuration.OnPullRequestClosed,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    &pullRequestNumber,
+				EventName:            "pull_request",
+				Namespace:            namespace,
+				RequestedBy:          sender,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 			//	TODO: Figure how to detect gitlab's "PR converted to draft" event
 		} else if payload.ObjectAttributes.Action == "converted_to_draft" {
@@ -135,25 +153,30 @@ func ConvertGithubPullRequestEventToJobs(payload *gitlab.MergeEvent, impactedPro
 			}
 
 			jobs = append(jobs, scheduler.Job{
-				ProjectName:        project.Name,
-				ProjectDir:         project.Dir,
-				ProjectWorkspace:   project.Workspace,
-				ProjectWorkflow:    project.Workflow,
-				Terragrunt:         project.Terragrunt,
-				OpenTofu:           project.OpenTofu,
-				Commands:           commands,
-				ApplyStage:         scheduler.ToConfigStage(workflow.Apply),
-				PlanStage:          scheduler.ToConfigStage(workflow.Plan),
-				RunEnvVars:         runEnvVars,
-				CommandEnvVars:     commandEnvVars,
-				StateEnvVars:       stateEnvVars,
-				PullRequestNumber:  &pullRequestNumber,
-				EventName:          "pull_request_converted_to_draft",
-				Namespace:          namespace,
-				RequestedBy:        sender,
-				CommandEnvProvider: CommandEnvProvider,
-				StateEnvProvider:   StateEnvProvider,
-				SkipMergeCheck:   	skipMerge,
+				ProjectName:          project.Name,
+				ProjectDir:           project.Dir,
+				ProjectLabels:        project.Labels,
+				ProjectEnvironment:   project.Environment,
+				ProjectOwners:        project.Owners,
+				RequireOwnerApproval: project.RequireOwnerApproval,
+				ProjectInputs:        project.Inputs,
+				ProjectWorkspace:     project.Workspace,
+				ProjectWorkflow:      project.Workflow,
+				Terragrunt:           project.Terragrunt,
+				OpenTofu:             project.OpenTofu,
+				Commands:             commands,
+				ApplyStage:           scheduler.ToConfigStage(workflow.Apply),
+				PlanStage:            scheduler.ToConfigStage(workflow.Plan),
+				RunEnvVars:           runEnvVars,
+				CommandEnvVars:       commandEnvVars,
+				StateEnvVars:         stateEnvVars,
+				PullRequestNumber:    &pullRequestNumber,
+				EventName:            "pull_request_converted_to_draft",
+				Namespace:            namespace,
+				RequestedBy:          sender,
+				CommandEnvProvider:   CommandEnvProvider,
+				StateEnvProvider:     StateEnvProvider,
+				SkipMergeCheck:       skipMerge,
 			})
 		}
 
@@ -0,0 +1,141 @@
+package ci
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// Owner is a CODEOWNERS style project owner, either a user (@user) or a team (@org/team)
+type Owner struct {
+	User         string
+	Organisation string
+	Team         string
+}
+
+func ParseOwner(owner string) Owner {
+	owner = strings.TrimPrefix(strings.TrimSpace(owner), "@")
+	if org, team, isTeam := strings.Cut(owner, "/"); isTeam {
+		return Owner{Organisation: org, Team: team}
+	}
+	return Owner{User: owner}
+}
+
+func (o Owner) IsTeam() bool {
+	return o.Team != ""
+}
+
+func (o Owner) String() string {
+	if o.IsTeam() {
+		return fmt.Sprintf("@%v/%v", o.Organisation, o.Team)
+	}
+	return "@" + o.User
+}
+
+// ReviewRequester is implemented by services that can request reviews on a pull request
+type ReviewRequester interface {
+	RequestReviewers(prNumber int, users []string, teams []string) error
+}
+
+// TeamSlugService is implemented by services that can list the teams of a user by slug
+type TeamSlugService interface {
+	GetUserTeamSlugs(organisation string, user string) ([]string, error)
+}
+
+// OwnerApprovals returns the approvers of the pull request who are owners, either directly
+// or through membership of an owning team. Teams are matched by organisation and slug
+func OwnerApprovals(orgService OrgService, prService PullRequestService, prNumber int, owners []string) ([]string, error) {
+	approvals, err := prService.GetApprovals(prNumber)
+	if err != nil {
+		return nil, fmt.Errorf("could not get approvals: %v", err)
+	}
+
+	ownerUsers := make([]string, 0)
+	ownerTeams := make([]Owner, 0)
+	for _, o := range owners {
+		owner := ParseOwner(o)
+		if owner.IsTeam() {
+			ownerTeams = append(ownerTeams, owner)
+		} else {
+			ownerUsers = append(ownerUsers, strings.ToLower(owner.User))
+		}
+	}
+
+	ownerApprovals := make([]string, 0)
+	for _, approver := range approvals {
+		if slices.Contains(ownerUsers, strings.ToLower(approver)) {
+			ownerApprovals = append(ownerApprovals, approver)
+			continue
+		}
+		if orgService != nil && isTeamOwner(orgService, ownerTeams, approver) {
+			ownerApprovals = append(ownerApprovals, approver)
+		}
+	}
+	return ownerApprovals, nil
+}
+
+// isTeamOwner returns whether the user is a member of one of the owning teams
+func isTeamOwner(orgService OrgService, ownerTeams []Owner, user string) bool {
+	if len(ownerTeams) == 0 {
+		return false
+	}
+	slugService, ok := orgService.(TeamSlugService)
+	if !ok {
+		log.Printf("listing teams by slug is not supported by this CI service, skipping owner teams")
+		return false
+	}
+	userTeams := make(map[string][]string)
+	for _, owner := range ownerTeams {
+		organisation := strings.ToLower(owner.Organisation)
+		teams, fetched := userTeams[organisation]
+		if !fetched {
+			var err error
+			teams, err = slugService.GetUserTeamSlugs(owner.Organisation, user)
+			if err != nil {
+				log.Printf("could not get teams of %v in %v: %v", user, owner.Organisation, err)
+			}
+			userTeams[organisation] = teams
+		}
+		if slices.ContainsFunc(teams, func(team string) bool { return strings.EqualFold(team, owner.Team) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestOwnerReviews requests a review from the owners of impacted projects, the author of the
+// pull request is skipped since they can't review their own changes
+func RequestOwnerReviews(prService PullRequestService, prNumber int, owners []string, author string) error {
+	requester, ok := prService.(ReviewRequester)
+	if !ok {
+		log.Printf("requesting reviews is not supported by this CI service, skipping")
+		return nil
+	}
+
+	users := make([]string, 0)
+	teams := make([]string, 0)
+	for _, o := range owners {
+		owner := ParseOwner(o)
+		if owner.IsTeam() {
+			if !slices.Contains(teams, owner.Team) {
+				teams = append(teams, owner.Team)
+			}
+		} else if !strings.EqualFold(owner.User, author) && !slices.Contains(users, owner.User) {
+			users = append(users, owner.User)
+		}
+	}
+	if len(users) == 0 && len(teams) == 0 {
+		return nil
+	}
+	return requester.RequestReviewers(prNumber, users, teams)
+}
+
+// MentionOwners formats owners so that they are notified when included in a comment
+func MentionOwners(owners []string) string {
+	mentions := make([]string, 0, len(owners))
+	for _, o := range owners {
+		mentions = append(mentions, ParseOwner(o).String())
+	}
+	return strings.Join(mentions, ", ")
+}
@@ -0,0 +1,70 @@
+package ci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reviewRequestingPullRequestManager struct {
+	MockPullRequestManager
+	Users []string
+	Teams []string
+}
+
+func (m *reviewRequestingPullRequestManager) RequestReviewers(prNumber int, users []string, teams []string) error {
+	m.Users = users
+	m.Teams = teams
+	return nil
+}
+
+// organisationTeams returns the team slugs of users by organisation
+type organisationTeams map[string][]string
+
+func (o organisationTeams) GetUserTeams(organisation string, user string) ([]string, error) {
+	return nil, nil
+}
+
+func (o organisationTeams) GetUserTeamSlugs(organisation string, user string) ([]string, error) {
+	return o[organisation], nil
+}
+
+func TestParseOwner(t *testing.T) {
+	assert.Equal(t, Owner{User: "motatoes"}, ParseOwner("@motatoes"))
+	assert.Equal(t, Owner{Organisation: "diggerhq", Team: "network"}, ParseOwner("@diggerhq/network"))
+	assert.Equal(t, "@motatoes, @diggerhq/network", MentionOwners([]string{"motatoes", "@diggerhq/network"}))
+}
+
+func TestOwnerApprovals(t *testing.T) {
+	prService := MockPullRequestManager{Approvals: []string{"rando", "Motatoes"}}
+	approvals, err := OwnerApprovals(prService, prService, 1, []string{"@motatoes"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Motatoes"}, approvals)
+
+	approvals, err = OwnerApprovals(prService, prService, 1, []string{"@someone-else"})
+	assert.NoError(t, err)
+	assert.Empty(t, approvals)
+
+	prService = MockPullRequestManager{Approvals: []string{"rando"}}
+	orgService := organisationTeams{"diggerhq": {"network-team"}}
+	approvals, err = OwnerApprovals(orgService, prService, 1, []string{"@diggerhq/Network-Team"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rando"}, approvals)
+
+	// a team with the same slug in another organisation is not an owner
+	approvals, err = OwnerApprovals(orgService, prService, 1, []string{"@otherorg/network-team"})
+	assert.NoError(t, err)
+	assert.Empty(t, approvals)
+}
+
+func TestRequestOwnerReviews(t *testing.T) {
+	prService := &reviewRequestingPullRequestManager{}
+	err := RequestOwnerReviews(prService, 1, []string{"@author", "@motatoes", "@motatoes", "@diggerhq/network"}, "author")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"motatoes"}, prService.Users)
+	assert.Equal(t, []string{"network"}, prService.Teams)
+
+	// services that can't request reviews are skipped
+	err = RequestOwnerReviews(MockPullRequestManager{}, 1, []string{"@motatoes"}, "author")
+	assert.NoError(t, err)
+}
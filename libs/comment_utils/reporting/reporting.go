@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/diggerhq/digger/libs/ci"
 	"github.com/diggerhq/digger/libs/comment_utils/utils"
+	"github.com/diggerhq/digger/libs/redact"
 	"log"
 	"strings"
 	"time"
@@ -17,6 +18,7 @@ type CiReporter struct {
 }
 
 func (ciReporter CiReporter) Report(report string, reportFormatting func(report string) string) (string, string, error) {
+	report = redact.String(report)
 	commentId, commentUrl, err := ciReporter.ReportStrategy.Report(ciReporter.CiService, ciReporter.PrNumber, report, reportFormatting, ciReporter.SupportsMarkdown())
 	return commentId, commentUrl, err
 }
@@ -50,7 +52,7 @@ func NewCiReporterLazy(ciReporter CiReporter) *CiReporterLazy {
 }
 
 func (lazyReporter *CiReporterLazy) Report(report string, reportFormatting func(report string) string) (string, string, error) {
-	lazyReporter.reports = append(lazyReporter.reports, report)
+	lazyReporter.reports = append(lazyReporter.reports, redact.String(report))
 	lazyReporter.formatters = append(lazyReporter.formatters, reportFormatting)
 	return "", "", nil
 }
@@ -87,7 +89,7 @@ func (lazyReporter *CiReporterLazy) SupportsMarkdown() bool {
 type StdOutReporter struct{}
 
 func (reporter StdOutReporter) Report(report string, reportFormatting func(report string) string) (string, string, error) {
-	log.Printf("Info: %v", report)
+	log.Printf("Info: %v", redact.String(report))
 	return "", "", nil
 }
 
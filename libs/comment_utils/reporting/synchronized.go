@@ -0,0 +1,36 @@
+package reporting
+
+import "sync"
+
+// SynchronizedReporter serialises calls to a reporter shared by projects running in parallel, so that
+// their reports are consolidated the same way as when they run one after another
+type SynchronizedReporter struct {
+	mu       sync.Mutex
+	Reporter Reporter
+}
+
+func NewSynchronizedReporter(reporter Reporter) *SynchronizedReporter {
+	return &SynchronizedReporter{Reporter: reporter}
+}
+
+func (s *SynchronizedReporter) Report(report string, reportFormatting func(report string) string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Reporter.Report(report, reportFormatting)
+}
+
+func (s *SynchronizedReporter) Flush() (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Reporter.Flush()
+}
+
+func (s *SynchronizedReporter) Suppress() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Reporter.Suppress()
+}
+
+func (s *SynchronizedReporter) SupportsMarkdown() bool {
+	return s.Reporter.SupportsMarkdown()
+}
@@ -25,14 +25,33 @@ func (b BasicCommentUpdater) UpdateComment(jobs []scheduler.SerializedJob, prNum
 	firstJobSpec := jobSpecs[0]
 	jobType := firstJobSpec.JobType
 	jobTypeTitle := cases.Title(language.AmericanEnglish).String(string(jobType))
+
+	environments := make([]string, len(jobSpecs))
+	for i, jobSpec := range jobSpecs {
+		environments[i] = jobSpec.ProjectEnvironment
+	}
+	order, groups := scheduler.GroupByEnvironment(environments)
+
 	message := ""
-	message = message + fmt.Sprintf("| Project | Status | %v | + | ~ | - |\n", jobTypeTitle)
-	message = message + fmt.Sprintf("|---------|--------|------|---|---|---|\n")
+	for _, environment := range order {
+		// only split the table up when projects declare an environment
+		if len(order) > 1 || environment != "" {
+			message = message + scheduler.EnvironmentHeading(environment)
+		}
+		message = message + fmt.Sprintf("| Project | Status | %v | + | ~ | - |\n", jobTypeTitle)
+		message = message + fmt.Sprintf("|---------|--------|------|---|---|---|\n")
 
-	for i, job := range jobs {
-		jobSpec := jobSpecs[i]
-		prCommentUrl := job.PRCommentUrl
-		message = message + fmt.Sprintf("|%v **%v** |<a href='%v'>%v</a> | <a href='%v'>%v</a> | %v | %v | %v|\n", job.Status.ToEmoji(), jobSpec.ProjectName, *job.WorkflowRunUrl, job.Status.ToString(), prCommentUrl, jobTypeTitle, job.ResourcesCreated, job.ResourcesUpdated, job.ResourcesDeleted)
+		for _, i := range groups[environment] {
+			job := jobs[i]
+			jobSpec := jobSpecs[i]
+			prCommentUrl := job.PRCommentUrl
+			status := fmt.Sprintf("<a href='%v'>%v</a>", *job.WorkflowRunUrl, job.StatusString())
+			if job.LogsUrl != "" {
+				status = status + fmt.Sprintf(" (<a href='%v'>logs</a>)", job.LogsUrl)
+			}
+			message = message + fmt.Sprintf("|%v **%v** |%v | <a href='%v'>%v</a> | %v | %v | %v|\n", job.Status.ToEmoji(), jobSpec.ProjectName, status, prCommentUrl, jobTypeTitle, job.ResourcesCreated, job.ResourcesUpdated, job.ResourcesDeleted)
+		}
+		message = message + "\n"
 	}
 
 	prService.EditComment(prNumber, prCommentId, message)
@@ -15,6 +15,9 @@ type DiggerConfig struct {
 	Workflows                  map[string]Workflow
 	MentionDriftedProjectsInPR bool
 	TraverseToNestedProjects   bool
+	// MaxParallelism is the number of projects without dependencies between them that the cli runs at the same time
+	MaxParallelism int
+	Retries        RetryPolicy
 }
 
 type DependencyConfiguration struct {
@@ -41,6 +44,13 @@ type Project struct {
 	DriftDetection     bool
 	AwsRoleToAssume    *AssumeRoleForProject
 	Generated          bool
+	Labels             map[string]string
+	Environment        string
+	Owners             []string
+	// RequireOwnerApproval blocks apply until one of the owners approved the pull request
+	RequireOwnerApproval bool
+	// Inputs maps terraform variable names to outputs of upstream projects, as "project.output"
+	Inputs map[string]string
 }
 
 type Workflow struct {
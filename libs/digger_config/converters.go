@@ -65,6 +65,11 @@ func copyProjects(projects []*ProjectYaml) []Project {
 			driftDetection,
 			roleToAssume,
 			p.Generated,
+			p.Labels,
+			p.Environment,
+			p.Owners,
+			p.OwnerApprovalRequired(),
+			p.Inputs,
 		}
 		result[i] = item
 	}
@@ -204,6 +209,18 @@ func ConvertDiggerYamlToConfig(diggerYaml *DiggerConfigYaml) (*DiggerConfig, gra
 		diggerConfig.TraverseToNestedProjects = false
 	}
 
+	if diggerYaml.MaxParallelism != nil {
+		diggerConfig.MaxParallelism = *diggerYaml.MaxParallelism
+	} else {
+		diggerConfig.MaxParallelism = 1
+	}
+
+	retries, err := RetryPolicyFromYaml(diggerYaml.Retries)
+	if err != nil {
+		return nil, nil, err
+	}
+	diggerConfig.Retries = retries
+
 	if diggerYaml.AllowDraftPRs != nil {
 		diggerConfig.AllowDraftPRs = *diggerYaml.AllowDraftPRs
 	} else {
@@ -8,9 +8,12 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/diggerhq/digger/libs/digger_config/terragrunt/atlantis"
+	"github.com/diggerhq/digger/libs/redact"
+	"github.com/diggerhq/digger/libs/secrets"
 
 	"github.com/dominikbraun/graph"
 	"gopkg.in/yaml.v3"
@@ -158,12 +161,7 @@ func LoadDiggerConfigFromString(yamlString string, terraformDir string) (*Digger
 		return nil, nil, nil, err
 	}
 
-	err = ValidateDiggerConfigYaml(configYaml, "loaded_yaml_string")
-	if err != nil {
-		return nil, nil, nil, err
-	}
-
-	err = HandleYamlProjectGeneration(configYaml, terraformDir, nil)
+	err = prepareDiggerConfigYaml(configYaml, "loaded_yaml_string", terraformDir, true, nil)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -182,7 +180,16 @@ func LoadDiggerConfigFromString(yamlString string, terraformDir string) (*Digger
 
 func LoadDiggerConfigYamlFromString(yamlString string) (*DiggerConfigYaml, error) {
 	configYaml := &DiggerConfigYaml{}
-	if err := yaml.Unmarshal([]byte(yamlString), configYaml); err != nil {
+	var configNode yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlString), &configNode); err != nil {
+		return nil, fmt.Errorf("error parsing yaml: %v", err)
+	}
+	// configs loaded from a string were stored by the backend, possibly by an older version, so unknown keys are
+	// only reported. Configs in the repository are checked when they are read.
+	for _, unknownKey := range checkYamlKeys(&configNode, reflect.TypeOf(configYaml), "") {
+		log.Printf("warning: ignoring digger config key: %v", unknownKey)
+	}
+	if err := configNode.Decode(configYaml); err != nil {
 		return nil, fmt.Errorf("error parsing yaml: %v", err)
 	}
 
@@ -278,6 +285,9 @@ func HandleYamlProjectGeneration(config *DiggerConfigYaml, terraformDir string,
 
 						// allow blocks to pass in roles that can be assummed by aws 					
 						tgParsingConfig.AwsRoleToAssume = b.AwsRoleToAssume
+						tgParsingConfig.Labels = b.Labels
+						tgParsingConfig.Environment = b.Environment
+						tgParsingConfig.Owners = b.Owners
 						
 
 						err := hydrateDiggerConfigYamlWithTerragrunt(config, tgParsingConfig, terraformDir)
@@ -304,6 +314,9 @@ func HandleYamlProjectGeneration(config *DiggerConfigYaml, terraformDir string,
 								Workspace:       "default",
 								AwsRoleToAssume: b.AwsRoleToAssume,
 								Generated:       true,
+								Labels:          b.Labels,
+								Environment:     b.Environment,
+								Owners:          b.Owners,
 							}
 							config.Projects = append(config.Projects, &project)
 						}
@@ -315,6 +328,25 @@ func HandleYamlProjectGeneration(config *DiggerConfigYaml, terraformDir string,
 	return nil
 }
 
+// prepareDiggerConfigYaml validates a decoded config, generates its projects and resolves inheritance. This is
+// the only place inheritance is resolved, so that module dependencies added afterwards are not replaced by
+// include patterns from defaults
+func prepareDiggerConfigYaml(configYaml *DiggerConfigYaml, fileName string, workingDir string, generateProjects bool, changedFiles []string) error {
+	err := ValidateDiggerConfigYaml(configYaml, fileName)
+	if err != nil {
+		return err
+	}
+
+	if generateProjects == true {
+		err = HandleYamlProjectGeneration(configYaml, workingDir, changedFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	return ResolveInheritance(configYaml)
+}
+
 func LoadDiggerConfigYaml(workingDir string, generateProjects bool, changedFiles []string) (*DiggerConfigYaml, error) {
 	configYaml := &DiggerConfigYaml{}
 	fileName, err := retrieveConfigFile(workingDir)
@@ -324,7 +356,12 @@ func LoadDiggerConfigYaml(workingDir string, generateProjects bool, changedFiles
 		}
 	}
 
-	if fileName == "" {
+	configNode, err := loadDiggerConfigNode(workingDir, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if configNode == nil {
 		configYaml, err = AutoDetectDiggerConfig(workingDir)
 		if err != nil {
 			return nil, fmt.Errorf("failed to auto detect digger digger_config: %v", err)
@@ -336,28 +373,16 @@ func LoadDiggerConfigYaml(workingDir string, generateProjects bool, changedFiles
 			log.Printf("Auto detected digger digger_config: \n%v", string(marshalledConfig))
 		}
 	} else {
-		data, err := os.ReadFile(fileName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read digger_config file %s: %v", fileName, err)
-		}
-
-		if err := yaml.Unmarshal(data, configYaml); err != nil {
+		if err := configNode.Decode(configYaml); err != nil {
 			return nil, fmt.Errorf("error parsing '%s': %v", fileName, err)
 		}
 	}
 
-	err = ValidateDiggerConfigYaml(configYaml, fileName)
+	err = prepareDiggerConfigYaml(configYaml, fileName, workingDir, generateProjects, changedFiles)
 	if err != nil {
 		return configYaml, err
 	}
-
-	if generateProjects == true {
-		err = HandleYamlProjectGeneration(configYaml, workingDir, changedFiles)
-		if err != nil {
-			return configYaml, err
-		}
-	}
-
+	addModuleDependencies(workingDir, configYaml)
 
 	return configYaml, nil
 }
@@ -389,6 +414,14 @@ func ValidateDiggerConfig(config *DiggerConfig) error {
 		return fmt.Errorf("invalid value for comment_render_mode, %v expecting %v, %v", config.CommentRenderMode, CommentRenderModeBasic, CommentRenderModeGroupByModule)
 	}
 
+	if config.MaxParallelism < 1 {
+		return fmt.Errorf("max_parallelism must be at least 1, got %v", config.MaxParallelism)
+	}
+
+	if err := validateRetryPolicy(config.Retries); err != nil {
+		return err
+	}
+
 	for _, p := range config.Projects {
 		_, ok := config.Workflows[p.Workflow]
 		if !ok {
@@ -396,6 +429,18 @@ func ValidateDiggerConfig(config *DiggerConfig) error {
 		}
 	}
 
+	for _, p := range config.Projects {
+		for variable, input := range p.Inputs {
+			project, _, err := ParseProjectInput(input)
+			if err != nil {
+				return fmt.Errorf("invalid input %v for project '%s': %v", variable, p.Name, err)
+			}
+			if !lo.Contains(p.DependencyProjects, project) {
+				return fmt.Errorf("input %v of project '%s' uses outputs of project '%s' which is not in its depends_on", variable, p.Name, project)
+			}
+		}
+	}
+
 	for _, w := range config.Workflows {
 		for _, s := range w.Plan.Steps {
 			if s.Action == "" {
@@ -509,6 +554,9 @@ func hydrateDiggerConfigYamlWithTerragrunt(configYaml *DiggerConfigYaml, parsing
 			IncludePatterns: atlantisProject.Autoplan.WhenModified,
 			Generated:       true,
 			AwsRoleToAssume: parsingConfig.AwsRoleToAssume,
+			Labels:          parsingConfig.Labels,
+			Environment:     parsingConfig.Environment,
+			Owners:          parsingConfig.Owners,
 		})
 	}
 	return nil
@@ -527,8 +575,6 @@ func AutoDetectDiggerConfig(workingDir string) (*DiggerConfigYaml, error) {
 
 	terragruntDirWalker := &FileSystemTerragruntDirWalker{}
 	terraformDirWalker := &FileSystemTopLevelTerraformDirWalker{}
-	moduleDirWalker := &FileSystemModuleDirWalker{}
-
 	terragruntDirs, err := terragruntDirWalker.GetDirs(workingDir, configYaml)
 
 	if err != nil {
@@ -540,16 +586,7 @@ func AutoDetectDiggerConfig(workingDir string) (*DiggerConfigYaml, error) {
 		return nil, err
 	}
 
-	moduleDirs, err := moduleDirWalker.GetDirs(workingDir, configYaml)
-
-	var modulePatterns []string
-	for _, dir := range moduleDirs {
-		modulePatterns = append(modulePatterns, dir+"/**")
-	}
-
-	if err != nil {
-		return nil, err
-	}
+	// modules sourced by each project are added to its include patterns when the config is loaded
 	if len(terragruntDirs) > 0 {
 		configYaml.GenerateProjectsConfig = &GenerateProjectsConfigYaml{
 			Terragrunt: true,
@@ -563,7 +600,7 @@ func AutoDetectDiggerConfig(workingDir string) (*DiggerConfigYaml, error) {
 			} else {
 				projectName = strings.ReplaceAll(dir, "/", "_")
 			}
-			project := ProjectYaml{Name: projectName, Dir: dir, Workflow: defaultWorkflowName, Workspace: "default", Terragrunt: false, IncludePatterns: modulePatterns}
+			project := ProjectYaml{Name: projectName, Dir: dir, Workflow: defaultWorkflowName, Workspace: "default", Terragrunt: false}
 			configYaml.Projects = append(configYaml.Projects, &project)
 		}
 		return configYaml, nil
@@ -697,7 +734,26 @@ func retrieveConfigFile(workingDir string) (string, error) {
 	return "", nil
 }
 
-func CollectTerraformEnvConfig(envs *TerraformEnvConfig, performInterpolation bool) (map[string]string, map[string]string) {
+// resolveEnvVarValueFrom reads the value of an env var from another env var or, for secret references
+// such as vault://path#key, from the secret store. The values are registered for redaction
+func resolveEnvVarValueFrom(envvar EnvVar) (string, error) {
+	if secrets.IsReference(envvar.ValueFrom) {
+		value, err := secrets.Resolve(envvar.ValueFrom)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %v for env var %v: %v", envvar.ValueFrom, envvar.Name, err)
+		}
+		return value, nil
+	}
+	// values read from other env vars are usually credentials as well
+	value := os.Getenv(envvar.ValueFrom)
+	redact.Register(value)
+	return value, nil
+}
+
+// CollectTerraformEnvConfig returns the state and command env vars of a workflow. With performInterpolation the
+// value_from references are resolved, an error is returned if one of them can't be, so that terraform never runs
+// with missing credentials
+func CollectTerraformEnvConfig(envs *TerraformEnvConfig, performInterpolation bool) (map[string]string, map[string]string, error) {
 	stateEnvVars := map[string]string{}
 	commandEnvVars := map[string]string{}
 
@@ -707,7 +763,11 @@ func CollectTerraformEnvConfig(envs *TerraformEnvConfig, performInterpolation bo
 				stateEnvVars[envvar.Name] = envvar.Value
 			} else if envvar.ValueFrom != "" {
 				if performInterpolation {
-					stateEnvVars[envvar.Name] = os.Getenv(envvar.ValueFrom)
+					value, err := resolveEnvVarValueFrom(envvar)
+					if err != nil {
+						return nil, nil, err
+					}
+					stateEnvVars[envvar.Name] = value
 				} else {
 					stateEnvVars[envvar.Name] = fmt.Sprintf("$DIGGER_%v", envvar.ValueFrom)
 				}
@@ -719,7 +779,11 @@ func CollectTerraformEnvConfig(envs *TerraformEnvConfig, performInterpolation bo
 				commandEnvVars[envvar.Name] = envvar.Value
 			} else if envvar.ValueFrom != "" {
 				if performInterpolation {
-					commandEnvVars[envvar.Name] = os.Getenv(envvar.ValueFrom)
+					value, err := resolveEnvVarValueFrom(envvar)
+					if err != nil {
+						return nil, nil, err
+					}
+					commandEnvVars[envvar.Name] = value
 				} else {
 					commandEnvVars[envvar.Name] = fmt.Sprintf("$DIGGER_%v", envvar.ValueFrom)
 				}
@@ -727,5 +791,5 @@ func CollectTerraformEnvConfig(envs *TerraformEnvConfig, performInterpolation bo
 		}
 	}
 
-	return stateEnvVars, commandEnvVars
+	return stateEnvVars, commandEnvVars, nil
 }
@@ -5,8 +5,13 @@ import (
 	"log"
 	"os"
 	"path"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/diggerhq/digger/libs/redact"
+	"github.com/diggerhq/digger/libs/secrets"
 	"github.com/dominikbraun/graph"
 	"github.com/go-git/go-git/v5"
 
@@ -506,10 +511,11 @@ projects:
   workflow: my_custom_workflow
 workflows:
   my_custom_workflow:
-    steps:
-      - run: echo "run"
-      - init: terraform init
-      - plan: terraform plan
+    plan:
+      steps:
+        - run: echo "run"
+        - init
+        - plan
 `
 	deleteFile := createFile(path.Join(tempDir, "digger.yaml"), diggerCfg)
 	defer deleteFile()
@@ -572,10 +578,11 @@ projects:
   workflow: my_custom_workflow
 workflows:
   my_custom_workflow_no_one_use:
-    steps:
-      - run: echo "run"
-      - init: terraform init
-      - plan: terraform plan
+    plan:
+      steps:
+        - run: echo "run"
+        - init
+        - plan
 `
 	deleteFile := createFile(path.Join(tempDir, "digger.yaml"), diggerCfg)
 	defer deleteFile()
@@ -921,15 +928,17 @@ generate_projects:
       workflow: prod_workflow
 workflows:
   dev_workflow:
-    steps:
-      - run: echo "run"
-      - init: terraform init
-      - plan: terraform plan
+    plan:
+      steps:
+        - run: echo "run"
+        - init
+        - plan
   prod_workflow:
-    steps:
-      - run: echo "run"
-      - init: terraform init
-      - plan: terraform plan
+    plan:
+      steps:
+        - run: echo "run"
+        - init
+        - plan
 `
 	deleteFile := createFile(path.Join(tempDir, "digger.yml"), diggerCfg)
 	defer deleteFile()
@@ -1231,3 +1240,579 @@ func TestGetModifiedProjectsReturnsCorrectSourceMapping(t *testing.T) {
 	assert.Equal(t, expectedImpactingLocations["prod"].ImpactingLocations, projectSourceMapping["prod"].ImpactingLocations)
 
 }
+
+func TestCollectTerraformEnvConfigResolvesSecretReferences(t *testing.T) {
+	defer func(previous *redact.Redactor) { redact.Default = previous }(redact.Default)
+	redact.Default = redact.NewRedactor()
+	secrets.RegisterProvider(secrets.AwsSecretsManagerScheme, secrets.NewLocalProvider(map[string]string{
+		"ci-credentials": `{"access_key": "ci-access-key-value"}`,
+	}))
+	defer secrets.RegisterProvider(secrets.AwsSecretsManagerScheme, secrets.AwsSecretsManagerProvider{})
+	os.Setenv("DIGGER_TEST_REGION", "us-east-1")
+	defer os.Unsetenv("DIGGER_TEST_REGION")
+
+	envs := &TerraformEnvConfig{
+		State: []EnvVar{
+			{Name: "AWS_ACCESS_KEY_ID", ValueFrom: "aws-sm://ci-credentials#access_key"},
+		},
+		Commands: []EnvVar{
+			{Name: "AWS_REGION", ValueFrom: "DIGGER_TEST_REGION"},
+			{Name: "TF_VAR_name", Value: "dev"},
+		},
+	}
+
+	stateEnvVars, commandEnvVars, err := CollectTerraformEnvConfig(envs, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-access-key-value", stateEnvVars["AWS_ACCESS_KEY_ID"])
+	assert.Equal(t, "us-east-1", commandEnvVars["AWS_REGION"])
+	assert.Equal(t, "dev", commandEnvVars["TF_VAR_name"])
+	// values resolved from secret stores and from other env vars are redacted, plain values aren't
+	assert.Equal(t, "<REDACTED> <REDACTED> dev", redact.String("ci-access-key-value us-east-1 dev"))
+
+	// references are passed through to the runner when not interpolating
+	stateEnvVars, _, err = CollectTerraformEnvConfig(envs, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "$DIGGER_aws-sm://ci-credentials#access_key", stateEnvVars["AWS_ACCESS_KEY_ID"])
+}
+
+func TestCollectTerraformEnvConfigFailsOnUnresolvedSecret(t *testing.T) {
+	secrets.RegisterProvider(secrets.AwsSecretsManagerScheme, secrets.NewLocalProvider(map[string]string{}))
+	defer secrets.RegisterProvider(secrets.AwsSecretsManagerScheme, secrets.AwsSecretsManagerProvider{})
+
+	envs := &TerraformEnvConfig{
+		State: []EnvVar{
+			{Name: "AWS_ACCESS_KEY_ID", ValueFrom: "aws-sm://missing#access_key"},
+		},
+	}
+
+	_, _, err := CollectTerraformEnvConfig(envs, true)
+	assert.ErrorContains(t, err, "AWS_ACCESS_KEY_ID")
+}
+
+func TestProjectAndBlockMetadata(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: network
+  dir: network
+  environment: prod
+  owners: ["@diggerhq/network"]
+  labels:
+    team: network
+generate_projects:
+  blocks:
+    - include: apps/*
+      environment: staging
+      owners: ["@diggerhq/apps"]
+      labels:
+        team: apps
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+	for _, dir := range []string{"network", "apps/api"} {
+		err := os.MkdirAll(path.Join(tempDir, dir), os.ModePerm)
+		assert.NoError(t, err)
+		defer createFile(path.Join(tempDir, dir, "main.tf"), "")()
+	}
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(dg.Projects))
+	assert.Equal(t, "prod", dg.Projects[0].Environment)
+	assert.Equal(t, []string{"@diggerhq/network"}, dg.Projects[0].Owners)
+	assert.Equal(t, map[string]string{"team": "network"}, dg.Projects[0].Labels)
+	assert.True(t, dg.Projects[0].RequireOwnerApproval)
+	assert.Equal(t, "apps_api", dg.Projects[1].Name)
+	assert.Equal(t, "staging", dg.Projects[1].Environment)
+	assert.Equal(t, []string{"@diggerhq/apps"}, dg.Projects[1].Owners)
+	assert.Equal(t, map[string]string{"team": "apps"}, dg.Projects[1].Labels)
+}
+
+func TestDefaultsAndExtends(t *testing.T) {
+	diggerCfg := `
+defaults:
+  workflow: base
+  include_patterns: ["modules/**"]
+  environment: dev
+projects:
+- name: prod-base
+  abstract: true
+  workflow: prod
+  environment: prod
+  aws_role_to_assume:
+    state: arn:aws:iam::123:role/state
+    command: arn:aws:iam::123:role/command
+- name: prod-network
+  dir: prod/network
+  extends: prod-base
+  include_patterns: []
+- name: dev-network
+  dir: dev/network
+workflows:
+  base:
+    plan:
+      steps:
+        - init
+        - plan:
+            extra_args: ["-lock=false"]
+  prod:
+    extends: base
+    workflow_configuration:
+      on_pull_request_pushed: ["digger plan"]
+      on_pull_request_closed: []
+      on_commit_to_default: ["digger apply"]
+`
+	dg, _, _, err := LoadDiggerConfigFromString(diggerCfg, "./")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(dg.Projects))
+
+	prod := dg.GetProject("prod-network")
+	assert.NotNil(t, prod)
+	assert.Equal(t, "prod", prod.Workflow)
+	assert.Equal(t, "prod", prod.Environment)
+	assert.Equal(t, []string{}, prod.IncludePatterns)
+	assert.Equal(t, "arn:aws:iam::123:role/state", prod.AwsRoleToAssume.State)
+
+	dev := dg.GetProject("dev-network")
+	assert.NotNil(t, dev)
+	assert.Equal(t, "base", dev.Workflow)
+	assert.Equal(t, "dev", dev.Environment)
+	assert.Equal(t, []string{"modules/**"}, dev.IncludePatterns)
+	assert.Nil(t, dev.AwsRoleToAssume)
+
+	prodWorkflow := dg.Workflows["prod"]
+	assert.Equal(t, []string{"-lock=false"}, prodWorkflow.Plan.Steps[1].ExtraArgs)
+	assert.Equal(t, []string{"digger apply"}, prodWorkflow.Configuration.OnCommitToDefault)
+}
+
+func TestExtendsValidationErrors(t *testing.T) {
+	_, _, _, err := LoadDiggerConfigFromString(`
+projects:
+- name: a
+  dir: a
+  extends: missing
+`, "./")
+	assert.ErrorContains(t, err, `projects[0] (a).extends: project "missing" is not defined`)
+
+	_, _, _, err = LoadDiggerConfigFromString(`
+projects:
+- name: a
+  dir: a
+  extends: b
+- name: b
+  dir: b
+  extends: a
+`, "./")
+	assert.ErrorContains(t, err, "extends: cycle detected")
+
+	_, _, _, err = LoadDiggerConfigFromString(`
+projects:
+- name: a
+  dir: a
+workflows:
+  prod:
+    extends: base
+`, "./")
+	assert.ErrorContains(t, err, `workflows.prod.extends: workflow "base" is not defined`)
+
+	_, _, _, err = LoadDiggerConfigFromString(`
+defaults:
+  dir: shared
+projects:
+- name: a
+  dir: a
+`, "./")
+	assert.ErrorContains(t, err, "defaults.dir: key can't be set in defaults")
+}
+
+func TestLoadDiggerConfigWithAbstractProject(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: base
+  abstract: true
+  environment: prod
+- name: network
+  dir: network
+  extends: base
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+
+	dg, configYaml, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(dg.Projects))
+	assert.Equal(t, "prod", dg.Projects[0].Environment)
+
+	// the loaded yaml is already resolved, resolving it again doesn't look for the removed abstract project
+	err = ResolveInheritance(configYaml)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(configYaml.Projects))
+	assert.Equal(t, "prod", configYaml.Projects[0].Environment)
+}
+
+func TestIncludesAndProjectFiles(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+include:
+  - config/*.yml
+defaults:
+  environment: prod
+projects:
+- name: network
+  dir: network
+`
+	includedCfg := `
+projects:
+- name: database
+  dir: database
+  workflow: db
+workflows:
+  db:
+    plan:
+      steps:
+        - init
+        - plan
+`
+	projectCfg := `
+workflow: db
+include_patterns: ["../modules/**"]
+`
+	for _, dir := range []string{"network", "database", "config", "apps/api"} {
+		err := os.MkdirAll(path.Join(tempDir, dir), os.ModePerm)
+		assert.NoError(t, err)
+	}
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "config", "database.yml"), includedCfg)()
+	defer createFile(path.Join(tempDir, "apps", "api", DiggerProjectFileName), projectCfg)()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(dg.Projects))
+	assert.Equal(t, "database", dg.Projects[1].Name)
+	assert.Equal(t, "apps_api", dg.Projects[2].Name)
+	assert.Equal(t, "apps/api", dg.Projects[2].Dir)
+	assert.Equal(t, "db", dg.Projects[2].Workflow)
+	assert.Equal(t, "prod", dg.Projects[2].Environment)
+	assert.Equal(t, []string{"apps/modules/**"}, dg.Projects[2].IncludePatterns)
+	assert.NotNil(t, dg.GetWorkflow("db"))
+
+	merged, err := LoadMergedDiggerConfigString(tempDir)
+	assert.NoError(t, err)
+	assert.NotContains(t, merged, "include:")
+	fromString, _, _, err := LoadDiggerConfigFromString(merged, tempDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(fromString.Projects))
+	assert.Equal(t, "apps/api", fromString.Projects[2].Dir)
+}
+
+func TestIncludesDuplicateProjects(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+include: other.yml
+projects:
+- name: network
+  dir: network
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+	defer createFile(path.Join(tempDir, "other.yml"), "projects:\n- name: network\n  dir: other\n")()
+
+	_, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `project "network" is defined in both digger.yml and other.yml`)
+
+	err = os.MkdirAll(path.Join(tempDir, "network"), os.ModePerm)
+	assert.NoError(t, err)
+	defer createFile(path.Join(tempDir, "other.yml"), "auto_merge: true\n")()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, "key auto_merge is not allowed in included files")
+
+	defer createFile(path.Join(tempDir, "other.yml"), "projects: []\n")()
+	defer createFile(path.Join(tempDir, "network", DiggerProjectFileName), "name: network\n")()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `project "network" is defined in both digger.yml and network/digger.project.yml`)
+}
+
+func TestProjectFilesWithoutDiggerYaml(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	err := os.MkdirAll(path.Join(tempDir, "prod", "vpc"), os.ModePerm)
+	assert.NoError(t, err)
+	defer createFile(path.Join(tempDir, "prod", "vpc", DiggerProjectFileName), "")()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(dg.Projects))
+	assert.Equal(t, "prod_vpc", dg.Projects[0].Name)
+	assert.Equal(t, "prod/vpc", dg.Projects[0].Dir)
+}
+
+func TestUnknownKeysAreReported(t *testing.T) {
+	diggerCfg := `
+projects:
+- name: prod
+  dir: prod
+  include_pattern: ["modules/**"]
+workflows:
+  default:
+    plann:
+      steps: [init, plan]
+`
+	tempDir, teardown := setUp()
+	defer teardown()
+	err := os.MkdirAll(path.Join(tempDir, "prod"), os.ModePerm)
+	assert.NoError(t, err)
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `line 5, column 3: unknown key "include_pattern" in projects[0], did you mean "include_patterns"?`)
+	assert.ErrorContains(t, err, `line 8, column 5: unknown key "plann" in workflows.default, did you mean "plan"?`)
+
+	err = os.Remove(path.Join(tempDir, "digger.yml"))
+	assert.NoError(t, err)
+	defer createFile(path.Join(tempDir, "prod", DiggerProjectFileName), "workspce: prod\n")()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `error parsing 'prod/digger.project.yml': line 1, column 1: unknown key "workspce", did you mean "workspace"?`)
+
+	// configs saved by the backend are loaded with the unknown keys ignored
+	configYaml, err := LoadDiggerConfigYamlFromString(diggerCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", configYaml.Projects[0].Name)
+}
+
+func TestJsonSchemaIsUpToDate(t *testing.T) {
+	schema, err := GenerateJsonSchema()
+	assert.NoError(t, err)
+	_, testFile, _, _ := runtime.Caller(0)
+	published, err := os.ReadFile(path.Join(path.Dir(testFile), "schema", "digger.schema.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(published), string(schema), "schema/digger.schema.json is out of date, run go generate ./digger_config")
+}
+
+func TestLintDiggerConfigYaml(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: network
+  dir: prod/network
+  depends_on: [app]
+- name: app
+  dir: prod/app
+  depends_on: [database]
+  include_patterns: ["modules/**"]
+- name: database
+  dir: prod/database
+  depends_on: [network]
+- name: prod
+  dir: prod
+workflows:
+  unused:
+    plan:
+      steps: [init, plan]
+`
+	for _, dir := range []string{"prod/network", "prod/app"} {
+		err := os.MkdirAll(path.Join(tempDir, dir), os.ModePerm)
+		assert.NoError(t, err)
+		defer createFile(path.Join(tempDir, dir, "main.tf"), "")()
+	}
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+
+	configYaml, err := LoadDiggerConfigYaml(tempDir, true, nil)
+	assert.NoError(t, err)
+	lints, err := LintDiggerConfigYaml(tempDir, configYaml)
+	assert.NoError(t, err)
+	assert.True(t, HasLintErrors(lints))
+
+	messages := make([]string, 0)
+	for _, l := range lints {
+		messages = append(messages, l.String())
+	}
+	assert.Contains(t, messages, "error: depends_on cycle detected: network -> app -> database -> network")
+	assert.Contains(t, messages, "warning: workflow unused is not used by any project")
+	assert.Contains(t, messages, "error: project database: dir prod/database does not exist")
+	assert.Contains(t, messages, "warning: project network (prod/network) is nested in project prod (prod), changes to network also impact prod")
+	assert.Contains(t, messages, "warning: project app: include pattern modules/** does not match any file")
+}
+
+func TestLintDiggerConfigYamlWithExtends(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: base
+  abstract: true
+  workflow: prod
+- name: network
+  dir: network
+  extends: base
+workflows:
+  base:
+    plan:
+      steps: [init, plan]
+  prod:
+    extends: base
+`
+	err := os.MkdirAll(path.Join(tempDir, "network"), os.ModePerm)
+	assert.NoError(t, err)
+	defer createFile(path.Join(tempDir, "network", "main.tf"), "")()
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+
+	configYaml, err := LoadDiggerConfigYaml(tempDir, true, nil)
+	assert.NoError(t, err)
+	lints, err := LintDiggerConfigYaml(tempDir, configYaml)
+	assert.NoError(t, err)
+	assert.Empty(t, lints)
+}
+
+func TestModuleDependencies(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	for _, dir := range []string{"prod/network", "prod/app", "modules/vpc", "modules/subnet", "modules/app"} {
+		err := os.MkdirAll(path.Join(tempDir, dir), os.ModePerm)
+		assert.NoError(t, err)
+	}
+	defer createFile(path.Join(tempDir, "prod/network/main.tf"), `
+module "vpc" {
+  source = "../../modules/vpc"
+}
+module "remote" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`)()
+	defer createFile(path.Join(tempDir, "prod/app/main.tf"), `
+module "app" {
+  source = "../../modules/app"
+}
+`)()
+	defer createFile(path.Join(tempDir, "modules/vpc/main.tf"), `
+module "subnet" {
+  source = "../subnet"
+}
+`)()
+	defer createFile(path.Join(tempDir, "modules/subnet/main.tf"), "")()
+	defer createFile(path.Join(tempDir, "modules/app/main.tf"), "")()
+
+	diggerCfg := `
+projects:
+- name: network
+  dir: prod/network
+- name: app
+  dir: prod/app
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+
+	assert.Equal(t, []string{"modules/subnet", "modules/vpc"}, LocalModuleDirs(tempDir, "prod/network"))
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"modules/subnet/**", "modules/vpc/**"}, dg.Projects[0].IncludePatterns)
+
+	impacted, _ := dg.GetModifiedProjects([]string{"modules/subnet/main.tf"})
+	assert.Equal(t, 1, len(impacted))
+	assert.Equal(t, "network", impacted[0].Name)
+
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg+"detect_module_dependencies: false\n")()
+	dg, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, dg.Projects[0].IncludePatterns)
+}
+
+func TestProjectInputs(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: network
+  dir: network
+- name: app
+  dir: app
+  depends_on: [network]
+  inputs:
+    vpc_id: network.vpc_id
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"vpc_id": "network.vpc_id"}, dg.Projects[1].Inputs)
+
+	defer createFile(path.Join(tempDir, "digger.yml"), strings.Replace(diggerCfg, "depends_on: [network]", "depends_on: []", 1))()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, "input vpc_id of project 'app' uses outputs of project 'network' which is not in its depends_on")
+
+	defer createFile(path.Join(tempDir, "digger.yml"), strings.Replace(diggerCfg, "network.vpc_id", "vpc_id", 1))()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `invalid input vpc_id for project 'app': expected project.output, got "vpc_id"`)
+}
+
+func TestRetries(t *testing.T) {
+	tempDir, teardown := setUp()
+	defer teardown()
+
+	diggerCfg := `
+projects:
+- name: prod
+  dir: prod
+`
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg)()
+	dg, _, _, err := LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dg.Retries.MaxAttempts)
+	assert.False(t, dg.Retries.IsRetryable(FailureClassThrottled, 1))
+
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg+`
+retries:
+  max_attempts: 3
+  backoff: 1m
+  on: [throttled, runner_lost]
+`)()
+	dg, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dg.Retries.MaxAttempts)
+	assert.True(t, dg.Retries.IsRetryable(FailureClassThrottled, 2))
+	assert.False(t, dg.Retries.IsRetryable(FailureClassThrottled, 3))
+	assert.False(t, dg.Retries.IsRetryable(FailureClassStateLock, 1))
+	assert.Equal(t, time.Minute, dg.Retries.BackoffAfter(1))
+	assert.Equal(t, 4*time.Minute, dg.Retries.BackoffAfter(3))
+
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg+`
+retries: {}
+`)()
+	dg, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dg.Retries.MaxAttempts)
+	assert.Equal(t, FailureClasses, dg.Retries.RetryableFailures)
+
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg+`
+retries:
+  on: [flaky]
+`)()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `unknown failure class "flaky" in retries`)
+
+	defer createFile(path.Join(tempDir, "digger.yml"), diggerCfg+`
+retries:
+  backoff: soon
+`)()
+	_, _, _, err = LoadDiggerConfig(tempDir, true, nil)
+	assert.ErrorContains(t, err, `invalid retries backoff "soon"`)
+}
+
+func TestClassifyFailure(t *testing.T) {
+	assert.Equal(t, FailureClassThrottled, ClassifyFailure("Error: creating EC2 Instance: operation error EC2: RunInstances, https response error StatusCode: 400, api error RequestLimitExceeded: Request limit exceeded."))
+	assert.Equal(t, FailureClassThrottled, ClassifyFailure("googleapi: Error 429: Too Many Requests"))
+	assert.Equal(t, FailureClassNetwork, ClassifyFailure("dial tcp: lookup registry.terraform.io: no such host"))
+	assert.Equal(t, FailureClassStateLock, ClassifyFailure("Error: Error acquiring the state lock"))
+	assert.Equal(t, "", ClassifyFailure("Error: Unsupported argument"))
+	assert.Equal(t, "", ClassifyFailure(""))
+}
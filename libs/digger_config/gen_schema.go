@@ -0,0 +1,24 @@
+//go:build ignore
+
+// gen_schema writes the JSON Schema of digger.yml to schema/digger.schema.json, run it with go generate
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+)
+
+func main() {
+	schema, err := digger_config.GenerateJsonSchema()
+	if err != nil {
+		log.Fatalf("failed to generate schema: %v", err)
+	}
+	if err := os.MkdirAll("schema", 0755); err != nil {
+		log.Fatalf("failed to create schema directory: %v", err)
+	}
+	if err := os.WriteFile("schema/digger.schema.json", schema, 0644); err != nil {
+		log.Fatalf("failed to write schema: %v", err)
+	}
+}
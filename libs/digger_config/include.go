@@ -0,0 +1,398 @@
+package digger_config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DiggerProjectFileName is the name of directory-local project files, each of them configures the
+// project of the directory it's in
+const DiggerProjectFileName = "digger.project.yml"
+
+// keys allowed in files pulled in with `include`
+var includeFileKeys = map[string]bool{
+	"projects":  true,
+	"workflows": true,
+	"include":   true,
+}
+
+type FileSystemProjectFileDirWalker struct {
+}
+
+// GetDirs returns the directories containing a digger.project.yml file
+func (walker *FileSystemProjectFileDirWalker) GetDirs(workingDir string, configYaml *DiggerConfigYaml) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(workingDir,
+		func(path string, info os.FileInfo, err error) error {
+
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != workingDir && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if isFileExists(filepath.Join(path, DiggerProjectFileName)) {
+					dir, err := filepath.Rel(workingDir, path)
+					if err != nil {
+						return err
+					}
+					dirs = append(dirs, filepath.ToSlash(dir))
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// configSources keeps track of the file every project and workflow of a merged config comes from,
+// so that duplicates can be reported with both locations
+type configSources struct {
+	projects  map[string]string
+	workflows map[string]string
+	included  map[string]bool
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func removeMappingKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// readYamlMapping parses a yaml file whose top level is expected to be a mapping, an empty file
+// results in an empty mapping
+func readYamlMapping(fileName string, displayName string) (*yaml.Node, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digger_config file %s: %v", displayName, err)
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("error parsing '%s': %v", displayName, err)
+	}
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("error parsing '%s': expected a mapping at the top level", displayName)
+	}
+	return mapping, nil
+}
+
+// checkFileKeys reports unknown keys of a config file
+func checkFileKeys(mapping *yaml.Node, t reflect.Type, displayName string) error {
+	if errs := checkYamlKeys(mapping, t, ""); len(errs) > 0 {
+		return fmt.Errorf("error parsing '%s': %w", displayName, errors.Join(errs...))
+	}
+	return nil
+}
+
+// mergeConfigMapping appends the projects and workflows of source to target
+func mergeConfigMapping(target *yaml.Node, source *yaml.Node, sourceName string, sources *configSources) error {
+	if projects := mappingValue(source, "projects"); projects != nil && projects.Kind != yaml.ScalarNode {
+		if projects.Kind != yaml.SequenceNode {
+			return fmt.Errorf("error parsing '%s': projects must be a list", sourceName)
+		}
+		targetProjects := mappingValue(target, "projects")
+		if targetProjects == nil || targetProjects.Kind != yaml.SequenceNode {
+			targetProjects = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(target, "projects", targetProjects)
+		}
+		for _, project := range projects.Content {
+			if err := sources.addProject(project, sourceName); err != nil {
+				return err
+			}
+			targetProjects.Content = append(targetProjects.Content, project)
+		}
+	}
+
+	if workflows := mappingValue(source, "workflows"); workflows != nil && workflows.Kind != yaml.ScalarNode {
+		if workflows.Kind != yaml.MappingNode {
+			return fmt.Errorf("error parsing '%s': workflows must be a mapping", sourceName)
+		}
+		targetWorkflows := mappingValue(target, "workflows")
+		if targetWorkflows == nil || targetWorkflows.Kind != yaml.MappingNode {
+			targetWorkflows = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(target, "workflows", targetWorkflows)
+		}
+		for i := 0; i+1 < len(workflows.Content); i += 2 {
+			name := workflows.Content[i].Value
+			if existing, ok := sources.workflows[name]; ok {
+				return fmt.Errorf("workflow %q is defined in both %v and %v", name, existing, sourceName)
+			}
+			sources.workflows[name] = sourceName
+			targetWorkflows.Content = append(targetWorkflows.Content, workflows.Content[i], workflows.Content[i+1])
+		}
+	}
+	return nil
+}
+
+func (s *configSources) addProject(project *yaml.Node, sourceName string) error {
+	if project.Kind != yaml.MappingNode {
+		return nil
+	}
+	name := mappingValue(project, "name")
+	if name == nil || name.Value == "" {
+		return nil
+	}
+	if existing, ok := s.projects[name.Value]; ok {
+		return fmt.Errorf("project %q is defined in both %v and %v", name.Value, existing, sourceName)
+	}
+	s.projects[name.Value] = sourceName
+	return nil
+}
+
+func (s *configSources) addWorkflows(mapping *yaml.Node, sourceName string) {
+	workflows := mappingValue(mapping, "workflows")
+	if workflows == nil || workflows.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(workflows.Content); i += 2 {
+		s.workflows[workflows.Content[i].Value] = sourceName
+	}
+}
+
+// includePatterns reads the `include` key, either a single path or a list of paths
+func includePatterns(mapping *yaml.Node, sourceName string) ([]string, error) {
+	include := mappingValue(mapping, "include")
+	if include == nil {
+		return nil, nil
+	}
+	var patterns []string
+	switch include.Kind {
+	case yaml.ScalarNode:
+		if include.Value != "" {
+			patterns = append(patterns, include.Value)
+		}
+	case yaml.SequenceNode:
+		for _, item := range include.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("error parsing '%s': include must be a list of paths", sourceName)
+			}
+			patterns = append(patterns, item.Value)
+		}
+	default:
+		return nil, fmt.Errorf("error parsing '%s': include must be a list of paths", sourceName)
+	}
+	return patterns, nil
+}
+
+// mergeIncludes merges the files included by source into target. Include paths are glob patterns
+// relative to the root of the repository, a file included more than once is only merged once.
+func mergeIncludes(workingDir string, target *yaml.Node, source *yaml.Node, sourceName string, sources *configSources) error {
+	patterns, err := includePatterns(source, sourceName)
+	if err != nil {
+		return err
+	}
+	for _, pattern := range patterns {
+		if path.IsAbs(pattern) || strings.HasPrefix(path.Clean(pattern), "..") {
+			return fmt.Errorf("include %q in %v must be a path inside the repository", pattern, sourceName)
+		}
+		matches, err := filepath.Glob(filepath.Join(workingDir, filepath.FromSlash(pattern)))
+		if err != nil {
+			return fmt.Errorf("include %q in %v is not a valid pattern: %v", pattern, sourceName, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("include %q in %v does not match any file", pattern, sourceName)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			relPath, err := filepath.Rel(workingDir, match)
+			if err != nil {
+				return err
+			}
+			includedName := filepath.ToSlash(relPath)
+			if sources.included[includedName] {
+				continue
+			}
+			sources.included[includedName] = true
+
+			included, err := readYamlMapping(match, includedName)
+			if err != nil {
+				return err
+			}
+			for i := 0; i+1 < len(included.Content); i += 2 {
+				if key := included.Content[i].Value; !includeFileKeys[key] {
+					return fmt.Errorf("error parsing '%s': key %v is not allowed in included files, only projects, workflows and include can be set", includedName, key)
+				}
+			}
+			if err := checkFileKeys(included, reflect.TypeOf(DiggerConfigYaml{}), includedName); err != nil {
+				return err
+			}
+			if err := mergeConfigMapping(target, included, includedName, sources); err != nil {
+				return err
+			}
+			if err := mergeIncludes(workingDir, target, included, includedName, sources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// projectFileNode reads a digger.project.yml file and makes its paths relative to the root of the repository.
+// The dir of the project defaults to the directory of the file and its name to that directory with
+// slashes replaced by underscores.
+func projectFileNode(workingDir string, dir string) (*yaml.Node, string, error) {
+	fileName := path.Join(dir, DiggerProjectFileName)
+	project, err := readYamlMapping(filepath.Join(workingDir, filepath.FromSlash(fileName)), fileName)
+	if err != nil {
+		return nil, fileName, err
+	}
+	if err := checkFileKeys(project, reflect.TypeOf(ProjectYaml{}), fileName); err != nil {
+		return nil, fileName, err
+	}
+
+	projectDir := dir
+	if dirNode := mappingValue(project, "dir"); dirNode != nil && dirNode.Value != "" {
+		projectDir = path.Join(dir, dirNode.Value)
+	}
+	setMappingValue(project, "dir", stringNode(projectDir))
+
+	if name := mappingValue(project, "name"); name == nil || name.Value == "" {
+		if dir == "." {
+			return nil, fileName, fmt.Errorf("error parsing '%s': name is required for a project file at the root of the repository", fileName)
+		}
+		setMappingValue(project, "name", stringNode(strings.ReplaceAll(dir, "/", "_")))
+	}
+
+	for _, key := range []string{"include_patterns", "exclude_patterns"} {
+		patterns := mappingValue(project, key)
+		if patterns == nil || patterns.Kind != yaml.SequenceNode {
+			continue
+		}
+		values := make([]string, 0, len(patterns.Content))
+		for _, p := range patterns.Content {
+			values = append(values, p.Value)
+		}
+		values, err = GetPatternsRelativeToRepo(dir, values)
+		if err != nil {
+			return nil, fileName, err
+		}
+		for i, value := range values {
+			patterns.Content[i] = stringNode(value)
+		}
+	}
+	return project, fileName, nil
+}
+
+// loadDiggerConfigNode reads the digger config file along with the files it includes and the digger.project.yml
+// files found in the repository, and merges them in a single document. When neither a config file nor project
+// files exist nil is returned.
+func loadDiggerConfigNode(workingDir string, fileName string) (*yaml.Node, error) {
+	walker := &FileSystemProjectFileDirWalker{}
+	projectDirs, err := walker.GetDirs(workingDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %v files: %v", DiggerProjectFileName, err)
+	}
+	if fileName == "" && len(projectDirs) == 0 {
+		return nil, nil
+	}
+
+	sources := &configSources{
+		projects:  make(map[string]string),
+		workflows: make(map[string]string),
+		included:  make(map[string]bool),
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	rootName := "digger.yml"
+	if fileName != "" {
+		rootName = filepath.Base(fileName)
+		root, err = readYamlMapping(fileName, fileName)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFileKeys(root, reflect.TypeOf(DiggerConfigYaml{}), fileName); err != nil {
+			return nil, err
+		}
+		if projects := mappingValue(root, "projects"); projects != nil && projects.Kind == yaml.SequenceNode {
+			for _, project := range projects.Content {
+				if err := sources.addProject(project, rootName); err != nil {
+					return nil, err
+				}
+			}
+		}
+		sources.addWorkflows(root, rootName)
+	}
+
+	if err := mergeIncludes(workingDir, root, root, rootName, sources); err != nil {
+		return nil, err
+	}
+	removeMappingKey(root, "include")
+
+	for _, dir := range projectDirs {
+		project, projectFileName, err := projectFileNode(workingDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		projects := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{project}}
+		source := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(source, "projects", projects)
+		if err := mergeConfigMapping(root, source, projectFileName, sources); err != nil {
+			return nil, err
+		}
+	}
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}, nil
+}
+
+// LoadMergedDiggerConfigString returns the digger config of workingDir with includes and digger.project.yml
+// files merged in, so that it can be stored and loaded later with LoadDiggerConfigFromString. An empty string
+// is returned when the repository has no digger config.
+func LoadMergedDiggerConfigString(workingDir string) (string, error) {
+	fileName, err := retrieveConfigFile(workingDir)
+	if err != nil {
+		return "", fmt.Errorf("error while retrieving digger_config file: %v", err)
+	}
+	node, err := loadDiggerConfigNode(workingDir, fileName)
+	if err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", nil
+	}
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal merged digger_config: %v", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,179 @@
+package digger_config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// keys that are never inherited from defaults or extended projects
+var nonInheritableProjectKeys = map[string]bool{
+	"name":      true,
+	"dir":       true,
+	"extends":   true,
+	"abstract":  true,
+	"generated": true,
+}
+
+func yamlTagName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	return name
+}
+
+// explicitKeys returns the keys set in the yaml, for structs built in code (e.g. generated projects)
+// every non zero field is considered set
+func explicitKeys(setKeys map[string]bool, value reflect.Value) map[string]bool {
+	if setKeys != nil {
+		return setKeys
+	}
+	keys := make(map[string]bool)
+	for i := 0; i < value.NumField(); i++ {
+		name := yamlTagName(value.Type().Field(i))
+		if name != "" && name != "-" && !value.Field(i).IsZero() {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// mergeYamlStruct copies the values of parent for every key that is not set in child. The keys copied
+// over are marked as set so that values further up the chain don't override them.
+func mergeYamlStruct(child reflect.Value, childKeys map[string]bool, parent reflect.Value, parentKeys map[string]bool, skip map[string]bool) {
+	for i := 0; i < child.NumField(); i++ {
+		name := yamlTagName(child.Type().Field(i))
+		if name == "" || name == "-" || skip[name] {
+			continue
+		}
+		if childKeys[name] || !parentKeys[name] {
+			continue
+		}
+		child.Field(i).Set(parent.Field(i))
+		childKeys[name] = true
+	}
+}
+
+func projectPath(index int, project *ProjectYaml) string {
+	if project.Name == "" {
+		return fmt.Sprintf("projects[%v]", index)
+	}
+	return fmt.Sprintf("projects[%v] (%v)", index, project.Name)
+}
+
+// ResolveInheritance applies `defaults` and `extends` of projects and workflows in place. Resolved projects
+// no longer extend anything, so resolving a config again leaves it unchanged
+func ResolveInheritance(diggerYaml *DiggerConfigYaml) error {
+	err := resolveWorkflowInheritance(diggerYaml.Workflows)
+	if err != nil {
+		return err
+	}
+	return resolveProjectInheritance(diggerYaml)
+}
+
+// resolveProjectInheritance merges extended projects and defaults into every project. Values set on the
+// project take precedence over values of the project it extends, which take precedence over defaults.
+// Abstract projects are removed once resolved.
+func resolveProjectInheritance(diggerYaml *DiggerConfigYaml) error {
+	if diggerYaml.Defaults != nil {
+		for key := range diggerYaml.Defaults.setKeys {
+			if nonInheritableProjectKeys[key] {
+				return fmt.Errorf("defaults.%v: key can't be set in defaults", key)
+			}
+		}
+	}
+
+	projectsByName := make(map[string]int)
+	for i, p := range diggerYaml.Projects {
+		if p == nil {
+			return fmt.Errorf("projects[%v]: project is empty", i)
+		}
+		p.setKeys = explicitKeys(p.setKeys, reflect.ValueOf(p).Elem())
+		projectsByName[p.Name] = i
+	}
+
+	resolved := make(map[int]bool)
+	var resolve func(index int, chain []string) error
+	resolve = func(index int, chain []string) error {
+		project := diggerYaml.Projects[index]
+		if resolved[index] || project.Extends == "" {
+			resolved[index] = true
+			return nil
+		}
+		for _, name := range chain {
+			if name == project.Name {
+				return fmt.Errorf("%v.extends: cycle detected: %v -> %v", projectPath(index, project), strings.Join(chain, " -> "), project.Name)
+			}
+		}
+		parentIndex, ok := projectsByName[project.Extends]
+		if !ok {
+			return fmt.Errorf("%v.extends: project %q is not defined", projectPath(index, project), project.Extends)
+		}
+		err := resolve(parentIndex, append(chain, project.Name))
+		if err != nil {
+			return err
+		}
+		parent := diggerYaml.Projects[parentIndex]
+		mergeYamlStruct(reflect.ValueOf(project).Elem(), project.setKeys, reflect.ValueOf(parent).Elem(), parent.setKeys, nonInheritableProjectKeys)
+		// the extended project may be abstract and removed below
+		project.Extends = ""
+		resolved[index] = true
+		return nil
+	}
+
+	for i := range diggerYaml.Projects {
+		if err := resolve(i, nil); err != nil {
+			return err
+		}
+	}
+
+	projects := make([]*ProjectYaml, 0, len(diggerYaml.Projects))
+	for _, p := range diggerYaml.Projects {
+		if p.Abstract {
+			continue
+		}
+		if diggerYaml.Defaults != nil {
+			mergeYamlStruct(reflect.ValueOf(p).Elem(), p.setKeys, reflect.ValueOf(diggerYaml.Defaults).Elem(), diggerYaml.Defaults.setKeys, nonInheritableProjectKeys)
+		}
+		projects = append(projects, p)
+	}
+	diggerYaml.Projects = projects
+	return nil
+}
+
+// resolveWorkflowInheritance merges extended workflows into the workflows extending them
+func resolveWorkflowInheritance(workflows map[string]*WorkflowYaml) error {
+	resolved := make(map[string]bool)
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		workflow := workflows[name]
+		if resolved[name] || workflow == nil || workflow.Extends == "" {
+			resolved[name] = true
+			return nil
+		}
+		for _, n := range chain {
+			if n == name {
+				return fmt.Errorf("workflows.%v.extends: cycle detected: %v -> %v", name, strings.Join(chain, " -> "), name)
+			}
+		}
+		parent, ok := workflows[workflow.Extends]
+		if !ok {
+			return fmt.Errorf("workflows.%v.extends: workflow %q is not defined", name, workflow.Extends)
+		}
+		if err := resolve(workflow.Extends, append(chain, name)); err != nil {
+			return err
+		}
+		if parent != nil {
+			workflow.setKeys = explicitKeys(workflow.setKeys, reflect.ValueOf(workflow).Elem())
+			parentKeys := explicitKeys(parent.setKeys, reflect.ValueOf(parent).Elem())
+			mergeYamlStruct(reflect.ValueOf(workflow).Elem(), workflow.setKeys, reflect.ValueOf(parent).Elem(), parentKeys, map[string]bool{"extends": true})
+		}
+		resolved[name] = true
+		return nil
+	}
+
+	for name := range workflows {
+		if err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package digger_config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseProjectInput splits an input of the form "project.output" into the project and output names
+func ParseProjectInput(input string) (string, string, error) {
+	i := strings.LastIndex(input, ".")
+	if i <= 0 || i == len(input)-1 {
+		return "", "", fmt.Errorf("expected project.output, got %q", input)
+	}
+	return input[:i], input[i+1:], nil
+}
@@ -0,0 +1,248 @@
+package digger_config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+// Lint is a problem found in a digger config that doesn't prevent it from loading
+type Lint struct {
+	Severity LintSeverity
+	Message  string
+}
+
+func (l Lint) String() string {
+	return fmt.Sprintf("%v: %v", l.Severity, l.Message)
+}
+
+// HasLintErrors returns true if any of the lints is an error
+func HasLintErrors(lints []Lint) bool {
+	for _, l := range lints {
+		if l.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintDiggerConfigYaml checks a config loaded with LoadDiggerConfigYaml against the repository in workingDir
+// for mistakes such as unused workflows, overlapping projects, include patterns that match nothing, dependency
+// cycles and project dirs that don't exist. The config is not modified.
+func LintDiggerConfigYaml(workingDir string, configYaml *DiggerConfigYaml) ([]Lint, error) {
+	lints := make([]Lint, 0)
+	lints = append(lints, lintDependencies(configYaml.Projects)...)
+	lints = append(lints, lintUnusedWorkflows(configYaml)...)
+	lints = append(lints, lintProjectDirs(workingDir, configYaml.Projects)...)
+	lints = append(lints, lintOverlappingProjects(configYaml.Projects)...)
+
+	files, err := repositoryFiles(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	lints = append(lints, lintIncludePatterns(configYaml.Projects, files)...)
+	return lints, nil
+}
+
+// findDependencyCycle returns the first cycle in depends_on as a path of project names starting and
+// ending with the same project, or nil if there is none
+func findDependencyCycle(names []string, dependencies map[string][]string) []string {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var stack []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = inProgress
+		stack = append(stack, name)
+		for _, dependency := range dependencies[name] {
+			if _, ok := dependencies[dependency]; !ok {
+				continue
+			}
+			switch state[dependency] {
+			case inProgress:
+				for i, n := range stack {
+					if n == dependency {
+						return append(append([]string{}, stack[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func lintDependencies(projects []*ProjectYaml) []Lint {
+	lints := make([]Lint, 0)
+	names := make(map[string]bool)
+	for _, p := range projects {
+		names[p.Name] = true
+	}
+	for _, p := range projects {
+		for _, dependency := range p.DependencyProjects {
+			if !names[dependency] {
+				lints = append(lints, Lint{LintError, fmt.Sprintf("project %v depends on project %v which does not exist", p.Name, dependency)})
+			}
+		}
+	}
+	dependencies := make(map[string][]string)
+	projectNames := make([]string, 0, len(projects))
+	for _, p := range projects {
+		dependencies[p.Name] = p.DependencyProjects
+		projectNames = append(projectNames, p.Name)
+	}
+	if cycle := findDependencyCycle(projectNames, dependencies); cycle != nil {
+		lints = append(lints, Lint{LintError, fmt.Sprintf("depends_on cycle detected: %v", strings.Join(cycle, " -> "))})
+	}
+	return lints
+}
+
+func lintUnusedWorkflows(configYaml *DiggerConfigYaml) []Lint {
+	used := make(map[string]bool)
+	for _, p := range configYaml.Projects {
+		used[p.Workflow] = true
+	}
+	if configYaml.GenerateProjectsConfig != nil {
+		for _, b := range configYaml.GenerateProjectsConfig.Blocks {
+			used[b.Workflow] = true
+		}
+		if parsing := configYaml.GenerateProjectsConfig.TerragruntParsingConfig; parsing != nil {
+			used[parsing.DefaultWorkflow] = true
+		}
+	}
+	for _, w := range configYaml.Workflows {
+		if w != nil && w.Extends != "" {
+			used[w.Extends] = true
+		}
+	}
+
+	names := make([]string, 0, len(configYaml.Workflows))
+	for name := range configYaml.Workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lints := make([]Lint, 0)
+	for _, name := range names {
+		if !used[name] && name != defaultWorkflowName {
+			lints = append(lints, Lint{LintWarning, fmt.Sprintf("workflow %v is not used by any project", name)})
+		}
+	}
+	return lints
+}
+
+func lintProjectDirs(workingDir string, projects []*ProjectYaml) []Lint {
+	lints := make([]Lint, 0)
+	for _, p := range projects {
+		info, err := os.Stat(filepath.Join(workingDir, filepath.FromSlash(p.Dir)))
+		if err != nil || !info.IsDir() {
+			lints = append(lints, Lint{LintError, fmt.Sprintf("project %v: dir %v does not exist", p.Name, p.Dir)})
+		}
+	}
+	return lints
+}
+
+func lintOverlappingProjects(projects []*ProjectYaml) []Lint {
+	lints := make([]Lint, 0)
+	for i, a := range projects {
+		for _, b := range projects[i+1:] {
+			dirA := path.Clean(a.Dir)
+			dirB := path.Clean(b.Dir)
+			switch {
+			case dirA == dirB && a.Workspace == b.Workspace:
+				lints = append(lints, Lint{LintError, fmt.Sprintf("projects %v and %v use the same dir %v and workspace %v", a.Name, b.Name, dirA, a.Workspace)})
+			case dirA == dirB:
+				continue
+			case !a.Terragrunt && isNestedDir(dirB, dirA):
+				lints = append(lints, Lint{LintWarning, fmt.Sprintf("project %v (%v) is nested in project %v (%v), changes to %v also impact %v", b.Name, dirB, a.Name, dirA, b.Name, a.Name)})
+			case !b.Terragrunt && isNestedDir(dirA, dirB):
+				lints = append(lints, Lint{LintWarning, fmt.Sprintf("project %v (%v) is nested in project %v (%v), changes to %v also impact %v", a.Name, dirA, b.Name, dirB, a.Name, b.Name)})
+			}
+		}
+	}
+	return lints
+}
+
+func isNestedDir(dir string, parent string) bool {
+	return parent == "." || strings.HasPrefix(dir, parent+"/")
+}
+
+// repositoryFiles lists the files of the repository relative to workingDir, hidden directories such as
+// .git and .terraform are skipped
+func repositoryFiles(workingDir string) ([]string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(workingDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if filePath != workingDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(workingDir, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %v", err)
+	}
+	return files, nil
+}
+
+func lintIncludePatterns(projects []*ProjectYaml, files []string) []Lint {
+	lints := make([]Lint, 0)
+	for _, p := range projects {
+		for _, pattern := range p.IncludePatterns {
+			if !doublestar.ValidatePathPattern(NormalizeFileName(pattern)) {
+				lints = append(lints, Lint{LintError, fmt.Sprintf("project %v: include pattern %v is not a valid pattern", p.Name, pattern)})
+				continue
+			}
+			matched := false
+			for _, f := range files {
+				if MatchIncludeExcludePatternsToFile(f, []string{pattern}, nil) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				lints = append(lints, Lint{LintWarning, fmt.Sprintf("project %v: include pattern %v does not match any file", p.Name, pattern)})
+			}
+		}
+	}
+	return lints
+}
@@ -0,0 +1,84 @@
+package digger_config
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-config-inspect/tfconfig"
+	"github.com/samber/lo"
+)
+
+var localModuleSourcePrefixes = []string{
+	"./",
+	"../",
+	".\\",
+	"..\\",
+}
+
+func isLocalModuleSource(source string) bool {
+	for _, prefix := range localModuleSourcePrefixes {
+		if strings.HasPrefix(source, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalModuleDirs returns the dirs of the local modules a root module sources, directly or through other
+// local modules. Dirs are relative to workingDir, sources pointing outside of it and remote sources are
+// ignored.
+func LocalModuleDirs(workingDir string, moduleDir string) []string {
+	visited := make(map[string]bool)
+	var visit func(dir string)
+	visit = func(dir string) {
+		module, diags := tfconfig.LoadModule(filepath.Join(workingDir, filepath.FromSlash(dir)))
+		if diags.HasErrors() {
+			// a partially parsed module still lists the module calls that could be read
+			log.Printf("could not fully parse module %v, module dependencies may be incomplete: %v", dir, diags.Error())
+		}
+		if module == nil {
+			return
+		}
+		for _, call := range module.ModuleCalls {
+			if !isLocalModuleSource(call.Source) {
+				continue
+			}
+			sourceDir := path.Join(dir, filepath.ToSlash(call.Source))
+			if sourceDir == ".." || strings.HasPrefix(sourceDir, "../") || visited[sourceDir] {
+				continue
+			}
+			visited[sourceDir] = true
+			visit(sourceDir)
+		}
+	}
+	visit(path.Clean(moduleDir))
+
+	dirs := make([]string, 0, len(visited))
+	for dir := range visited {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// addModuleDependencies adds the dirs of the local modules sourced by every terraform project to its
+// include patterns, so that a change to a module only impacts the projects using it
+func addModuleDependencies(workingDir string, configYaml *DiggerConfigYaml) {
+	if configYaml.DetectModuleDependencies != nil && !*configYaml.DetectModuleDependencies {
+		return
+	}
+	for _, p := range configYaml.Projects {
+		if p.Terragrunt {
+			continue
+		}
+		for _, dir := range LocalModuleDirs(workingDir, p.Dir) {
+			pattern := dir + "/**"
+			if !lo.Contains(p.IncludePatterns, pattern) {
+				p.IncludePatterns = append(p.IncludePatterns, pattern)
+			}
+		}
+	}
+}
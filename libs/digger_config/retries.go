@@ -0,0 +1,138 @@
+package digger_config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// classes of failures a job can be retried for
+const (
+	// the cloud provider throttled its API calls
+	FailureClassThrottled = "throttled"
+	// a connection to the provider or the state backend failed
+	FailureClassNetwork = "network"
+	// the state was locked by another run
+	FailureClassStateLock = "state_lock"
+	// the CI run of the job never started, for instance because the runner was evicted
+	FailureClassRunnerLost = "runner_lost"
+)
+
+var FailureClasses = []string{FailureClassThrottled, FailureClassNetwork, FailureClassStateLock, FailureClassRunnerLost}
+
+// output fragments, in lower case, identifying the failures that are likely to go away when the job runs again
+var failurePatterns = map[string][]string{
+	FailureClassThrottled: {
+		"throttling",
+		"rate exceeded",
+		"ratelimitexceeded",
+		"requestlimitexceeded",
+		"too many requests",
+		"status code: 429",
+		"slowdown",
+	},
+	FailureClassNetwork: {
+		"connection reset by peer",
+		"connection refused",
+		"i/o timeout",
+		"tls handshake timeout",
+		"no such host",
+		"temporary failure in name resolution",
+		"unexpected eof",
+	},
+	FailureClassStateLock: {
+		"error acquiring the state lock",
+	},
+}
+
+// ClassifyFailure returns the class of the failure a job reported output for, empty if it isn't a known
+// transient failure
+func ClassifyFailure(output string) string {
+	output = strings.ToLower(output)
+	for _, class := range FailureClasses {
+		for _, pattern := range failurePatterns[class] {
+			if strings.Contains(output, pattern) {
+				return class
+			}
+		}
+	}
+	return ""
+}
+
+const defaultRetryMaxAttempts = 3
+const defaultRetryBackoff = 30 * time.Second
+
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a job runs at most, 1 disables retries
+	MaxAttempts int
+	// Backoff is the delay before the first retry, it doubles for every following retry
+	Backoff           time.Duration
+	RetryableFailures []string
+}
+
+// IsRetryable returns whether a job that failed with a failure of class can run again after attempts runs
+func (p RetryPolicy) IsRetryable(class string, attempts int) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	for _, c := range p.RetryableFailures {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// BackoffAfter returns the delay before running a job again after attempts runs
+func (p RetryPolicy) BackoffAfter(attempts int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+// RetryPolicyFromYaml converts the retries block of digger.yml, jobs aren't retried when it is not set
+func RetryPolicyFromYaml(retries *RetryPolicyYaml) (RetryPolicy, error) {
+	if retries == nil {
+		return RetryPolicy{MaxAttempts: 1}, nil
+	}
+	policy := RetryPolicy{
+		MaxAttempts:       defaultRetryMaxAttempts,
+		Backoff:           defaultRetryBackoff,
+		RetryableFailures: FailureClasses,
+	}
+	if retries.MaxAttempts != nil {
+		policy.MaxAttempts = *retries.MaxAttempts
+	}
+	if retries.Backoff != nil {
+		backoff, err := time.ParseDuration(*retries.Backoff)
+		if err != nil {
+			return policy, fmt.Errorf("invalid retries backoff %q: %v", *retries.Backoff, err)
+		}
+		policy.Backoff = backoff
+	}
+	if retries.On != nil {
+		policy.RetryableFailures = retries.On
+	}
+	return policy, nil
+}
+
+func validateRetryPolicy(policy RetryPolicy) error {
+	if policy.MaxAttempts < 1 {
+		return fmt.Errorf("retries max_attempts must be at least 1, got %v", policy.MaxAttempts)
+	}
+	if policy.Backoff < 0 {
+		return fmt.Errorf("retries backoff can't be negative, got %v", policy.Backoff)
+	}
+	for _, class := range policy.RetryableFailures {
+		known := false
+		for _, c := range FailureClasses {
+			known = known || c == class
+		}
+		if !known {
+			return fmt.Errorf("unknown failure class %q in retries, expecting one of %v", class, FailureClasses)
+		}
+	}
+	return nil
+}
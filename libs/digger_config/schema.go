@@ -0,0 +1,294 @@
+package digger_config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:generate go run gen_schema.go
+
+// JsonSchemaId is the $id of the generated schema
+const JsonSchemaId = "https://digger.dev/schemas/digger.schema.json"
+
+// jsonSchemaProvider is implemented by yaml types with a custom UnmarshalYAML whose accepted
+// shape can't be derived from the struct fields
+type jsonSchemaProvider interface {
+	JsonSchema() map[string]interface{}
+}
+
+// deprecatedYamlKeys are no longer used but still accepted so that existing configs keep loading
+var deprecatedYamlKeys = map[string]bool{
+	"ProjectYaml.branch": true,
+}
+
+var yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// fieldSchemaOverrides describes fields that accept more than their go type
+var fieldSchemaOverrides = map[string]map[string]interface{}{
+	"DiggerConfigYaml.include": {
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	},
+}
+
+func (s StepYaml) JsonSchema() map[string]interface{} {
+	extraArgs := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	commandStep := func(action string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				action: map[string]interface{}{
+					"oneOf": []interface{}{
+						map[string]interface{}{"type": "null"},
+						map[string]interface{}{
+							"type":                 "object",
+							"properties":           map[string]interface{}{"extra_args": extraArgs},
+							"additionalProperties": false,
+						},
+					},
+				},
+				"extra_args": extraArgs,
+			},
+			"required":             []string{action},
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string", "enum": []string{"init", "plan", "apply"}},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"run":   map[string]interface{}{"type": "string"},
+					"shell": map[string]interface{}{"type": "string"},
+				},
+				"required":             []string{"run"},
+				"additionalProperties": false,
+			},
+			commandStep("init"),
+			commandStep("plan"),
+			commandStep("apply"),
+		},
+	}
+}
+
+// yamlFieldName returns the key a struct field is decoded from, the same way yaml.v3 does
+func yamlFieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return "-"
+	}
+	name := yamlTagName(field)
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func schemaDefinitionName(t reflect.Type) string {
+	return strings.TrimSuffix(t.Name(), "Yaml")
+}
+
+func typeJsonSchema(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if provider, ok := reflect.Zero(t).Interface().(jsonSchemaProvider); ok {
+		return provider.JsonSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeJsonSchema(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeJsonSchema(t.Elem(), definitions)}
+	case reflect.Struct:
+		name := schemaDefinitionName(t)
+		ref := map[string]interface{}{"$ref": "#/$defs/" + name}
+		if _, ok := definitions[name]; ok {
+			return ref
+		}
+		// registered before the fields are visited so that recursive types terminate
+		definitions[name] = nil
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := yamlFieldName(field)
+			if key == "-" {
+				continue
+			}
+			if override, ok := fieldSchemaOverrides[t.Name()+"."+key]; ok {
+				properties[key] = override
+				continue
+			}
+			properties[key] = typeJsonSchema(field.Type, definitions)
+		}
+		definitions[name] = map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		return ref
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// JsonSchema returns a JSON Schema of digger.yml generated from the yaml structs
+func JsonSchema() map[string]interface{} {
+	definitions := make(map[string]interface{})
+	root := typeJsonSchema(reflect.TypeOf(DiggerConfigYaml{}), definitions)
+	rootName := strings.TrimPrefix(root["$ref"].(string), "#/$defs/")
+	schema := map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     JsonSchemaId,
+		"title":   "digger.yml",
+		"$ref":    "#/$defs/" + rootName,
+		"$defs":   definitions,
+	}
+	return schema
+}
+
+// GenerateJsonSchema returns the indented JSON of JsonSchema
+func GenerateJsonSchema() ([]byte, error) {
+	data, err := json.MarshalIndent(JsonSchema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// levenshtein returns the edit distance of two keys, used to suggest the key that was meant
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func suggestKey(key string, known []string) string {
+	best := ""
+	bestDistance := 3
+	for _, k := range known {
+		if d := levenshtein(key, k); d < bestDistance {
+			best = k
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+func joinYamlPath(parent string, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// checkYamlKeys reports every mapping key of node that doesn't match a field of t, with the line and
+// column it was found at
+func checkYamlKeys(node *yaml.Node, t reflect.Type, yamlPath string) []error {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return checkYamlKeys(node.Content[0], t, yamlPath)
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// types with a node based UnmarshalYAML decide for themselves what they accept
+	if reflect.PointerTo(t).Implements(yamlUnmarshalerType) {
+		return nil
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		known := make([]string, 0)
+		for i := 0; i < t.NumField(); i++ {
+			key := yamlFieldName(t.Field(i))
+			if key == "-" {
+				continue
+			}
+			fields[key] = t.Field(i).Type
+			known = append(known, key)
+		}
+		sort.Strings(known)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode := node.Content[i]
+			if keyNode.Value == "<<" {
+				continue
+			}
+			if deprecatedYamlKeys[t.Name()+"."+keyNode.Value] {
+				log.Printf("line %v, column %v: key %q is deprecated and ignored", keyNode.Line, keyNode.Column, keyNode.Value)
+				continue
+			}
+			fieldType, ok := fields[keyNode.Value]
+			if !ok {
+				message := fmt.Sprintf("line %v, column %v: unknown key %q", keyNode.Line, keyNode.Column, keyNode.Value)
+				if yamlPath != "" {
+					message += " in " + yamlPath
+				}
+				if suggestion := suggestKey(keyNode.Value, known); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				errs = append(errs, errors.New(message))
+				continue
+			}
+			errs = append(errs, checkYamlKeys(node.Content[i+1], fieldType, joinYamlPath(yamlPath, keyNode.Value))...)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, checkYamlKeys(node.Content[i+1], t.Elem(), joinYamlPath(yamlPath, node.Content[i].Value))...)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			errs = append(errs, checkYamlKeys(item, t.Elem(), fmt.Sprintf("%v[%v]", yamlPath, i))...)
+		}
+	}
+	return errs
+}
@@ -19,6 +19,24 @@ type DiggerConfigYaml struct {
 	GenerateProjectsConfig     *GenerateProjectsConfigYaml  `yaml:"generate_projects"`
 	TraverseToNestedProjects   *bool                        `yaml:"traverse_to_nested_projects"`
 	MentionDriftedProjectsInPR *bool                        `yaml:"mention_drifted_projects_in_pr"`
+	// MaxParallelism is the number of independent projects the cli runs at the same time, defaults to 1
+	MaxParallelism *int `yaml:"max_parallelism,omitempty"`
+	// DetectModuleDependencies adds the local modules sourced by a project to its include patterns, defaults to true
+	DetectModuleDependencies *bool `yaml:"detect_module_dependencies,omitempty"`
+	// Include lists other yaml files, relative to the root of the repository, whose projects and workflows
+	// are merged into this config
+	Include []string `yaml:"include,omitempty"`
+	// Defaults are applied to every project for the keys the project doesn't set itself
+	Defaults *ProjectYaml `yaml:"defaults,omitempty"`
+	// Retries makes the orchestrator backend run failed jobs again when they failed for a transient reason
+	Retries *RetryPolicyYaml `yaml:"retries,omitempty"`
+}
+
+type RetryPolicyYaml struct {
+	MaxAttempts *int    `yaml:"max_attempts,omitempty"`
+	Backoff     *string `yaml:"backoff,omitempty"`
+	// On lists the failure classes that are retried, all of them by default
+	On []string `yaml:"on,omitempty"`
 }
 
 type DependencyConfigurationYaml struct {
@@ -39,6 +57,20 @@ type ProjectYaml struct {
 	DriftDetection     *bool                       `yaml:"drift_detection,omitempty"`
 	AwsRoleToAssume    *AssumeRoleForProjectConfig `yaml:"aws_role_to_assume,omitempty"`
 	Generated          bool                        `yaml:"generated"`
+	Labels             map[string]string           `yaml:"labels,omitempty"`
+	Environment        string                      `yaml:"environment,omitempty"`
+	Owners             []string                    `yaml:"owners,omitempty"`
+	// RequireOwnerApproval defaults to true when owners are set
+	RequireOwnerApproval *bool `yaml:"require_owner_approval,omitempty"`
+	// Inputs maps terraform variable names to outputs of projects this project depends on, as "project.output"
+	Inputs map[string]string `yaml:"inputs,omitempty"`
+	// Extends is the name of a project whose values are used for the keys this project doesn't set
+	Extends string `yaml:"extends,omitempty"`
+	// Abstract projects are only used as a base for other projects and are not projects themselves
+	Abstract bool `yaml:"abstract,omitempty"`
+
+	// keys set explicitly in the yaml, used to merge defaults and extended projects
+	setKeys map[string]bool
 }
 
 type WorkflowYaml struct {
@@ -46,6 +78,11 @@ type WorkflowYaml struct {
 	Plan          *StageYaml                 `yaml:"plan,omitempty"`
 	Apply         *StageYaml                 `yaml:"apply,omitempty"`
 	Configuration *WorkflowConfigurationYaml `yaml:"workflow_configuration"`
+	// Extends is the name of a workflow whose values are used for the keys this workflow doesn't set
+	Extends string `yaml:"extends,omitempty"`
+
+	// keys set explicitly in the yaml, used to merge extended workflows
+	setKeys map[string]bool
 }
 
 type WorkflowConfigurationYaml struct {
@@ -101,6 +138,11 @@ type BlockYaml struct {
 	Workflow        string                      `yaml:"workflow"`
 	WorkflowFile    string                      `yaml:"workflow_file"`
 	AwsRoleToAssume *AssumeRoleForProjectConfig `yaml:"aws_role_to_assume,omitempty"`
+
+	// metadata copied onto every project generated from the block
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Environment string            `yaml:"environment,omitempty"`
+	Owners      []string          `yaml:"owners,omitempty"`
 }
 
 type AssumeRoleForProjectConfig struct {
@@ -144,6 +186,9 @@ type TerragruntParsingConfig struct {
 	ExecutionOrderGroups           *bool    `yaml:"executionOrderGroups"`
 	WorkflowFile                   string   `yaml:"workflow_file"`
 	AwsRoleToAssume         *AssumeRoleForProjectConfig `yaml:"aws_role_to_assume,omitempty"`
+	Labels                  map[string]string           `yaml:"labels,omitempty"`
+	Environment             string                      `yaml:"environment,omitempty"`
+	Owners                  []string                    `yaml:"owners,omitempty"`
 }
 
 func (p *ProjectYaml) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -156,10 +201,36 @@ func (p *ProjectYaml) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := unmarshal(&raw); err != nil {
 		return err
 	}
+	setKeys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
 	*p = ProjectYaml(raw)
+	p.setKeys = setKeys
 	return nil
 }
 
+// OwnerApprovalRequired returns whether apply waits for an owner approval, which is the default when owners are set
+func (p *ProjectYaml) OwnerApprovalRequired() bool {
+	if p.RequireOwnerApproval != nil {
+		return *p.RequireOwnerApproval
+	}
+	return len(p.Owners) > 0
+}
+
+// yamlKeys returns the keys of the mapping being unmarshalled
+func yamlKeys(unmarshal func(interface{}) error) (map[string]bool, error) {
+	var values map[string]interface{}
+	if err := unmarshal(&values); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(values))
+	for k := range values {
+		keys[k] = true
+	}
+	return keys, nil
+}
+
 func (w *WorkflowYaml) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type rawWorkflow WorkflowYaml
 	raw := rawWorkflow{
@@ -200,7 +271,12 @@ func (w *WorkflowYaml) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if err := validateWorkflowConfigurationYaml(raw.Configuration); err != nil {
 		return err
 	}
+	setKeys, err := yamlKeys(unmarshal)
+	if err != nil {
+		return err
+	}
 	*w = WorkflowYaml(raw)
+	w.setKeys = setKeys
 	return nil
 }
 
@@ -118,6 +118,8 @@ type DiggerExecutorResult struct {
 
 type DiggerExecutorApplyResult struct {
 	ApplySummary terraform_utils.TerraformSummary
+	// Outputs are the non sensitive terraform outputs of the project after the apply
+	Outputs map[string]string
 }
 
 type DiggerExecutorPlanResult struct {
@@ -223,7 +225,7 @@ func (d DiggerExecutor) Plan() (*terraform_utils.TerraformSummary, bool, bool, s
 			_, stderr, err := d.TerraformExecutor.Init(step.ExtraArgs, d.StateEnvVars)
 			if err != nil {
 				reportError(d.Reporter, stderr)
-				return nil, false, false, "", "", fmt.Errorf("error running init: %v", err)
+				return nil, false, false, "", "", fmt.Errorf("error running init: %v\n%v", err, stderr)
 			}
 		}
 		if step.Action == "plan" {
@@ -231,7 +233,8 @@ func (d DiggerExecutor) Plan() (*terraform_utils.TerraformSummary, bool, bool, s
 			planArgs = append(planArgs, step.ExtraArgs...)
 			_, stdout, stderr, err := d.TerraformExecutor.Plan(planArgs, d.CommandEnvVars)
 			if err != nil {
-				return nil, false, false, "", "", fmt.Errorf("error executing plan: %v", err)
+				// stderr is kept so that the backend can tell transient failures apart
+				return nil, false, false, "", "", fmt.Errorf("error executing plan: %v\n%v", err, stderr)
 			}
 			showArgs := []string{"-no-color", "-json", d.PlanPathProvider.LocalPlanFilePath()}
 			terraformPlanOutput, _, _ = d.TerraformExecutor.Show(showArgs, d.CommandEnvVars)
@@ -279,9 +282,9 @@ func (d DiggerExecutor) Plan() (*terraform_utils.TerraformSummary, bool, bool, s
 			}
 			commands = append(commands, step.Value)
 			log.Printf("Running %v for **%v**\n", step.Value, d.ProjectNamespace+"#"+d.ProjectName)
-			_, _, err := d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
+			_, stderr, err := d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
 			if err != nil {
-				return nil, false, false, "", "", fmt.Errorf("error running command: %v", err)
+				return nil, false, false, "", "", fmt.Errorf("error running command: %v\n%v", err, stderr)
 			}
 		}
 	}
@@ -335,7 +338,7 @@ func (d DiggerExecutor) Apply() (*terraform_utils.TerraformSummary, bool, string
 			stdout, stderr, err := d.TerraformExecutor.Init(step.ExtraArgs, d.StateEnvVars)
 			if err != nil {
 				reportTerraformError(d.Reporter, stderr)
-				return nil, false, stdout, fmt.Errorf("error running init: %v", err)
+				return nil, false, stdout, fmt.Errorf("error running init: %v\n%v", err, stderr)
 			}
 		}
 		if step.Action == "apply" {
@@ -347,7 +350,7 @@ func (d DiggerExecutor) Apply() (*terraform_utils.TerraformSummary, bool, string
 			reportTerraformApplyOutput(d.Reporter, d.projectId(), applyOutput)
 			if err != nil {
 				reportApplyError(d.Reporter, err)
-				return nil, false, stdout, fmt.Errorf("error executing apply: %v", err)
+				return nil, false, stdout, fmt.Errorf("error executing apply: %v\n%v", err, stderr)
 			}
 
 			summary, err = terraform_utils.GetSummaryFromTerraformApplyOutput(stdout)
@@ -364,7 +367,7 @@ func (d DiggerExecutor) Apply() (*terraform_utils.TerraformSummary, bool, string
 			log.Printf("Running %v for **%v**\n", step.Value, d.ProjectNamespace+"#"+d.ProjectName)
 			_, stderr, err := d.CommandRunner.Run(d.ProjectPath, step.Shell, commands, d.RunEnvVars)
 			if err != nil {
-				return nil, false, stderr, fmt.Errorf("error running command: %v", err)
+				return nil, false, stderr, fmt.Errorf("error running command: %v\n%v", err, stderr)
 			}
 		}
 	}
@@ -372,6 +375,15 @@ func (d DiggerExecutor) Apply() (*terraform_utils.TerraformSummary, bool, string
 	return &summary, true, applyOutput, nil
 }
 
+// Outputs returns the non sensitive outputs of the project, used as inputs of the projects depending on it
+func (d DiggerExecutor) Outputs() (map[string]string, error) {
+	stdout, _, err := d.TerraformExecutor.Output([]string{}, d.CommandEnvVars)
+	if err != nil {
+		return nil, fmt.Errorf("error running output: %v", err)
+	}
+	return terraform_utils.ParseTerraformOutputJson(stdout)
+}
+
 func reportApplyError(r reporting.Reporter, err error) {
 	if r.SupportsMarkdown() {
 		_, _, commentErr := r.Report(err.Error(), utils.AsCollapsibleComment("Error during applying.", false))
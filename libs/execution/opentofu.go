@@ -13,6 +13,10 @@ import (
 type OpenTofu struct {
 	WorkingDir string
 	Workspace  string
+	// OutputPrefix is prepended to every line of output printed by the commands
+	OutputPrefix string
+	// LogWriter receives a copy of the output printed by the commands when set, e.g. to stream it to the backend
+	LogWriter io.Writer
 }
 
 func (tf OpenTofu) Init(params []string, envs map[string]string) (string, string, error) {
@@ -62,6 +66,15 @@ func (tf OpenTofu) Show(params []string, envs map[string]string) (string, string
 	return stdout, stderr, nil
 }
 
+func (tf OpenTofu) Output(params []string, envs map[string]string) (string, string, error) {
+	params = append(params, "-json")
+	stdout, stderr, _, err := tf.runOpentofuCommand("output", false, envs, params...)
+	if err != nil {
+		return "", "", err
+	}
+	return stdout, stderr, nil
+}
+
 func (tf OpenTofu) Destroy(params []string, envs map[string]string) (string, string, error) {
 	if tf.Workspace != "default" {
 		err := tf.switchToWorkspace(envs)
@@ -111,8 +124,9 @@ func (tf OpenTofu) runOpentofuCommand(command string, printOutputToStdout bool,
 	var mwout, mwerr io.Writer
 	var stdout, stderr bytes.Buffer
 	if printOutputToStdout {
-		mwout = io.MultiWriter(os.Stdout, &stdout)
-		mwerr = io.MultiWriter(os.Stderr, &stderr)
+		printOut, printErr := outputWriters(tf.OutputPrefix, tf.LogWriter)
+		mwout = io.MultiWriter(printOut, &stdout)
+		mwerr = io.MultiWriter(printErr, &stderr)
 	} else {
 		mwout = io.Writer(&stdout)
 		mwerr = io.Writer(&stderr)
@@ -0,0 +1,57 @@
+package execution
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// prefixedWriter prefixes every line written to it, so that the output of projects running in parallel
+// can be told apart
+type prefixedWriter struct {
+	mu          sync.Mutex
+	w           io.Writer
+	prefix      []byte
+	atLineStart bool
+}
+
+func NewPrefixedWriter(w io.Writer, prefix string) io.Writer {
+	return &prefixedWriter{w: w, prefix: []byte(prefix), atLineStart: true}
+}
+
+func (p *prefixedWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if p.atLineStart {
+			out.Write(p.prefix)
+		}
+		out.Write(line)
+		p.atLineStart = line[len(line)-1] == '\n'
+	}
+	// written at once so that lines of different projects don't interleave
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// outputWriters returns the writers the output of commands is printed to, prefixed when prefix is set. Both
+// stdout and stderr are also copied to logWriter when it is set.
+func outputWriters(prefix string, logWriter io.Writer) (io.Writer, io.Writer) {
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if logWriter != nil {
+		stdout = io.MultiWriter(os.Stdout, logWriter)
+		stderr = io.MultiWriter(os.Stderr, logWriter)
+	}
+	if prefix == "" {
+		return stdout, stderr
+	}
+	return NewPrefixedWriter(stdout, prefix), NewPrefixedWriter(stderr, prefix)
+}
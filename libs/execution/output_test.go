@@ -0,0 +1,16 @@
+package execution
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixedWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewPrefixedWriter(&out, "[prod] ")
+	w.Write([]byte("Initializing...\nTerraform has"))
+	w.Write([]byte(" been initialized\n\n"))
+	assert.Equal(t, "[prod] Initializing...\n[prod] Terraform has been initialized\n[prod] \n", out.String())
+}
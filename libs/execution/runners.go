@@ -14,6 +14,10 @@ type CommandRun interface {
 }
 
 type CommandRunner struct {
+	// OutputPrefix is prepended to every line of output printed by the commands
+	OutputPrefix string
+	// LogWriter receives a copy of the output printed by the commands when set, e.g. to stream it to the backend
+	LogWriter io.Writer
 }
 
 func (c CommandRunner) Run(workingDir string, shell string, commands []string, envs map[string]string) (string, string, error) {
@@ -47,8 +51,9 @@ func (c CommandRunner) Run(workingDir string, shell string, commands []string, e
 	cmd.Env = env
 
 	var stdout, stderr bytes.Buffer
-	mwout := io.MultiWriter(os.Stdout, &stdout)
-	mwerr := io.MultiWriter(os.Stderr, &stderr)
+	printOut, printErr := outputWriters(c.OutputPrefix, c.LogWriter)
+	mwout := io.MultiWriter(printOut, &stdout)
+	mwerr := io.MultiWriter(printErr, &stderr)
 	cmd.Stdout = mwout
 	cmd.Stderr = mwerr
 	err = cmd.Run()
@@ -12,6 +12,10 @@ import (
 
 type Terragrunt struct {
 	WorkingDir string
+	// OutputPrefix is prepended to every line of output printed by the commands
+	OutputPrefix string
+	// LogWriter receives a copy of the output printed by the commands when set, e.g. to stream it to the backend
+	LogWriter io.Writer
 }
 
 func (terragrunt Terragrunt) Init(params []string, envs map[string]string) (string, string, error) {
@@ -68,6 +72,16 @@ func (terragrunt Terragrunt) Show(params []string, envs map[string]string) (stri
 	return stdout, stderr, err
 }
 
+func (terragrunt Terragrunt) Output(params []string, envs map[string]string) (string, string, error) {
+	params = append(params, "-json")
+	stdout, stderr, exitCode, err := terragrunt.runTerragruntCommand("output", false, envs, params...)
+	if exitCode != 0 {
+		logCommandFail(exitCode, err)
+	}
+
+	return stdout, stderr, err
+}
+
 func (terragrunt Terragrunt) runTerragruntCommand(command string, printOutputToStdout bool, envs map[string]string, arg ...string) (stdOut string, stdErr string, exitCode int, err error) {
 	args := []string{command}
 	args = append(args, arg...)
@@ -85,8 +99,9 @@ func (terragrunt Terragrunt) runTerragruntCommand(command string, printOutputToS
 	var mwout, mwerr io.Writer
 	var stdout, stderr bytes.Buffer
 	if printOutputToStdout {
-		mwout = io.MultiWriter(os.Stdout, &stdout)
-		mwerr = io.MultiWriter(os.Stderr, &stderr)
+		printOut, printErr := outputWriters(terragrunt.OutputPrefix, terragrunt.LogWriter)
+		mwout = io.MultiWriter(printOut, &stdout)
+		mwerr = io.MultiWriter(printErr, &stderr)
 	} else {
 		mwout = io.Writer(&stdout)
 		mwerr = io.Writer(&stderr)
@@ -3,6 +3,7 @@ package execution
 import (
 	"bytes"
 	"fmt"
+	"github.com/diggerhq/digger/libs/redact"
 	"io"
 	"log"
 	"os"
@@ -17,11 +18,16 @@ type TerraformExecutor interface {
 	Destroy([]string, map[string]string) (string, string, error)
 	Plan([]string, map[string]string) (bool, string, string, error)
 	Show([]string, map[string]string) (string, string, error)
+	Output([]string, map[string]string) (string, string, error)
 }
 
 type Terraform struct {
 	WorkingDir string
 	Workspace  string
+	// OutputPrefix is prepended to every line of output printed by the commands
+	OutputPrefix string
+	// LogWriter receives a copy of the output printed by the commands when set, e.g. to stream it to the backend
+	LogWriter io.Writer
 }
 
 func (tf Terraform) Init(params []string, envs map[string]string) (string, string, error) {
@@ -93,8 +99,9 @@ func (tf Terraform) runTerraformCommand(command string, printOutputToStdout bool
 	var mwout, mwerr io.Writer
 	var stdout, stderr bytes.Buffer
 	if printOutputToStdout {
-		mwout = io.MultiWriter(os.Stdout, &stdout)
-		mwerr = io.MultiWriter(os.Stderr, &stderr)
+		printOut, printErr := outputWriters(tf.OutputPrefix, tf.LogWriter)
+		mwout = io.MultiWriter(printOut, &stdout)
+		mwerr = io.MultiWriter(printErr, &stderr)
 	} else {
 		mwout = io.Writer(&stdout)
 		mwerr = io.Writer(&stderr)
@@ -152,6 +159,15 @@ func (tf Terraform) Show(params []string, envs map[string]string) (string, strin
 	return stdout, stderr, nil
 }
 
+func (tf Terraform) Output(params []string, envs map[string]string) (string, string, error) {
+	params = append(params, "-json")
+	stdout, stderr, _, err := tf.runTerraformCommand("output", false, envs, params...)
+	if err != nil {
+		return "", "", err
+	}
+	return stdout, stderr, nil
+}
+
 func RedactSecret(s string) string {
 	exps := []*regexp.Regexp{
 		regexp.MustCompile(`\-backend\-config\=access\_key\=(.*)`),
@@ -164,7 +180,7 @@ func RedactSecret(s string) string {
 			s = strings.ReplaceAll(s, x[1], "<REDACTED>")
 		}
 	}
-	return s
+	return redact.String(s)
 }
 
 func RedactSecrets(secrets []string) []string {
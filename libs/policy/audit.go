@@ -0,0 +1,78 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/diggerhq/digger/libs/ci"
+)
+
+// policyEnforcementRule reads the `enforcement` rule of the digger package, policies without it are enforced
+func policyEnforcementRule(ctx context.Context, policy string) string {
+	query, err := prepareQuery(ctx, "data.digger.enforcement", policy)
+	if err != nil {
+		log.Printf("could not prepare enforcement query, enforcing policy: %v", err)
+		return EnforcementModeEnforce
+	}
+	results, err := query.Eval(ctx)
+	if err != nil || len(results) == 0 || len(results[0].Expressions) == 0 {
+		return EnforcementModeEnforce
+	}
+	if mode, ok := results[0].Expressions[0].Value.(string); ok && mode == EnforcementModeAudit {
+		return EnforcementModeAudit
+	}
+	return EnforcementModeEnforce
+}
+
+func (p DiggerPolicyChecker) enforcementMode(ctx context.Context, policyType string, policy string, SCMOrganisation string, SCMrepository string, projectName string) string {
+	if enforcementProvider, ok := p.PolicyProvider.(EnforcementProvider); ok {
+		if enforcementProvider.GetEnforcement(policyType, SCMOrganisation, SCMrepository, projectName) == EnforcementModeAudit {
+			return EnforcementModeAudit
+		}
+	}
+	return policyEnforcementRule(ctx, policy)
+}
+
+func (p DiggerPolicyChecker) recordDecision(decision Decision) {
+	log.Printf("AUDIT: %v policy decision for project %v (command: %v, requested by: %v): allowed=%v violations=%v",
+		decision.PolicyType, decision.ProjectName, decision.Command, decision.RequestedBy, decision.Allowed, decision.Violations)
+	recorder, ok := p.PolicyProvider.(DecisionRecorder)
+	if !ok {
+		return
+	}
+	err := recorder.RecordDecision(decision)
+	if err != nil {
+		log.Printf("failed to record %v policy decision: %v", decision.PolicyType, err)
+	}
+}
+
+// reportEvaluation sends the outcome and the evaluation time of a policy in enforce mode to the backend for its
+// metrics, unlike the decisions of policies in audit mode they are not stored
+func (p DiggerPolicyChecker) reportEvaluation(decision Decision) {
+	recorder, ok := p.PolicyProvider.(DecisionRecorder)
+	if !ok {
+		return
+	}
+	err := recorder.RecordDecision(decision)
+	if err != nil {
+		log.Printf("failed to report %v policy evaluation: %v", decision.PolicyType, err)
+	}
+}
+
+// publishAuditNote lets the PR know that a policy in audit mode would have blocked the operation
+func publishAuditNote(prService *ci.PullRequestService, prNumber *int, decision Decision) {
+	if prService == nil || prNumber == nil {
+		return
+	}
+	note := fmt.Sprintf(":information_source: The %v policy is in audit mode and would have denied `%v` on project %v for %v, it is not enforced.",
+		decision.PolicyType, decision.Command, decision.ProjectName, decision.RequestedBy)
+	if len(decision.Violations) > 0 {
+		note = note + "\n\nPlan policy violations:\n" + strings.Join(decision.Violations, "\n")
+	}
+	_, err := (*prService).PublishComment(*prNumber, note)
+	if err != nil {
+		log.Printf("failed to publish audit note: %v", err)
+	}
+}
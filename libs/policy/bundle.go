@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// gzipMagic is the header of a gzip stream, OPA bundles are always gzipped tarballs
+const gzipMagic = "\x1f\x8b"
+
+// IsBundle returns true if the policy content is an OPA bundle (tar.gz with rego modules and data.json)
+// rather than a single rego module
+func IsBundle(policy string) bool {
+	return strings.HasPrefix(policy, gzipMagic)
+}
+
+// LoadBundle reads and parses an OPA bundle, bundles are not signed so verification is skipped
+func LoadBundle(contents []byte) (*bundle.Bundle, error) {
+	b, err := bundle.NewReader(bytes.NewReader(contents)).WithSkipBundleVerification(true).Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read policy bundle: %v", err)
+	}
+	if len(b.Modules) == 0 {
+		return nil, fmt.Errorf("policy bundle does not contain any rego modules")
+	}
+	return &b, nil
+}
+
+// RenderBundle returns a readable representation of a bundle (modules sorted by path followed by data)
+// that is used when diffing policy versions
+func RenderBundle(b *bundle.Bundle) string {
+	modules := make([]bundle.ModuleFile, len(b.Modules))
+	copy(modules, b.Modules)
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+
+	var sb strings.Builder
+	for _, m := range modules {
+		sb.WriteString(fmt.Sprintf("# %v\n", m.Path))
+		sb.Write(m.Raw)
+		if !bytes.HasSuffix(m.Raw, []byte("\n")) {
+			sb.WriteString("\n")
+		}
+	}
+	if len(b.Data) > 0 {
+		data, err := json.MarshalIndent(b.Data, "", "  ")
+		if err == nil {
+			sb.WriteString("# data.json\n")
+			sb.Write(data)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// prepareQuery prepares a rego query against either a single module or all modules and data of a bundle
+func prepareQuery(ctx context.Context, query string, policy string) (rego.PreparedEvalQuery, error) {
+	if !IsBundle(policy) {
+		return rego.New(
+			rego.Query(query),
+			rego.Module("digger", policy),
+		).PrepareForEval(ctx)
+	}
+
+	b, err := LoadBundle([]byte(policy))
+	if err != nil {
+		return rego.PreparedEvalQuery{}, err
+	}
+	return rego.New(
+		rego.Query(query),
+		rego.ParsedBundle("digger", b),
+	).PrepareForEval(ctx)
+}
@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+type cachedPolicy struct {
+	ETag    string
+	Content string
+}
+
+// PolicyCache keeps policies fetched from the backend keyed by request url, cached entries are
+// revalidated with If-None-Match so unchanged policies are not downloaded again
+type PolicyCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedPolicy
+	// enforcement modes of the policies returned for a policy type and project
+	enforcements map[string]string
+}
+
+// DefaultPolicyCache is shared by all http policy providers within a single cli run
+var DefaultPolicyCache = NewPolicyCache()
+
+func NewPolicyCache() *PolicyCache {
+	return &PolicyCache{entries: make(map[string]cachedPolicy), enforcements: make(map[string]string)}
+}
+
+func (c *PolicyCache) Get(key string) (cachedPolicy, bool) {
+	if c == nil {
+		return cachedPolicy{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+func (c *PolicyCache) Set(key string, etag string, content string) {
+	if c == nil || etag == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedPolicy{ETag: etag, Content: content}
+}
+
+func (c *PolicyCache) GetEnforcement(key string) string {
+	if c == nil {
+		return EnforcementModeEnforce
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mode, ok := c.enforcements[key]; ok {
+		return mode
+	}
+	return EnforcementModeEnforce
+}
+
+func (c *PolicyCache) SetEnforcement(key string, mode string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enforcements[key] = mode
+}
+
+// PolicyVersionsFromEnv reads pinned policy versions, for example DIGGER_PLAN_POLICY_VERSION=3
+// will make the provider always fetch version 3 of the plan policy
+func PolicyVersionsFromEnv() map[string]string {
+	versions := make(map[string]string)
+	for _, policyType := range []string{AccessPolicyType, PlanPolicyType, DriftPolicyType} {
+		envName := fmt.Sprintf("DIGGER_%v_POLICY_VERSION", strings.ToUpper(policyType))
+		if version := os.Getenv(envName); version != "" {
+			versions[policyType] = version
+		}
+	}
+	return versions
+}
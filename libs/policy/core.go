@@ -4,6 +4,60 @@ import (
 	"github.com/diggerhq/digger/libs/ci"
 )
 
+const (
+	AccessPolicyType = "access"
+	PlanPolicyType   = "plan"
+	DriftPolicyType  = "drift"
+)
+
+const (
+	// EnforcementModeEnforce blocks operations that violate the policy
+	EnforcementModeEnforce = "enforce"
+	// EnforcementModeAudit evaluates and records decisions without ever blocking
+	EnforcementModeAudit = "audit"
+)
+
+// ProjectMetadata is the environment, labels and owners declared on a project in digger.yml,
+// policies receive it as input.environment, input.labels and input.owners
+type ProjectMetadata struct {
+	Environment string
+	Labels      map[string]string
+	Owners      []string
+}
+
+func (m ProjectMetadata) addToInput(input map[string]interface{}) {
+	labels := m.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	owners := m.Owners
+	if owners == nil {
+		owners = []string{}
+	}
+	input["environment"] = m.Environment
+	input["labels"] = labels
+	input["owners"] = owners
+}
+
+// EnforcementHeader is set by the backend on policy responses with the enforcement mode of the policy
+const EnforcementHeader = "X-Digger-Policy-Enforcement"
+
+// Decision is the outcome of a policy evaluation, the backend stores the decisions of policies in audit mode and
+// measures the evaluation time of all of them
+type Decision struct {
+	PolicyType   string   `json:"policy_type"`
+	Enforcement  string   `json:"enforcement"`
+	Organisation string   `json:"organisation"`
+	Repository   string   `json:"repository"`
+	ProjectName  string   `json:"project_name"`
+	Command      string   `json:"command"`
+	RequestedBy  string   `json:"requested_by"`
+	Allowed      bool     `json:"allowed"`
+	Violations   []string `json:"violations"`
+	// time taken to evaluate the policy
+	EvaluationSeconds float64 `json:"evaluation_seconds,omitempty"`
+}
+
 type Provider interface {
 	GetAccessPolicy(organisation string, repository string, projectname string, projectDir string) (string, error)
 	GetPlanPolicy(organisation string, repository string, projectname string, projectDir string) (string, error)
@@ -11,11 +65,23 @@ type Provider interface {
 	GetOrganisation() string // TODO: remove this method from here since out of place
 }
 
+// EnforcementProvider is implemented by providers that store the enforcement mode alongside the policy,
+// for other providers the mode is read from an `enforcement` rule in the digger package of the policy
+type EnforcementProvider interface {
+	GetEnforcement(policyType string, organisation string, repository string, projectname string) string
+}
+
+// DecisionRecorder is implemented by providers that can report decisions to the backend
+type DecisionRecorder interface {
+	RecordDecision(decision Decision) error
+}
+
 type Checker interface {
 	// TODO refactor arguments - use AccessPolicyContext
-	CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error)
-	CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error)
+	CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectMetadata ProjectMetadata, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error)
+	CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, projectMetadata ProjectMetadata, planOutput string) (bool, []Violation, error)
 	CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectname string) (bool, error)
+	CheckDriftPlanPolicy(SCMOrganisation string, SCMrepository string, projectName string, projectMetadata ProjectMetadata, planOutput string) ([]Violation, error)
 }
 
 type PolicyCheckerProvider interface {
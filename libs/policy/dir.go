@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirPolicyProvider loads policies from a local directory laid out like a conftest policy directory,
+// each policy type has its own subdirectory (access/, plan/, drift/) with any number of rego modules
+// in any namespaces and optional data.json files. The modules are packed into a bundle in memory
+type DirPolicyProvider struct {
+	PolicyDir          string
+	DiggerOrganisation string
+}
+
+func (p DirPolicyProvider) GetAccessPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	policy, err := p.loadPolicy(AccessPolicyType)
+	if err != nil {
+		return "", err
+	}
+	if policy == "" {
+		return DefaultAccessPolicy, nil
+	}
+	return policy, nil
+}
+
+func (p DirPolicyProvider) GetPlanPolicy(organisation string, repo string, projectName string, projectDir string) (string, error) {
+	return p.loadPolicy(PlanPolicyType)
+}
+
+func (p DirPolicyProvider) GetDriftPolicy() (string, error) {
+	return p.loadPolicy(DriftPolicyType)
+}
+
+func (p DirPolicyProvider) GetOrganisation() string {
+	return p.DiggerOrganisation
+}
+
+// loadPolicy returns the modules of the policy type subdirectory as a bundle, or empty string if there are none
+func (p DirPolicyProvider) loadPolicy(policyType string) (string, error) {
+	root := filepath.Join(p.PolicyDir, policyType)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return "", nil
+	}
+
+	files := make(map[string][]byte)
+	hasModules := false
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		isModule := strings.HasSuffix(name, ".rego") && !strings.HasSuffix(name, "_test.rego")
+		if !isModule && name != "data.json" {
+			return nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = contents
+		hasModules = hasModules || isModule
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not read %v policies from %v: %v", policyType, root, err)
+	}
+	if !hasModules {
+		return "", nil
+	}
+
+	bundle, err := packBundle(files)
+	if err != nil {
+		return "", fmt.Errorf("could not pack %v policies from %v: %v", policyType, root, err)
+	}
+	return bundle, nil
+}
+
+func packBundle(files map[string][]byte) (string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		contents := files[name]
+		err := tw.WriteHeader(&tar.Header{
+			Name:     "/" + name,
+			Mode:     0600,
+			Typeflag: tar.TypeReg,
+			Size:     int64(len(contents)),
+		})
+		if err != nil {
+			return "", err
+		}
+		if _, err := tw.Write(contents); err != nil {
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return "", err
+	}
+	if err := gw.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// PolicyDirFromEnv returns the local policy directory configured with DIGGER_POLICY_DIR, when set policies
+// are loaded from that directory instead of the backend
+func PolicyDirFromEnv() string {
+	return os.Getenv("DIGGER_POLICY_DIR")
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"time"
 )
 
 const DefaultAccessPolicy = `
@@ -25,16 +27,20 @@ type DiggerHttpPolicyProvider struct {
 	DiggerOrganisation string
 	AuthToken          string
 	HttpClient         *http.Client
+	// PolicyVersions pins a policy type (access, plan, drift) to a specific stored version
+	PolicyVersions map[string]string
+	// Cache is used to revalidate previously fetched policies by ETag, caching is disabled if nil
+	Cache *PolicyCache
 }
 
 type NoOpPolicyChecker struct {
 }
 
-func (p NoOpPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+func (p NoOpPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectMetadata ProjectMetadata, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
 	return true, nil
 }
 
-func (p NoOpPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error) {
+func (p NoOpPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, projectMetadata ProjectMetadata, planOutput string) (bool, []Violation, error) {
 	return true, nil, nil
 }
 
@@ -42,137 +48,74 @@ func (p NoOpPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepositor
 	return true, nil
 }
 
-func getAccessPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
-	organisation := p.DiggerOrganisation
-	u, err := url.Parse(p.DiggerHost)
-	if err != nil {
-		log.Fatalf("Not able to parse digger cloud url: %v", err)
-	}
-	u.Path = "/orgs/" + organisation + "/access-policy"
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return "", nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
-
-	resp, err := p.HttpClient.Do(req)
-	if err != nil {
-		return "", nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", resp, nil
-	}
-	return string(body), resp, nil
+func (p NoOpPolicyChecker) CheckDriftPlanPolicy(SCMOrganisation string, SCMrepository string, projectName string, projectMetadata ProjectMetadata, planOutput string) ([]Violation, error) {
+	return nil, nil
 }
 
-func getPlanPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
-	organisation := p.DiggerOrganisation
+func fetchPolicy(p *DiggerHttpPolicyProvider, policyPath string, policyType string) (string, *http.Response, error) {
 	u, err := url.Parse(p.DiggerHost)
 	if err != nil {
 		log.Fatalf("Not able to parse digger cloud url: %v", err)
 	}
-	u.Path = "/orgs/" + organisation + "/plan-policy"
+	u.Path = policyPath
+	if version, ok := p.PolicyVersions[policyType]; ok {
+		query := u.Query()
+		query.Set("version", version)
+		u.RawQuery = query.Encode()
+	}
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return "", nil, err
 	}
 	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
 
+	cached, isCached := p.Cache.Get(u.String())
+	if isCached {
+		req.Header.Add("If-None-Match", cached.ETag)
+	}
+
 	resp, err := p.HttpClient.Do(req)
 	if err != nil {
-		return "", nil, err
+		return "", resp, err
 	}
 	defer resp.Body.Close()
 
+	if isCached && resp.StatusCode == http.StatusNotModified {
+		log.Printf("%v policy not modified (etag %v), using cached version", policyType, cached.ETag)
+		// callers only deal with found / not found so the cached policy is reported as a regular hit
+		resp.StatusCode = http.StatusOK
+		return cached.Content, resp, nil
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", resp, nil
 	}
+	if resp.StatusCode == http.StatusOK {
+		p.Cache.Set(u.String(), resp.Header.Get("ETag"), string(body))
+	}
 	return string(body), resp, nil
 }
 
-func getDriftPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
-	organisation := p.DiggerOrganisation
-	u, err := url.Parse(p.DiggerHost)
-	if err != nil {
-		log.Fatalf("Not able to parse digger cloud url: %v", err)
-	}
-	u.Path = "/orgs/" + organisation + "/drift-policy"
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return "", nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
+func getAccessPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
+	return fetchPolicy(p, "/orgs/"+p.DiggerOrganisation+"/access-policy", AccessPolicyType)
+}
 
-	resp, err := p.HttpClient.Do(req)
-	if err != nil {
-		return "", nil, err
-	}
-	defer resp.Body.Close()
+func getPlanPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
+	return fetchPolicy(p, "/orgs/"+p.DiggerOrganisation+"/plan-policy", PlanPolicyType)
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", resp, nil
-	}
-	return string(body), resp, nil
+func getDriftPolicyForOrganisation(p *DiggerHttpPolicyProvider) (string, *http.Response, error) {
+	return fetchPolicy(p, "/orgs/"+p.DiggerOrganisation+"/drift-policy", DriftPolicyType)
 }
 
 func getAccessPolicyForNamespace(p *DiggerHttpPolicyProvider, namespace string, projectName string) (string, *http.Response, error) {
 	// fetch RBAC policies for project from Digger API
-	u, err := url.Parse(p.DiggerHost)
-	if err != nil {
-		log.Fatalf("Not able to parse digger cloud url: %v", err)
-	}
-	u.Path = "/repos/" + namespace + "/projects/" + projectName + "/access-policy"
-	req, err := http.NewRequest("GET", u.String(), nil)
-
-	if err != nil {
-		return "", nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
-
-	resp, err := p.HttpClient.Do(req)
-	if err != nil {
-		return "", resp, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", resp, nil
-	}
-	return string(body), resp, nil
-
+	return fetchPolicy(p, "/repos/"+namespace+"/projects/"+projectName+"/access-policy", AccessPolicyType)
 }
 
 func getPlanPolicyForNamespace(p *DiggerHttpPolicyProvider, namespace string, projectName string) (string, *http.Response, error) {
-	u, err := url.Parse(p.DiggerHost)
-	if err != nil {
-		log.Fatalf("Not able to parse digger cloud url: %v", err)
-	}
-	u.Path = "/repos/" + namespace + "/projects/" + projectName + "/plan-policy"
-	req, err := http.NewRequest("GET", u.String(), nil)
-
-	if err != nil {
-		return "", nil, err
-	}
-	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
-
-	resp, err := p.HttpClient.Do(req)
-	if err != nil {
-		return "", nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", resp, nil
-	}
-	return string(body), resp, nil
-
+	return fetchPolicy(p, "/repos/"+namespace+"/projects/"+projectName+"/plan-policy", PlanPolicyType)
 }
 
 // GetPolicy fetches policy for particular project,  if not found then it will fallback to org level policy
@@ -185,6 +128,7 @@ func (p DiggerHttpPolicyProvider) GetAccessPolicy(organisation string, repo stri
 
 	// project policy found
 	if resp.StatusCode == 200 && content != "" {
+		p.rememberEnforcement(AccessPolicyType, organisation, repo, projectName, resp)
 		return content, nil
 	}
 
@@ -195,6 +139,7 @@ func (p DiggerHttpPolicyProvider) GetAccessPolicy(organisation string, repo stri
 			return "", fmt.Errorf("error while fetching access policy for organisation: %v", err)
 		}
 		if resp.StatusCode == 200 {
+			p.rememberEnforcement(AccessPolicyType, organisation, repo, projectName, resp)
 			return content, nil
 		} else if resp.StatusCode == 404 {
 			return DefaultAccessPolicy, nil
@@ -215,6 +160,7 @@ func (p DiggerHttpPolicyProvider) GetPlanPolicy(organisation string, repo string
 
 	// project policy found
 	if resp.StatusCode == 200 && content != "" {
+		p.rememberEnforcement(PlanPolicyType, organisation, repo, projectName, resp)
 		return content, nil
 	}
 
@@ -225,6 +171,7 @@ func (p DiggerHttpPolicyProvider) GetPlanPolicy(organisation string, repo string
 			return "", err
 		}
 		if resp.StatusCode == 200 {
+			p.rememberEnforcement(PlanPolicyType, organisation, repo, projectName, resp)
 			return content, nil
 		} else if resp.StatusCode == 404 {
 			return "", nil
@@ -254,12 +201,60 @@ func (p DiggerHttpPolicyProvider) GetOrganisation() string {
 	return p.DiggerOrganisation
 }
 
+func enforcementKey(policyType string, organisation string, repo string, projectName string) string {
+	return fmt.Sprintf("%v/%v/%v/%v", policyType, organisation, repo, projectName)
+}
+
+func (p DiggerHttpPolicyProvider) rememberEnforcement(policyType string, organisation string, repo string, projectName string, resp *http.Response) {
+	mode := resp.Header.Get(EnforcementHeader)
+	if mode != EnforcementModeAudit {
+		mode = EnforcementModeEnforce
+	}
+	p.Cache.SetEnforcement(enforcementKey(policyType, organisation, repo, projectName), mode)
+}
+
+// GetEnforcement returns the enforcement mode of the policy last fetched for the project
+func (p DiggerHttpPolicyProvider) GetEnforcement(policyType string, organisation string, repo string, projectName string) string {
+	return p.Cache.GetEnforcement(enforcementKey(policyType, organisation, repo, projectName))
+}
+
+// RecordDecision sends a decision of a policy in audit mode to the backend
+func (p DiggerHttpPolicyProvider) RecordDecision(decision Decision) error {
+	u, err := url.Parse(p.DiggerHost)
+	if err != nil {
+		return fmt.Errorf("not able to parse digger cloud url: %v", err)
+	}
+	namespace := fmt.Sprintf("%v-%v", decision.Organisation, decision.Repository)
+	u.Path = "/repos/" + namespace + "/projects/" + decision.ProjectName + "/policies/" + decision.PolicyType + "/decisions"
+
+	body, err := json.Marshal(decision)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", u.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", "Bearer "+p.AuthToken)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code while recording policy decision: %v", resp.StatusCode)
+	}
+	return nil
+}
+
 type DiggerPolicyChecker struct {
 	PolicyProvider Provider
 }
 
 // TODO refactor to use AccessPolicyContext - too many arguments
-func (p DiggerPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
+func (p DiggerPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prService *ci.PullRequestService, SCMOrganisation string, SCMrepository string, projectName string, projectDir string, projectMetadata ProjectMetadata, command string, prNumber *int, requestedBy string, planPolicyViolations []string) (bool, error) {
 
 	policy, err := p.PolicyProvider.GetAccessPolicy(SCMOrganisation, SCMrepository, projectName, projectDir)
 
@@ -290,6 +285,7 @@ func (p DiggerPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prServic
 		"action":               command,
 		"project":              projectName,
 	}
+	projectMetadata.addToInput(input)
 
 	if policy == "" {
 		return true, nil
@@ -297,10 +293,8 @@ func (p DiggerPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prServic
 
 	ctx := context.Background()
 	log.Printf("DEBUG: passing the following input policy: %v ||| text: %v", input, policy)
-	query, err := rego.New(
-		rego.Query("data.digger.allow"),
-		rego.Module("digger", policy),
-	).PrepareForEval(ctx)
+	evaluationStart := time.Now()
+	query, err := prepareQuery(ctx, "data.digger.allow", policy)
 
 	if err != nil {
 		return false, err
@@ -313,20 +307,44 @@ func (p DiggerPolicyChecker) CheckAccessPolicy(ciService ci.OrgService, prServic
 
 	expressions := results[0].Expressions
 
+	allowed := true
 	for _, expression := range expressions {
 		decision, ok := expression.Value.(bool)
 		if !ok {
 			return false, fmt.Errorf("decision is not a boolean")
 		}
 		if !decision {
-			return false, nil
+			allowed = false
+		}
+	}
+	evaluationTime := time.Since(evaluationStart)
+
+	decision := Decision{
+		PolicyType:        AccessPolicyType,
+		Enforcement:       EnforcementModeEnforce,
+		Organisation:      SCMOrganisation,
+		Repository:        SCMrepository,
+		ProjectName:       projectName,
+		Command:           command,
+		RequestedBy:       requestedBy,
+		Allowed:           allowed,
+		Violations:        planPolicyViolations,
+		EvaluationSeconds: evaluationTime.Seconds(),
+	}
+	if p.enforcementMode(ctx, AccessPolicyType, policy, SCMOrganisation, SCMrepository, projectName) == EnforcementModeAudit {
+		decision.Enforcement = EnforcementModeAudit
+		p.recordDecision(decision)
+		if !allowed {
+			publishAuditNote(prService, prNumber, decision)
 		}
+		return true, nil
 	}
 
-	return true, nil
+	p.reportEvaluation(decision)
+	return allowed, nil
 }
 
-func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, planOutput string) (bool, []string, error) {
+func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisation string, projectname string, projectDir string, projectMetadata ProjectMetadata, planOutput string) (bool, []Violation, error) {
 	policy, err := p.PolicyProvider.GetPlanPolicy(SCMOrganisation, SCMrepository, projectname, projectDir)
 	if err != nil {
 		return false, nil, fmt.Errorf("failed get plan policy: %v", err)
@@ -338,10 +356,6 @@ func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisati
 		return false, nil, fmt.Errorf("failed to parse json terraform output to map: %v", err)
 	}
 
-	input := map[string]interface{}{
-		"terraform": parsedPlanOutput,
-	}
-
 	if policy == "" {
 		log.Printf("No plan policies found, succeeding")
 		return true, nil, nil
@@ -349,44 +363,91 @@ func (p DiggerPolicyChecker) CheckPlanPolicy(SCMrepository string, SCMOrganisati
 
 	ctx := context.Background()
 	log.Printf("DEBUG: passing the following input policy: %v", policy)
-	query, err := rego.New(
-		rego.Query("data.digger.deny"),
-		rego.Module("digger", policy),
-	).PrepareForEval(ctx)
-
+	input := planPolicyInput(parsedPlanOutput)
+	input["project"] = projectname
+	projectMetadata.addToInput(input)
+	evaluationStart := time.Now()
+	violations, err := EvaluateSeverityRules(ctx, policy, input)
 	if err != nil {
 		return false, nil, err
 	}
-
-	results, err := query.Eval(ctx, rego.EvalInput(input))
-	if len(results) == 0 || len(results[0].Expressions) == 0 {
-		return false, nil, fmt.Errorf("no result found")
+	evaluationTime := time.Since(evaluationStart)
+
+	for _, v := range violations {
+		log.Printf("%v: %v\n", v.Severity, v)
+	}
+
+	denies := Denies(violations)
+	decision := Decision{
+		PolicyType:        PlanPolicyType,
+		Enforcement:       EnforcementModeEnforce,
+		Organisation:      SCMOrganisation,
+		Repository:        SCMrepository,
+		ProjectName:       projectname,
+		Command:           "digger plan",
+		Allowed:           len(denies) == 0,
+		Violations:        denies,
+		EvaluationSeconds: evaluationTime.Seconds(),
+	}
+	if p.enforcementMode(ctx, PlanPolicyType, policy, SCMOrganisation, SCMrepository, projectname) == EnforcementModeAudit {
+		decision.Enforcement = EnforcementModeAudit
+		p.recordDecision(decision)
+		for i := range violations {
+			if violations[i].Severity == SeverityDeny {
+				violations[i].Audit = true
+			}
+		}
+		return true, violations, nil
 	}
 
-	expressions := results[0].Expressions
-
-	decisionsResult := make([]string, 0)
-	for _, expression := range expressions {
-		decisions, ok := expression.Value.([]interface{})
+	p.reportEvaluation(decision)
+	if len(denies) > 0 {
+		return false, violations, nil
+	}
 
-		if !ok {
-			return false, nil, fmt.Errorf("decision is not a slice of interfaces")
-		}
-		if len(decisions) > 0 {
-			for _, d := range decisions {
-				decisionsResult = append(decisionsResult, d.(string))
-				log.Printf("denied: %v\n", d)
-			}
+	return true, violations, nil
+}
 
-		}
+// CheckDriftPlanPolicy evaluates the deny / warn rules of the drift policy against the plan produced by drift detection
+func (p DiggerPolicyChecker) CheckDriftPlanPolicy(SCMOrganisation string, SCMrepository string, projectName string, projectMetadata ProjectMetadata, planOutput string) ([]Violation, error) {
+	policy, err := p.PolicyProvider.GetDriftPolicy()
+	if err != nil {
+		log.Printf("Error while fetching drift policy: %v", err)
+		return nil, err
+	}
 
+	if policy == "" {
+		return nil, nil
 	}
 
-	if len(decisionsResult) > 0 {
-		return false, decisionsResult, nil
+	var parsedPlanOutput map[string]interface{}
+	err = json.Unmarshal([]byte(planOutput), &parsedPlanOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse json terraform output to map: %v", err)
 	}
 
-	return true, []string{}, nil
+	input := planPolicyInput(parsedPlanOutput)
+	input["organisation"] = SCMOrganisation
+	input["project"] = projectName
+	projectMetadata.addToInput(input)
+
+	evaluationStart := time.Now()
+	violations, err := EvaluateSeverityRules(context.Background(), policy, input)
+	if err != nil {
+		return nil, err
+	}
+	p.reportEvaluation(Decision{
+		PolicyType:        DriftPolicyType,
+		Enforcement:       EnforcementModeEnforce,
+		Organisation:      SCMOrganisation,
+		Repository:        SCMrepository,
+		ProjectName:       projectName,
+		Command:           "digger drift-detect",
+		Allowed:           len(Denies(violations)) == 0,
+		Violations:        Denies(violations),
+		EvaluationSeconds: time.Since(evaluationStart).Seconds(),
+	})
+	return violations, nil
 }
 
 func (p DiggerPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMrepository string, projectName string) (bool, error) {
@@ -409,10 +470,8 @@ func (p DiggerPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMreposit
 
 	ctx := context.Background()
 	log.Printf("DEBUG: passing the following input policy: %v ||| text: %v", input, policy)
-	query, err := rego.New(
-		rego.Query("data.digger.enable"),
-		rego.Module("digger", policy),
-	).PrepareForEval(ctx)
+	evaluationStart := time.Now()
+	query, err := prepareQuery(ctx, "data.digger.enable", policy)
 
 	if err != nil {
 		return false, err
@@ -425,22 +484,40 @@ func (p DiggerPolicyChecker) CheckDriftPolicy(SCMOrganisation string, SCMreposit
 
 	expressions := results[0].Expressions
 
+	enabled := true
 	for _, expression := range expressions {
 		decision, ok := expression.Value.(bool)
 		if !ok {
 			return false, fmt.Errorf("decision is not a boolean")
 		}
 		if !decision {
-			return false, nil
+			enabled = false
 		}
 	}
-
-	return true, nil
+	p.reportEvaluation(Decision{
+		PolicyType:        DriftPolicyType,
+		Enforcement:       EnforcementModeEnforce,
+		Organisation:      SCMOrganisation,
+		Repository:        SCMrepository,
+		ProjectName:       projectName,
+		Command:           "digger drift-detect",
+		Allowed:           enabled,
+		EvaluationSeconds: time.Since(evaluationStart).Seconds(),
+	})
+
+	return enabled, nil
 }
 
 func NewPolicyChecker(hostname string, organisationName string, authToken string) Checker {
 	var policyChecker Checker
-	if os.Getenv("NO_BACKEND") == "true" {
+	if policyDir := PolicyDirFromEnv(); policyDir != "" {
+		log.Printf("Loading policies from local directory %v", policyDir)
+		policyChecker = DiggerPolicyChecker{
+			PolicyProvider: DirPolicyProvider{
+				PolicyDir:          policyDir,
+				DiggerOrganisation: organisationName,
+			}}
+	} else if os.Getenv("NO_BACKEND") == "true" {
 		log.Println("WARNING: running in 'backendless' mode. Features that require backend will not be available.")
 		policyChecker = NoOpPolicyChecker{}
 	} else {
@@ -450,6 +527,8 @@ func NewPolicyChecker(hostname string, organisationName string, authToken string
 				DiggerOrganisation: organisationName,
 				AuthToken:          authToken,
 				HttpClient:         http.DefaultClient,
+				PolicyVersions:     PolicyVersionsFromEnv(),
+				Cache:              DefaultPolicyCache,
 			}}
 	}
 	return policyChecker
@@ -1,7 +1,15 @@
 package policy
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"github.com/diggerhq/digger/libs/ci"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -221,7 +229,7 @@ func TestDiggerAccessPolicyChecker_Check(t *testing.T) {
 				PolicyProvider: tt.fields.PolicyProvider,
 			}
 			ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
-			got, err := p.CheckAccessPolicy(ciService, nil, tt.organisation, tt.name, tt.name, "", tt.command, nil, tt.requestedBy, tt.planPolicyViolations)
+			got, err := p.CheckAccessPolicy(ciService, nil, tt.organisation, tt.name, tt.name, "", ProjectMetadata{}, tt.command, nil, tt.requestedBy, tt.planPolicyViolations)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DiggerPolicyChecker.CheckAccessPolicy() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -273,7 +281,7 @@ func TestDiggerPlanPolicyChecker_Check(t *testing.T) {
 			var p = &DiggerPolicyChecker{
 				PolicyProvider: tt.fields.PolicyProvider,
 			}
-			got, _, err := p.CheckPlanPolicy("", "", "", "", tt.planJsonOutput)
+			got, _, err := p.CheckPlanPolicy("", "", "", "", ProjectMetadata{}, tt.planJsonOutput)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DiggerPolicyChecker.CheckPlanPolicy() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -284,3 +292,259 @@ func TestDiggerPlanPolicyChecker_Check(t *testing.T) {
 		})
 	}
 }
+
+func createBundle(t *testing.T, files map[string]string) string {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, contents := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents))})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(contents))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+	return buf.String()
+}
+
+type BundlePolicyProvider struct {
+	Bundle string
+}
+
+func (s *BundlePolicyProvider) GetAccessPolicy(organisation string, repository string, projectname string, projectDir string) (string, error) {
+	return s.Bundle, nil
+}
+
+func (s *BundlePolicyProvider) GetPlanPolicy(organisation string, repository string, projectname string, projectDir string) (string, error) {
+	return "", nil
+}
+
+func (s *BundlePolicyProvider) GetDriftPolicy() (string, error) {
+	return "", nil
+}
+
+func (s *BundlePolicyProvider) GetOrganisation() string {
+	return "ORGANISATIONDIGGER"
+}
+
+func TestDiggerAccessPolicyCheckerWithBundle(t *testing.T) {
+	bundle := createBundle(t, map[string]string{
+		"/lib/helpers.rego": "package lib\n\nis_admin(user) {\n    data.admins[_] == user\n}\n",
+		"/digger.rego":      "package digger\n\nimport data.lib\n\ndefault allow = false\nallow {\n    lib.is_admin(input.user)\n}\n",
+		"/data.json":        "{\"admins\": [\"motatoes\"]}",
+	})
+	assert.True(t, IsBundle(bundle))
+
+	p := &DiggerPolicyChecker{PolicyProvider: &BundlePolicyProvider{Bundle: bundle}}
+	ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
+
+	allowed, err := p.CheckAccessPolicy(ciService, nil, "", "", "", "", ProjectMetadata{}, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = p.CheckAccessPolicy(ciService, nil, "", "", "", "", ProjectMetadata{}, "digger apply", nil, "rando", nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestDiggerHttpPolicyProviderUsesETagAndVersion(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/orgs/digger/plan-policy", r.URL.Path)
+		assert.Equal(t, "3", r.URL.Query().Get("version"))
+		if r.Header.Get("If-None-Match") == "\"abc\"" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", "\"abc\"")
+		w.Write([]byte("package digger\n"))
+	}))
+	defer server.Close()
+
+	provider := DiggerHttpPolicyProvider{
+		DiggerHost:         server.URL,
+		DiggerOrganisation: "digger",
+		HttpClient:         server.Client(),
+		PolicyVersions:     map[string]string{PlanPolicyType: "3"},
+		Cache:              NewPolicyCache(),
+	}
+
+	for i := 0; i < 2; i++ {
+		content, resp, err := getPlanPolicyForOrganisation(&provider)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "package digger\n", content)
+	}
+	assert.Equal(t, 2, requests)
+}
+
+func TestDiggerPlanPolicyCheckerWithSeverities(t *testing.T) {
+	policyDir := t.TempDir()
+	files := map[string]string{
+		"plan/instances.rego": "package instances\n\ndeny[msg] {\n    r := input.resource_changes[_]\n    r.type == \"aws_instance\"\n    msg := sprintf(\"%v is not allowed\", [r.address])\n}\n",
+		"plan/tags.rego":      "package tags\n\nwarn_untagged[msg] {\n    r := input.terraform.resource_changes[_]\n    not r.change.after.tags\n    msg := sprintf(\"%v has no tags\", [r.address])\n}\n",
+		"drift/drift.rego":    "package digger\n\ndefault enable = true\n\nwarn[msg] {\n    count(input.resource_changes) > 0\n    msg := sprintf(\"%v drifted\", [input.project])\n}\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(policyDir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+
+	p := &DiggerPolicyChecker{PolicyProvider: DirPolicyProvider{PolicyDir: policyDir}}
+
+	allowed, violations, err := p.CheckPlanPolicy("", "", "", "", ProjectMetadata{}, `{"resource_changes": [{"address": "null_resource.a", "type": "null_resource", "change": {"after": {}}}]}`)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Empty(t, Denies(violations))
+	assert.Equal(t, []string{"tags: null_resource.a has no tags"}, Warnings(violations))
+
+	allowed, violations, err = p.CheckPlanPolicy("", "", "", "", ProjectMetadata{}, `{"resource_changes": [{"address": "aws_instance.web", "type": "aws_instance", "change": {"after": {"tags": {"team": "infra"}}}}]}`)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"instances: aws_instance.web is not allowed"}, Denies(violations))
+	assert.Empty(t, Warnings(violations))
+
+	violations, err = p.CheckDriftPlanPolicy("", "", "dev", ProjectMetadata{}, `{"resource_changes": [{"address": "null_resource.a"}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dev drifted"}, Warnings(violations))
+
+	accessPolicy, err := DirPolicyProvider{PolicyDir: policyDir}.GetAccessPolicy("", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultAccessPolicy, accessPolicy)
+}
+
+type AuditPolicyProvider struct {
+	AccessPolicy string
+	PlanPolicy   string
+	Decisions    []Decision
+}
+
+func (s *AuditPolicyProvider) GetAccessPolicy(organisation string, repository string, projectname string, projectDir string) (string, error) {
+	return s.AccessPolicy, nil
+}
+
+func (s *AuditPolicyProvider) GetPlanPolicy(organisation string, repository string, projectname string, projectDir string) (string, error) {
+	return s.PlanPolicy, nil
+}
+
+func (s *AuditPolicyProvider) GetDriftPolicy() (string, error) {
+	return "", nil
+}
+
+func (s *AuditPolicyProvider) GetOrganisation() string {
+	return "ORGANISATIONDIGGER"
+}
+
+func (s *AuditPolicyProvider) RecordDecision(decision Decision) error {
+	s.Decisions = append(s.Decisions, decision)
+	return nil
+}
+
+func TestDiggerPolicyCheckerAuditMode(t *testing.T) {
+	provider := &AuditPolicyProvider{
+		AccessPolicy: "package digger\n\nenforcement := \"audit\"\n\ndefault allow = false\n",
+		PlanPolicy:   "package digger\n\nenforcement := \"audit\"\n\ndeny[msg] {\n    input.resource_changes[_].type == \"aws_instance\"\n    msg := \"instances are not allowed\"\n}\n",
+	}
+	p := &DiggerPolicyChecker{PolicyProvider: provider}
+	ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
+
+	allowed, err := p.CheckAccessPolicy(ciService, nil, "diggerhq", "digger", "dev", "", ProjectMetadata{}, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, violations, err := p.CheckPlanPolicy("digger", "diggerhq", "dev", "", ProjectMetadata{}, `{"resource_changes": [{"type": "aws_instance"}]}`)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Empty(t, Denies(violations))
+	assert.Equal(t, []string{"instances are not allowed"}, Audited(violations))
+
+	assert.Equal(t, 2, len(provider.Decisions))
+	assert.Equal(t, AccessPolicyType, provider.Decisions[0].PolicyType)
+	assert.False(t, provider.Decisions[0].Allowed)
+	assert.Equal(t, PlanPolicyType, provider.Decisions[1].PolicyType)
+	assert.Equal(t, []string{"instances are not allowed"}, provider.Decisions[1].Violations)
+}
+
+func TestDiggerPolicyCheckerReportsEnforcedEvaluations(t *testing.T) {
+	provider := &AuditPolicyProvider{
+		AccessPolicy: "package digger\n\ndefault allow = false\n",
+		PlanPolicy:   "package digger\n\ndeny[msg] {\n    input.resource_changes[_].type == \"aws_instance\"\n    msg := \"instances are not allowed\"\n}\n",
+	}
+	p := &DiggerPolicyChecker{PolicyProvider: provider}
+	ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
+
+	allowed, err := p.CheckAccessPolicy(ciService, nil, "diggerhq", "digger", "dev", "", ProjectMetadata{}, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, _, err = p.CheckPlanPolicy("digger", "diggerhq", "dev", "", ProjectMetadata{}, `{"resource_changes": [{"type": "aws_instance"}]}`)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	assert.Equal(t, 2, len(provider.Decisions))
+	for _, decision := range provider.Decisions {
+		assert.Equal(t, EnforcementModeEnforce, decision.Enforcement)
+		assert.False(t, decision.Allowed)
+		assert.Greater(t, decision.EvaluationSeconds, 0.0)
+	}
+	assert.Equal(t, AccessPolicyType, provider.Decisions[0].PolicyType)
+	assert.Equal(t, PlanPolicyType, provider.Decisions[1].PolicyType)
+}
+
+func TestDiggerHttpPolicyProviderEnforcementHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/diggerhq-digger/projects/dev/access-policy" {
+			w.Header().Set(EnforcementHeader, EnforcementModeAudit)
+			w.Write([]byte("package digger\n\ndefault allow = false\n"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	provider := DiggerHttpPolicyProvider{
+		DiggerHost:         server.URL,
+		DiggerOrganisation: "diggerhq",
+		HttpClient:         server.Client(),
+		Cache:              NewPolicyCache(),
+	}
+	p := &DiggerPolicyChecker{PolicyProvider: provider}
+	ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
+
+	allowed, err := p.CheckAccessPolicy(ciService, nil, "diggerhq", "digger", "dev", "", ProjectMetadata{}, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, EnforcementModeAudit, provider.GetEnforcement(AccessPolicyType, "diggerhq", "digger", "dev"))
+}
+
+func TestDiggerPolicyCheckerProjectMetadataInput(t *testing.T) {
+	provider := &AuditPolicyProvider{
+		AccessPolicy: "package digger\n\ndefault allow = false\n\nallow {\n    input.environment != \"prod\"\n}\n\nallow {\n    input.labels.team == input.teams[_]\n}\n",
+		PlanPolicy:   "package digger\n\ndeny[msg] {\n    input.environment == \"prod\"\n    input.resource_changes[_].change.actions[_] == \"delete\"\n    msg := sprintf(\"deletes are not allowed in %v\", [input.environment])\n}\n",
+	}
+	p := &DiggerPolicyChecker{PolicyProvider: provider}
+	ciService := ci.MockPullRequestManager{Teams: []string{"engineering"}}
+
+	prod := ProjectMetadata{Environment: "prod", Labels: map[string]string{"team": "network"}}
+	allowed, err := p.CheckAccessPolicy(ciService, nil, "diggerhq", "digger", "prod-network", "", prod, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	prod.Labels["team"] = "engineering"
+	allowed, err = p.CheckAccessPolicy(ciService, nil, "diggerhq", "digger", "prod-network", "", prod, "digger apply", nil, "motatoes", nil)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	plan := `{"resource_changes": [{"change": {"actions": ["delete"]}}]}`
+	allowed, violations, err := p.CheckPlanPolicy("digger", "diggerhq", "prod-network", "", prod, plan)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, []string{"deletes are not allowed in prod"}, Denies(violations))
+
+	allowed, _, err = p.CheckPlanPolicy("digger", "diggerhq", "dev-network", "", ProjectMetadata{Environment: "dev"}, plan)
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+}
@@ -10,7 +10,14 @@ type PolicyCheckerProviderBasic struct{}
 
 func (p PolicyCheckerProviderBasic) Get(hostname string, organisationName string, authToken string) (Checker, error) {
 	var policyChecker Checker
-	if os.Getenv("NO_BACKEND") == "true" {
+	if policyDir := PolicyDirFromEnv(); policyDir != "" {
+		log.Printf("Loading policies from local directory %v", policyDir)
+		policyChecker = DiggerPolicyChecker{
+			PolicyProvider: DirPolicyProvider{
+				PolicyDir:          policyDir,
+				DiggerOrganisation: organisationName,
+			}}
+	} else if os.Getenv("NO_BACKEND") == "true" {
 		log.Println("WARNING: running in 'backendless' mode. No policies will be supported.")
 		policyChecker = NoOpPolicyChecker{}
 	} else {
@@ -20,6 +27,8 @@ func (p PolicyCheckerProviderBasic) Get(hostname string, organisationName string
 				DiggerOrganisation: organisationName,
 				AuthToken:          authToken,
 				HttpClient:         http.DefaultClient,
+				PolicyVersions:     PolicyVersionsFromEnv(),
+				Cache:              DefaultPolicyCache,
 			}}
 	}
 	return policyChecker, nil
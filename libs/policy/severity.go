@@ -0,0 +1,206 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+type Severity string
+
+const (
+	SeverityDeny Severity = "deny"
+	SeverityWarn Severity = "warn"
+)
+
+// rule prefixes recognised in every namespace, same conventions as conftest (deny, deny_xyz, violation, warn ...)
+var severityRulePrefixes = []struct {
+	Prefix   string
+	Severity Severity
+}{
+	{"deny", SeverityDeny},
+	{"violation", SeverityDeny},
+	{"warn", SeverityWarn},
+}
+
+// Violation is a single message produced by a deny or warn rule. Denies block the operation, warnings are only reported
+type Violation struct {
+	Severity  Severity
+	Namespace string
+	Message   string
+	// Audit is set for denies of a policy in audit mode, they are reported but do not block
+	Audit bool
+}
+
+func (v Violation) String() string {
+	// the digger namespace is the default one so we keep messages as they were before namespaces were supported
+	if v.Namespace == "" || v.Namespace == "digger" {
+		return v.Message
+	}
+	return fmt.Sprintf("%v: %v", v.Namespace, v.Message)
+}
+
+func messagesWithSeverity(violations []Violation, severity Severity, audit bool) []string {
+	messages := make([]string, 0)
+	for _, v := range violations {
+		if v.Severity == severity && v.Audit == audit {
+			messages = append(messages, v.String())
+		}
+	}
+	return messages
+}
+
+// Denies returns the messages of all blocking violations
+func Denies(violations []Violation) []string {
+	return messagesWithSeverity(violations, SeverityDeny, false)
+}
+
+// Warnings returns the messages of all non-blocking violations
+func Warnings(violations []Violation) []string {
+	return messagesWithSeverity(violations, SeverityWarn, false)
+}
+
+// Audited returns the messages of denies that were not enforced because the policy is in audit mode
+func Audited(violations []Violation) []string {
+	return messagesWithSeverity(violations, SeverityDeny, true)
+}
+
+func ruleSeverity(ruleName string) (Severity, bool) {
+	for _, p := range severityRulePrefixes {
+		if ruleName == p.Prefix || strings.HasPrefix(ruleName, p.Prefix+"_") {
+			return p.Severity, true
+		}
+	}
+	return "", false
+}
+
+func policyModules(policy string) ([]*ast.Module, error) {
+	if !IsBundle(policy) {
+		module, err := ast.ParseModule("digger", policy)
+		if err != nil {
+			return nil, err
+		}
+		return []*ast.Module{module}, nil
+	}
+
+	b, err := LoadBundle([]byte(policy))
+	if err != nil {
+		return nil, err
+	}
+	modules := make([]*ast.Module, 0)
+	for _, m := range b.Modules {
+		modules = append(modules, m.Parsed)
+	}
+	return modules, nil
+}
+
+type severityRule struct {
+	Namespace string
+	Name      string
+	Severity  Severity
+}
+
+// findSeverityRules lists all deny / warn rules declared across the namespaces (packages) of the policy
+func findSeverityRules(modules []*ast.Module) []severityRule {
+	seen := make(map[string]bool)
+	rules := make([]severityRule, 0)
+	for _, module := range modules {
+		namespace := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		for _, rule := range module.Rules {
+			ruleRef := rule.Head.Ref()
+			if len(ruleRef) == 0 {
+				continue
+			}
+			name := strings.Trim(ruleRef[0].Value.String(), "\"")
+			severity, ok := ruleSeverity(name)
+			if !ok {
+				continue
+			}
+			key := namespace + "." + name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			rules = append(rules, severityRule{Namespace: namespace, Name: name, Severity: severity})
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Namespace != rules[j].Namespace {
+			return rules[i].Namespace < rules[j].Namespace
+		}
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+// ruleMessages converts a rule result into messages, rules can produce strings, objects with a msg field or booleans
+func ruleMessages(ruleName string, value interface{}) []string {
+	messages := make([]string, 0)
+	switch v := value.(type) {
+	case bool:
+		if v {
+			messages = append(messages, ruleName)
+		}
+	case string:
+		messages = append(messages, v)
+	case map[string]interface{}:
+		if msg, ok := v["msg"]; ok {
+			messages = append(messages, fmt.Sprintf("%v", msg))
+		} else {
+			messages = append(messages, fmt.Sprintf("%v", v))
+		}
+	case []interface{}:
+		for _, item := range v {
+			messages = append(messages, ruleMessages(ruleName, item)...)
+		}
+	}
+	return messages
+}
+
+// EvaluateSeverityRules evaluates every deny / violation / warn rule in every namespace of the policy
+// (a single module or a bundle of modules) against the input
+func EvaluateSeverityRules(ctx context.Context, policy string, input interface{}) ([]Violation, error) {
+	modules, err := policyModules(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse policy: %v", err)
+	}
+
+	violations := make([]Violation, 0)
+	for _, rule := range findSeverityRules(modules) {
+		query, err := prepareQuery(ctx, fmt.Sprintf("data.%v.%v", rule.Namespace, rule.Name), policy)
+		if err != nil {
+			return nil, err
+		}
+		results, err := query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, fmt.Errorf("could not evaluate %v.%v: %v", rule.Namespace, rule.Name, err)
+		}
+		for _, result := range results {
+			for _, expression := range result.Expressions {
+				for _, message := range ruleMessages(rule.Name, expression.Value) {
+					violations = append(violations, Violation{
+						Severity:  rule.Severity,
+						Namespace: rule.Namespace,
+						Message:   message,
+					})
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// planPolicyInput exposes the plan both at the top level (like conftest) and under the terraform key
+// which is what digger plan policies have always used
+func planPolicyInput(parsedPlanOutput map[string]interface{}) map[string]interface{} {
+	input := make(map[string]interface{}, len(parsedPlanOutput)+1)
+	for k, v := range parsedPlanOutput {
+		input[k] = v
+	}
+	input["terraform"] = parsedPlanOutput
+	return input
+}
@@ -0,0 +1,126 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const Placeholder = "<REDACTED>"
+
+// values shorter than this are not treated as secrets, otherwise values like "1" or "dev"
+// would be scrubbed from every comment
+const minSecretLength = 4
+
+// patterns of common credentials that are scrubbed even if they were never registered,
+// a submatch named "secret" limits the replacement to that part of the match
+var patterns = []*regexp.Regexp{
+	// private keys (PEM, OpenSSH)
+	regexp.MustCompile(`-----BEGIN[ A-Z0-9]*PRIVATE KEY-----[\s\S]*?-----END[ A-Z0-9]*PRIVATE KEY-----`),
+	// AWS access key ids
+	regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`),
+	// AWS secret access keys and session tokens assigned to a known name
+	regexp.MustCompile(`(?i)(?:aws_secret_access_key|secret_access_key|secret_key|aws_session_token|session_token)["']?\s*[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{16,})`),
+	// GitHub tokens
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	// GitLab tokens
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`),
+	// Slack tokens
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`),
+	// bearer tokens in headers
+	regexp.MustCompile(`(?i)bearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{20,}=*)`),
+}
+
+// Redactor scrubs known secret values and common credential patterns from text before it is published
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets map[string]bool
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: make(map[string]bool)}
+}
+
+// Default is the redactor used by reporters, secrets are registered when variables are resolved
+var Default = NewRedactor()
+
+// Register adds secret values to the default redactor
+func Register(values ...string) {
+	Default.Register(values...)
+}
+
+// String redacts the text with the default redactor
+func String(s string) string {
+	return Default.Redact(s)
+}
+
+// Register adds secret values to the redactor, multi-line values are also registered line by line
+// since tools often re-indent or wrap them in their output
+func (r *Redactor) Register(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		r.add(value)
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				r.add(line)
+			}
+		}
+	}
+}
+
+func (r *Redactor) add(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength {
+		return
+	}
+	r.secrets[value] = true
+}
+
+// sortedSecrets returns the secrets longest first so that a secret containing another one is replaced whole
+func (r *Redactor) sortedSecrets() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return secrets[i] < secrets[j]
+	})
+	return secrets
+}
+
+// Redact replaces registered secret values and common credential patterns with a placeholder
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, secret := range r.sortedSecrets() {
+		s = strings.ReplaceAll(s, secret, Placeholder)
+	}
+	for _, pattern := range patterns {
+		s = redactPattern(pattern, s)
+	}
+	return s
+}
+
+func redactPattern(pattern *regexp.Regexp, s string) string {
+	secretIndex := pattern.SubexpIndex("secret")
+	if secretIndex < 0 {
+		return pattern.ReplaceAllString(s, Placeholder)
+	}
+	return pattern.ReplaceAllStringFunc(s, func(match string) string {
+		submatches := pattern.FindStringSubmatchIndex(match)
+		start, end := submatches[2*secretIndex], submatches[2*secretIndex+1]
+		if start < 0 {
+			return match
+		}
+		return match[:start] + Placeholder + match[end:]
+	})
+}
@@ -23,14 +23,22 @@ func ConvertProjectsToJobs(actor string, repoNamespace string, command string, p
 			skipMerge = false
 		}
 
-		stateEnvVars, commandEnvVars := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		stateEnvVars, commandEnvVars, err := digger_config.CollectTerraformEnvConfig(workflow.EnvVars, false)
+		if err != nil {
+			return nil, false, fmt.Errorf("could not collect env vars of project %v: %v", project.Name, err)
+		}
 		StateEnvProvider, CommandEnvProvider := GetStateAndCommandProviders(project)
 		jobs = append(jobs, Job{
-			ProjectName:      project.Name,
-			ProjectDir:       project.Dir,
-			ProjectWorkspace: project.Workspace,
-			Terragrunt:       project.Terragrunt,
-			OpenTofu:         project.OpenTofu,
+			ProjectName:          project.Name,
+			ProjectDir:           project.Dir,
+			ProjectLabels:        project.Labels,
+			ProjectEnvironment:   project.Environment,
+			ProjectOwners:        project.Owners,
+			RequireOwnerApproval: project.RequireOwnerApproval,
+			ProjectInputs:        project.Inputs,
+			ProjectWorkspace:     project.Workspace,
+			Terragrunt:           project.Terragrunt,
+			OpenTofu:             project.OpenTofu,
 			// TODO: expose lower level api per command configuration
 			Commands:   []string{command},
 			ApplyStage: ToConfigStage(workflow.Apply),
@@ -27,6 +27,13 @@ type Job struct {
 	StateEnvProvider   *stscreds.WebIdentityRoleProvider
 	CommandEnvProvider *stscreds.WebIdentityRoleProvider
 	SkipMergeCheck	   bool
+	ProjectLabels      map[string]string
+	ProjectEnvironment string
+	ProjectOwners      []string
+	// RequireOwnerApproval blocks apply until one of the project owners approved the pull request
+	RequireOwnerApproval bool
+	// ProjectInputs maps terraform variable names to outputs of upstream projects, as "project.output"
+	ProjectInputs map[string]string
 }
 
 type Step struct {
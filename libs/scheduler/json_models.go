@@ -43,6 +43,11 @@ type JobJson struct {
 	BackendOrganisationName string            `json:"backend_organisation_hostname"`
 	BackendJobToken         string            `json:"backend_job_token"`
 	SkipMergeCheck          bool              `json:"skip_merge_check"`
+	ProjectLabels           map[string]string `json:"projectLabels,omitempty"`
+	ProjectEnvironment      string            `json:"projectEnvironment,omitempty"`
+	ProjectOwners           []string          `json:"projectOwners,omitempty"`
+	RequireOwnerApproval    bool              `json:"requireOwnerApproval,omitempty"`
+	ProjectInputs           map[string]string `json:"projectInputs,omitempty"`
 }
 
 func (j *JobJson) IsPlan() bool {
@@ -87,29 +92,39 @@ func JobToJson(job Job, jobType DiggerCommand, organisationName string, branch s
 		BackendJobToken:         jobToken,
 		BackendOrganisationName: organisationName,
 		SkipMergeCheck:          job.SkipMergeCheck,
+		ProjectLabels:           job.ProjectLabels,
+		ProjectEnvironment:      job.ProjectEnvironment,
+		ProjectOwners:           job.ProjectOwners,
+		RequireOwnerApproval:    job.RequireOwnerApproval,
+		ProjectInputs:           job.ProjectInputs,
 	}
 }
 
 func JsonToJob(jobJson JobJson) Job {
 	return Job{
-		ProjectName:        jobJson.ProjectName,
-		ProjectDir:         jobJson.ProjectDir,
-		ProjectWorkspace:   jobJson.ProjectWorkspace,
-		OpenTofu:           jobJson.OpenTofu,
-		Terragrunt:         jobJson.Terragrunt,
-		Commands:           jobJson.Commands,
-		ApplyStage:         jsonToStage(jobJson.ApplyStage),
-		PlanStage:          jsonToStage(jobJson.PlanStage),
-		PullRequestNumber:  jobJson.PullRequestNumber,
-		EventName:          jobJson.EventName,
-		RequestedBy:        jobJson.RequestedBy,
-		Namespace:          jobJson.Namespace,
-		RunEnvVars:         jobJson.RunEnvVars,
-		StateEnvVars:       jobJson.StateEnvVars,
-		CommandEnvVars:     jobJson.CommandEnvVars,
-		StateEnvProvider:   GetProviderFromRole(jobJson.StateRoleName, jobJson.AwsRoleRegion),
-		CommandEnvProvider: GetProviderFromRole(jobJson.CommandRoleName, jobJson.AwsRoleRegion),
-		SkipMergeCheck:     jobJson.SkipMergeCheck,
+		ProjectName:          jobJson.ProjectName,
+		ProjectDir:           jobJson.ProjectDir,
+		ProjectWorkspace:     jobJson.ProjectWorkspace,
+		OpenTofu:             jobJson.OpenTofu,
+		Terragrunt:           jobJson.Terragrunt,
+		Commands:             jobJson.Commands,
+		ApplyStage:           jsonToStage(jobJson.ApplyStage),
+		PlanStage:            jsonToStage(jobJson.PlanStage),
+		PullRequestNumber:    jobJson.PullRequestNumber,
+		EventName:            jobJson.EventName,
+		RequestedBy:          jobJson.RequestedBy,
+		Namespace:            jobJson.Namespace,
+		RunEnvVars:           jobJson.RunEnvVars,
+		StateEnvVars:         jobJson.StateEnvVars,
+		CommandEnvVars:       jobJson.CommandEnvVars,
+		StateEnvProvider:     GetProviderFromRole(jobJson.StateRoleName, jobJson.AwsRoleRegion),
+		CommandEnvProvider:   GetProviderFromRole(jobJson.CommandRoleName, jobJson.AwsRoleRegion),
+		SkipMergeCheck:       jobJson.SkipMergeCheck,
+		ProjectLabels:        jobJson.ProjectLabels,
+		ProjectEnvironment:   jobJson.ProjectEnvironment,
+		ProjectOwners:        jobJson.ProjectOwners,
+		RequireOwnerApproval: jobJson.RequireOwnerApproval,
+		ProjectInputs:        jobJson.ProjectInputs,
 	}
 }
 
@@ -13,6 +13,10 @@ const (
 	BatchJobFailed      DiggerBatchStatus = 3
 	BatchJobSucceeded   DiggerBatchStatus = 4
 	BatchJobInvalidated DiggerBatchStatus = 5
+	// a newer push to the pull request replaced the batch
+	BatchJobSuperseded DiggerBatchStatus = 6
+	// cancelled with a digger cancel comment
+	BatchJobCancelled DiggerBatchStatus = 7
 )
 
 type WorkflowInput struct {
@@ -43,6 +47,7 @@ const (
 	DiggerJobStarted      DiggerJobStatus = 4
 	DiggerJobSucceeded    DiggerJobStatus = 5
 	DiggerJobQueuedForRun DiggerJobStatus = 6
+	DiggerJobCancelled    DiggerJobStatus = 7
 )
 
 func (d *DiggerJobStatus) ToString() string {
@@ -59,6 +64,8 @@ func (d *DiggerJobStatus) ToString() string {
 		return "created"
 	case DiggerJobQueuedForRun:
 		return "created"
+	case DiggerJobCancelled:
+		return "cancelled"
 	default:
 		return "unknown status"
 	}
@@ -78,6 +85,8 @@ func (d *DiggerJobStatus) ToEmoji() string {
 		return ":clock11:"
 	case DiggerJobQueuedForRun:
 		return ":clock11:"
+	case DiggerJobCancelled:
+		return ":no_entry_sign:"
 	default:
 		return ":question:"
 	}
@@ -94,6 +103,25 @@ type SerializedJob struct {
 	ResourcesCreated uint            `json:"resources_created"`
 	ResourcesDeleted uint            `json:"resources_deleted"`
 	ResourcesUpdated uint            `json:"resources_updated"`
+	// position of the job in the queue of jobs waiting for a concurrency slot, 0 if it isn't queued
+	QueuePosition int `json:"queue_position,omitempty"`
+	// number of times the job ran, more than 1 when it was retried
+	Attempt int `json:"attempt,omitempty"`
+	// page following the output of the job live, empty when the backend doesn't serve live logs
+	LogsUrl string `json:"logs_url,omitempty"`
+}
+
+// StatusString returns the status of the job for display, with its position in the queue if it is queued
+// and its attempt if it was retried
+func (j *SerializedJob) StatusString() string {
+	status := j.Status.ToString()
+	if j.Status == DiggerJobQueuedForRun && j.QueuePosition > 0 {
+		status = fmt.Sprintf("queued (#%v)", j.QueuePosition)
+	}
+	if j.Attempt > 1 {
+		status = fmt.Sprintf("%v, attempt %v", status, j.Attempt)
+	}
+	return status
 }
 
 type SerializedBatch struct {
@@ -131,7 +159,7 @@ func (b *SerializedBatch) ToStatusCheck() string {
 	switch b.Status {
 	case BatchJobCreated:
 		return "pending"
-	case BatchJobInvalidated:
+	case BatchJobInvalidated, BatchJobSuperseded, BatchJobCancelled:
 		return "failure"
 	case BatchJobFailed:
 		return "success"
@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+)
+
+// ProjectSelectors describes which projects a comment command targets, e.g.
+//
+//	digger plan -p prod-* -p shared
+//	digger plan -l env=prod
+//	digger apply --all
+//
+// Name patterns are glob patterns (path.Match syntax). Label selectors are
+// either "key=value" or just "key" to match any project that has the label.
+// Selectors of the same kind are combined as a union, different kinds as an
+// intersection, so "-p net-* -l env=prod" selects the prod networking projects.
+type ProjectSelectors struct {
+	All      bool
+	Patterns []string
+	Labels   []string
+}
+
+func (s ProjectSelectors) IsEmpty() bool {
+	return !s.All && len(s.Patterns) == 0 && len(s.Labels) == 0
+}
+
+// ParseProjectSelectors reads -p/--project, -l/--label and --all flags from a comment.
+// Flag values may be repeated or comma separated.
+func ParseProjectSelectors(comment string) (ProjectSelectors, error) {
+	selectors := ProjectSelectors{}
+	tokens := strings.Fields(comment)
+	for i := 0; i < len(tokens); i++ {
+		flag, value, hasValue := strings.Cut(tokens[i], "=")
+		switch flag {
+		case "--all":
+			if hasValue {
+				return selectors, fmt.Errorf("--all does not take a value")
+			}
+			selectors.All = true
+		case "-p", "--project", "-l", "--label":
+			if !hasValue {
+				if i+1 >= len(tokens) || strings.HasPrefix(tokens[i+1], "-") {
+					return selectors, fmt.Errorf("no value found after %v flag", flag)
+				}
+				i++
+				value = tokens[i]
+			}
+			for _, v := range strings.Split(value, ",") {
+				v = strings.TrimSpace(v)
+				if v == "" {
+					continue
+				}
+				if flag == "-p" || flag == "--project" {
+					if _, err := path.Match(v, ""); err != nil {
+						return selectors, fmt.Errorf("invalid project pattern %q: %v", v, err)
+					}
+					selectors.Patterns = append(selectors.Patterns, v)
+				} else {
+					if key, _, _ := strings.Cut(v, "="); key == "" {
+						return selectors, fmt.Errorf("invalid label selector %q, expected key=value or key", v)
+					}
+					selectors.Labels = append(selectors.Labels, v)
+				}
+			}
+		}
+	}
+	return selectors, nil
+}
+
+func matchesLabel(project digger_config.Project, selector string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	actual, ok := project.Labels[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || actual == value
+}
+
+func matchesPattern(project digger_config.Project, pattern string) bool {
+	matched, _ := path.Match(pattern, project.Name)
+	return matched
+}
+
+// SelectProjects resolves selectors against the impacted projects, or against every
+// configured project when --all is given. Each selector must match at least one
+// project, otherwise an error describing the offending selector is returned.
+func SelectProjects(selectors ProjectSelectors, impactedProjects []digger_config.Project, allProjects []digger_config.Project) ([]digger_config.Project, error) {
+	candidates := impactedProjects
+	if selectors.All {
+		candidates = allProjects
+	}
+
+	selected := make(map[string]bool)
+	for _, p := range candidates {
+		selected[p.Name] = true
+	}
+
+	filter := func(kind string, values []string, matches func(digger_config.Project, string) bool) error {
+		if len(values) == 0 {
+			return nil
+		}
+		matchedAny := make(map[string]bool)
+		for _, value := range values {
+			found := false
+			for _, p := range candidates {
+				if matches(p, value) {
+					matchedAny[p.Name] = true
+					found = true
+				}
+			}
+			if found {
+				continue
+			}
+			for _, p := range allProjects {
+				if matches(p, value) {
+					return fmt.Errorf("%v %q matches project %v which is not impacted by this PR, use --all to select projects regardless of changes", kind, value, p.Name)
+				}
+			}
+			return fmt.Errorf("%v %q does not match any project in digger.yml", kind, value)
+		}
+		for name := range selected {
+			if !matchedAny[name] {
+				delete(selected, name)
+			}
+		}
+		return nil
+	}
+
+	if err := filter("project selector", selectors.Patterns, matchesPattern); err != nil {
+		return nil, err
+	}
+	if err := filter("label selector", selectors.Labels, matchesLabel); err != nil {
+		return nil, err
+	}
+
+	result := make([]digger_config.Project, 0)
+	for _, p := range candidates {
+		if selected[p.Name] {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		if len(candidates) == 0 && selectors.All {
+			return nil, fmt.Errorf("no projects are configured in digger.yml")
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no projects to select from, this PR does not impact any project")
+		}
+		return nil, fmt.Errorf("no project matches all of the given selectors")
+	}
+	return result, nil
+}
+
+// SelectRequestedProjects returns the projects selected by the -p/-l/--all selectors of a comment, nil if it has
+// no selectors and the command runs for every impacted project
+func SelectRequestedProjects(comment string, impactedProjects []digger_config.Project, allProjects []digger_config.Project) ([]digger_config.Project, error) {
+	selectors, err := ParseProjectSelectors(comment)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse project selectors: %v", err)
+	}
+	if selectors.IsEmpty() {
+		return nil, nil
+	}
+	return SelectProjects(selectors, impactedProjects, allProjects)
+}
+
+// ProjectsToRun returns the projects a command runs for, the requested projects if the comment selected some and
+// the impacted projects otherwise, and whether they include every impacted project
+func ProjectsToRun(impactedProjects []digger_config.Project, requestedProjects []digger_config.Project) ([]digger_config.Project, bool) {
+	if len(requestedProjects) == 0 {
+		return impactedProjects, true
+	}
+	requested := make(map[string]bool)
+	for _, p := range requestedProjects {
+		requested[p.Name] = true
+	}
+	for _, p := range impactedProjects {
+		if !requested[p.Name] {
+			return requestedProjects, false
+		}
+	}
+	return requestedProjects, true
+}
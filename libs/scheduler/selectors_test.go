@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/diggerhq/digger/libs/digger_config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProjectSelectors(t *testing.T) {
+	selectors, err := ParseProjectSelectors("digger plan")
+	assert.NoError(t, err)
+	assert.True(t, selectors.IsEmpty())
+
+	selectors, err = ParseProjectSelectors("digger plan -p prod-* -p shared,network --label env=prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod-*", "shared", "network"}, selectors.Patterns)
+	assert.Equal(t, []string{"env=prod"}, selectors.Labels)
+	assert.False(t, selectors.All)
+
+	selectors, err = ParseProjectSelectors("digger apply --all -l team")
+	assert.NoError(t, err)
+	assert.True(t, selectors.All)
+	assert.Equal(t, []string{"team"}, selectors.Labels)
+
+	_, err = ParseProjectSelectors("digger plan -p")
+	assert.Error(t, err)
+
+	_, err = ParseProjectSelectors("digger plan -p prod-[")
+	assert.Error(t, err)
+}
+
+func TestSelectProjects(t *testing.T) {
+	all := []digger_config.Project{
+		{Name: "prod-network", Labels: map[string]string{"env": "prod", "team": "network"}},
+		{Name: "prod-app", Labels: map[string]string{"env": "prod"}},
+		{Name: "dev-app", Labels: map[string]string{"env": "dev"}},
+	}
+	impacted := all[:2]
+
+	selected, err := SelectProjects(ProjectSelectors{Patterns: []string{"prod-*"}}, impacted, all)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 2)
+
+	selected, err = SelectProjects(ProjectSelectors{Patterns: []string{"prod-*"}, Labels: []string{"team=network"}}, impacted, all)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-network", selected[0].Name)
+	assert.Len(t, selected, 1)
+
+	_, err = SelectProjects(ProjectSelectors{Patterns: []string{"dev-app"}}, impacted, all)
+	assert.ErrorContains(t, err, "not impacted by this PR")
+
+	_, err = SelectProjects(ProjectSelectors{Patterns: []string{"staging-*"}}, impacted, all)
+	assert.ErrorContains(t, err, "does not match any project")
+
+	selected, err = SelectProjects(ProjectSelectors{All: true, Labels: []string{"env=dev"}}, impacted, all)
+	assert.NoError(t, err)
+	assert.Equal(t, "dev-app", selected[0].Name)
+
+	selected, err = SelectProjects(ProjectSelectors{All: true}, nil, all)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 3)
+}
+
+func TestProjectsToRunCoverage(t *testing.T) {
+	all := []digger_config.Project{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	impacted := all[:2]
+
+	requested, err := SelectRequestedProjects("digger apply", impacted, all)
+	assert.NoError(t, err)
+	assert.Nil(t, requested)
+	runFor, coversAll := ProjectsToRun(impacted, requested)
+	assert.Len(t, runFor, 2)
+	assert.True(t, coversAll)
+
+	_, err = SelectRequestedProjects("digger apply -p a,c", impacted, all)
+	assert.ErrorContains(t, err, "not impacted by this PR")
+
+	// a subset of the impacted projects doesn't cover them all
+	requested, err = SelectRequestedProjects("digger apply -p a -p b", all, all)
+	assert.NoError(t, err)
+	runFor, coversAll = ProjectsToRun(all, requested)
+	assert.Len(t, runFor, 2)
+	assert.False(t, coversAll)
+
+	requested, err = SelectRequestedProjects("digger apply --all", impacted, all)
+	assert.NoError(t, err)
+	runFor, coversAll = ProjectsToRun(impacted, requested)
+	assert.Len(t, runFor, 3)
+	assert.True(t, coversAll)
+}
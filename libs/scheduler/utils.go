@@ -22,6 +22,7 @@ const DiggerCommandPlan DiggerCommand = "plan"
 const DiggerCommandApply DiggerCommand = "apply"
 const DiggerCommandLock DiggerCommand = "lock"
 const DiggerCommandUnlock DiggerCommand = "unlock"
+const DiggerCommandCancel DiggerCommand = "cancel"
 
 func GetCommandFromComment(comment string) (*DiggerCommand, error) {
 	supportedCommands := map[string]DiggerCommand{
@@ -30,6 +31,7 @@ func GetCommandFromComment(comment string) (*DiggerCommand, error) {
 		"digger apply":  DiggerCommandApply,
 		"digger unlock": DiggerCommandUnlock,
 		"digger lock":   DiggerCommandLock,
+		"digger cancel": DiggerCommandCancel,
 	}
 	diggerCommand := strings.ToLower(comment)
 	diggerCommand = strings.TrimSpace(diggerCommand)
@@ -65,3 +67,25 @@ func GetCommandFromJob(job Job) (*DiggerCommand, error) {
 	}
 	return nil, fmt.Errorf("could not figure out command: %v", job.Commands)
 }
+
+// GroupByEnvironment groups the indexes of jobs by their project environment, keeping the order in which
+// environments first appear. Jobs without an environment are grouped under the empty string.
+func GroupByEnvironment(environments []string) ([]string, map[string][]int) {
+	order := make([]string, 0)
+	groups := make(map[string][]int)
+	for i, environment := range environments {
+		if _, ok := groups[environment]; !ok {
+			order = append(order, environment)
+		}
+		groups[environment] = append(groups[environment], i)
+	}
+	return order, groups
+}
+
+// EnvironmentHeading is the heading used for a group of jobs in PR comments
+func EnvironmentHeading(environment string) string {
+	if environment == "" {
+		return "**No environment**\n\n"
+	}
+	return fmt.Sprintf("**Environment: %v**\n\n", environment)
+}
@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// AwsSecretsManagerProvider reads secrets from AWS Secrets Manager using the default credential chain,
+// the path is the secret ARN or name: aws-sm://arn:aws:secretsmanager:us-east-1:123456789012:secret:ci#password
+type AwsSecretsManagerProvider struct{}
+
+func (p AwsSecretsManagerProvider) GetSecret(ref Reference) (string, error) {
+	ctx := context.Background()
+	var options []func(*config.LoadOptions) error
+	// secrets referenced by ARN are read from the region of the ARN
+	if parsed, err := arn.Parse(ref.Path); err == nil {
+		options = append(options, config.WithRegion(parsed.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return "", fmt.Errorf("could not load aws config: %v", err)
+	}
+
+	output, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(ref.Path),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret %v has no string value", ref.Path)
+	}
+	return extractKey(*output.SecretString, ref.Key)
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2/google"
+)
+
+const gcpSecretManagerHost = "https://secretmanager.googleapis.com/v1/"
+
+// GcpSecretManagerProvider reads secrets from Google Secret Manager using application default credentials,
+// the path is the secret version name: gcp-sm://projects/my-project/secrets/ci/versions/latest#password.
+// The version can be omitted in which case the latest one is used
+type GcpSecretManagerProvider struct{}
+
+func (p GcpSecretManagerProvider) GetSecret(ref Reference) (string, error) {
+	name := ref.Path
+	if !strings.Contains(name, "/versions/") {
+		name = name + "/versions/latest"
+	}
+
+	ctx := context.Background()
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", fmt.Errorf("could not get google credentials: %v", err)
+	}
+
+	resp, err := client.Get(gcpSecretManagerHost + name + ":access")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %v while accessing secret %v", resp.StatusCode, name)
+	}
+
+	var accessResponse struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	err = json.Unmarshal(body, &accessResponse)
+	if err != nil {
+		return "", fmt.Errorf("could not parse secret manager response: %v", err)
+	}
+	secret, err := base64.StdEncoding.DecodeString(accessResponse.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("could not decode secret payload: %v", err)
+	}
+	return extractKey(string(secret), ref.Key)
+}
@@ -0,0 +1,50 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// LocalProvider is a stand-in for real secret stores, secrets are looked up by path (and path#key)
+// in a map. It is meant for tests and local runs and can be registered for any scheme
+type LocalProvider struct {
+	Secrets map[string]string
+}
+
+func NewLocalProvider(secrets map[string]string) LocalProvider {
+	return LocalProvider{Secrets: secrets}
+}
+
+// LocalProviderFromEnv loads the local:// secrets from the JSON file in DIGGER_LOCAL_SECRETS_FILE if set
+func LocalProviderFromEnv() LocalProvider {
+	provider := NewLocalProvider(map[string]string{})
+	path := os.Getenv("DIGGER_LOCAL_SECRETS_FILE")
+	if path == "" {
+		return provider
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("could not read local secrets file %v: %v", path, err)
+		return provider
+	}
+	err = json.Unmarshal(contents, &provider.Secrets)
+	if err != nil {
+		log.Printf("could not parse local secrets file %v: %v", path, err)
+	}
+	return provider
+}
+
+func (p LocalProvider) GetSecret(ref Reference) (string, error) {
+	if ref.Key != "" {
+		if secret, ok := p.Secrets[ref.Path+"#"+ref.Key]; ok {
+			return secret, nil
+		}
+	}
+	secret, ok := p.Secrets[ref.Path]
+	if !ok {
+		return "", fmt.Errorf("secret %v not found", ref.Path)
+	}
+	return extractKey(secret, ref.Key)
+}
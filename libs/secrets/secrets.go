@@ -0,0 +1,124 @@
+// Package secrets resolves secret references used as env var sources in digger.yml, for example
+// vault://secret/data/ci#token, aws-sm://arn:aws:secretsmanager:...#password,
+// gcp-sm://projects/p/secrets/s/versions/latest or sops://secrets/prod.enc.yaml#db_password
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/diggerhq/digger/libs/redact"
+)
+
+const (
+	VaultScheme             = "vault"
+	AwsSecretsManagerScheme = "aws-sm"
+	GcpSecretManagerScheme  = "gcp-sm"
+	SopsScheme              = "sops"
+	LocalScheme             = "local"
+)
+
+// Reference points to a secret in a secret store, Key selects a single field of structured secrets
+type Reference struct {
+	Scheme string
+	Path   string
+	Key    string
+}
+
+func (r Reference) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Key != "" {
+		s = s + "#" + r.Key
+	}
+	return s
+}
+
+type Provider interface {
+	GetSecret(ref Reference) (string, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{
+		VaultScheme:             VaultProvider{},
+		AwsSecretsManagerScheme: AwsSecretsManagerProvider{},
+		GcpSecretManagerScheme:  GcpSecretManagerProvider{},
+		SopsScheme:              SopsProvider{},
+		LocalScheme:             LocalProviderFromEnv(),
+	}
+)
+
+// RegisterProvider replaces the provider for a scheme, tests use it to swap real stores for a LocalProvider
+func RegisterProvider(scheme string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = provider
+}
+
+func getProvider(scheme string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[scheme]
+	return provider, ok
+}
+
+// ParseReference parses a secret reference, ok is false if the value does not use a known scheme
+// (for example a plain env var name)
+func ParseReference(value string) (Reference, bool) {
+	scheme, rest, found := strings.Cut(value, "://")
+	if !found {
+		return Reference{}, false
+	}
+	if _, ok := getProvider(scheme); !ok {
+		return Reference{}, false
+	}
+	path, key, _ := strings.Cut(rest, "#")
+	return Reference{Scheme: scheme, Path: path, Key: key}, true
+}
+
+// IsReference returns true if the value is a secret reference rather than an env var name
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+// Resolve fetches the secret behind a reference and registers it for redaction
+func Resolve(value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return "", fmt.Errorf("%v is not a valid secret reference", value)
+	}
+	provider, _ := getProvider(ref.Scheme)
+	secret, err := provider.GetSecret(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve secret %v: %v", ref, err)
+	}
+	redact.Register(secret)
+	return secret, nil
+}
+
+// extractKey returns a field of a JSON object secret, or the whole secret if no key is requested
+func extractKey(secret string, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var fields map[string]interface{}
+	err := json.Unmarshal([]byte(secret), &fields)
+	if err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select key %v", key)
+	}
+	return fieldValue(fields, key)
+}
+
+func fieldValue(fields map[string]interface{}, key string) (string, error) {
+	value, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key %v not found in secret", key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", value), nil
+}
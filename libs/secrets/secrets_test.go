@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diggerhq/digger/libs/redact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, ok := ParseReference("vault://secret/data/ci#token")
+	assert.True(t, ok)
+	assert.Equal(t, Reference{Scheme: VaultScheme, Path: "secret/data/ci", Key: "token"}, ref)
+
+	ref, ok = ParseReference("aws-sm://arn:aws:secretsmanager:us-east-1:123456789012:secret:ci")
+	assert.True(t, ok)
+	assert.Equal(t, "arn:aws:secretsmanager:us-east-1:123456789012:secret:ci", ref.Path)
+	assert.Equal(t, "", ref.Key)
+
+	_, ok = ParseReference("AWS_SECRET_ACCESS_KEY")
+	assert.False(t, ok)
+	_, ok = ParseReference("https://example.com")
+	assert.False(t, ok)
+}
+
+func TestResolveWithLocalProvider(t *testing.T) {
+	RegisterProvider(VaultScheme, NewLocalProvider(map[string]string{
+		"secret/data/ci":     `{"token": "vault-token-value", "port": 5432}`,
+		"secret/data/plain":  "plain-secret-value",
+		"secret/data/ci#raw": "raw-key-value",
+	}))
+	defer RegisterProvider(VaultScheme, VaultProvider{})
+
+	value, err := Resolve("vault://secret/data/ci#token")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-token-value", value)
+
+	value, err = Resolve("vault://secret/data/ci#port")
+	assert.NoError(t, err)
+	assert.Equal(t, "5432", value)
+
+	value, err = Resolve("vault://secret/data/plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain-secret-value", value)
+
+	value, err = Resolve("vault://secret/data/ci#raw")
+	assert.NoError(t, err)
+	assert.Equal(t, "raw-key-value", value)
+
+	_, err = Resolve("vault://secret/data/missing")
+	assert.Error(t, err)
+
+	// resolved values are marked as secret
+	assert.Equal(t, "token=<REDACTED>", redact.String("token=vault-token-value"))
+}
+
+func TestVaultSecretValue(t *testing.T) {
+	kv2 := map[string]interface{}{
+		"data":     map[string]interface{}{"password": "hunter22"},
+		"metadata": map[string]interface{}{"version": 3},
+	}
+	value, err := vaultSecretValue(kv2, "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter22", value)
+
+	value, err = vaultSecretValue(kv2, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter22", value)
+
+	kv1 := map[string]interface{}{"user": "admin", "password": "hunter22"}
+	value, err = vaultSecretValue(kv1, "user")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", value)
+}
+
+func TestSopsProvider(t *testing.T) {
+	// stand-in for the sops binary that prints its arguments
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "sops")
+	err := os.WriteFile(binary, []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	assert.NoError(t, err)
+
+	value, err := SopsProvider{Binary: binary}.GetSecret(Reference{Scheme: SopsScheme, Path: "secrets/prod.enc.yaml", Key: "database.password"})
+	assert.NoError(t, err)
+	assert.Equal(t, `--decrypt --extract ["database"]["password"] secrets/prod.enc.yaml`, value)
+}
@@ -0,0 +1,47 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SopsProvider decrypts SOPS encrypted files checked into the repository with the sops binary,
+// the key selects a (dot separated) path in the file: sops://secrets/prod.enc.yaml#database.password
+type SopsProvider struct {
+	// Binary defaults to sops from PATH
+	Binary string
+}
+
+func (p SopsProvider) GetSecret(ref Reference) (string, error) {
+	binary := p.Binary
+	if binary == "" {
+		binary = "sops"
+	}
+
+	args := []string{"--decrypt"}
+	if ref.Key != "" {
+		args = append(args, "--extract", sopsExtractPath(ref.Key))
+	}
+	args = append(args, ref.Path)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("sops could not decrypt %v: %v %v", ref.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+// sopsExtractPath converts database.password into the ["database"]["password"] syntax expected by sops
+func sopsExtractPath(key string) string {
+	var path strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		path.WriteString(fmt.Sprintf("[%q]", part))
+	}
+	return path.String()
+}
@@ -0,0 +1,51 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+
+	vault "github.com/hashicorp/vault/api"
+)
+
+// VaultProvider reads secrets from HashiCorp Vault, the client is configured from the standard
+// VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE env vars. Both KV v1 and v2 paths are supported:
+// vault://secret/data/ci#token
+type VaultProvider struct{}
+
+func (p VaultProvider) GetSecret(ref Reference) (string, error) {
+	client, err := vault.NewClient(vault.DefaultConfig())
+	if err != nil {
+		return "", fmt.Errorf("could not create vault client: %v", err)
+	}
+	secret, err := client.Logical().Read(ref.Path)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", fmt.Errorf("secret %v not found", ref.Path)
+	}
+	return vaultSecretValue(secret.Data, ref.Key)
+}
+
+func vaultSecretValue(data map[string]interface{}, key string) (string, error) {
+	// KV v2 nests the fields under data next to the version metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = nested
+		}
+	}
+
+	if key != "" {
+		return fieldValue(data, key)
+	}
+	if len(data) == 1 {
+		for k := range data {
+			return fieldValue(data, k)
+		}
+	}
+	contents, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
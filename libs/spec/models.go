@@ -24,6 +24,8 @@ type BackendSpec struct {
 	BackendHostname         string `json:"backend_hostname"`
 	BackendOrganisationName string `json:"backend_organisation_hostname"`
 	BackendJobToken         string `json:"backend_job_token"`
+	// trace context of the running job, set by the cli to propagate it to the backend
+	TraceContext map[string]string `json:"-"`
 }
 
 type VcsSpec struct {
@@ -66,4 +68,6 @@ type Spec struct {
 	VCS            VcsSpec            `json:"vcs"`
 	Policy         PolicySpec         `json:"policy_provider"`
 	Variables      []VariableSpec     `json:"variables"`
+	// trace context of the backend span that triggered the job, so that the spans of the cli join its trace
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
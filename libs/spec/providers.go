@@ -162,7 +162,7 @@ func (b BackendApiProvider) GetBackendApi(backendSpec BackendSpec) (backend2.Api
 	case "noop":
 		return backend2.NoopApi{}, nil
 	case "backend":
-		return backend2.NewBackendApi(backendSpec.BackendHostname, backendSpec.BackendJobToken), nil
+		return backend2.NewTracedBackendApi(backendSpec.BackendHostname, backendSpec.BackendJobToken, backendSpec.TraceContext), nil
 	default:
 		return backend2.NoopApi{}, nil
 	}
@@ -232,6 +232,14 @@ type SpecPolicyProvider interface {
 type BasicPolicyProvider struct{}
 
 func (p BasicPolicyProvider) GetPolicyProvider(policySpec PolicySpec, diggerHost string, diggerOrg string, token string) (policy2.Checker, error) {
+	if policyDir := policy2.PolicyDirFromEnv(); policyDir != "" {
+		return policy2.DiggerPolicyChecker{
+			PolicyProvider: policy2.DirPolicyProvider{
+				PolicyDir:          policyDir,
+				DiggerOrganisation: diggerOrg,
+			},
+		}, nil
+	}
 	switch policySpec.PolicyType {
 	case "http":
 		return policy2.DiggerPolicyChecker{
@@ -240,6 +248,8 @@ func (p BasicPolicyProvider) GetPolicyProvider(policySpec PolicySpec, diggerHost
 				DiggerOrganisation: diggerOrg,
 				AuthToken:          token,
 				HttpClient:         http.DefaultClient,
+				PolicyVersions:     policy2.PolicyVersionsFromEnv(),
+				Cache:              policy2.DefaultPolicyCache,
 			},
 		}, nil
 	default:
@@ -3,6 +3,8 @@ package spec
 import (
 	"fmt"
 	digger_crypto "github.com/diggerhq/digger/libs/crypto"
+	"github.com/diggerhq/digger/libs/redact"
+	digger_secrets "github.com/diggerhq/digger/libs/secrets"
 	"github.com/samber/lo"
 	"os"
 )
@@ -27,6 +29,15 @@ func (p VariablesProvider) GetVariables(variables []VariableSpec) (map[string]st
 				return nil, fmt.Errorf("could not decrypt value using private key: %v", err)
 			}
 			res[v.Name] = string(value)
+			// make sure the decrypted value never ends up in comments or the backend
+			redact.Register(string(value))
+		} else if v.IsInterpolated && digger_secrets.IsReference(v.Value) {
+			// secret references (vault://, aws-sm://, gcp-sm://, sops://) are resolved just in time in the runner
+			value, err := digger_secrets.Resolve(v.Value)
+			if err != nil {
+				return nil, err
+			}
+			res[v.Name] = value
 		} else if v.IsInterpolated {
 			// if it is an interpolated value we get it form env variable of the variable
 			res[v.Name] = os.Getenv(v.Value)
@@ -0,0 +1,39 @@
+package terraform_utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type terraformOutput struct {
+	Sensitive bool            `json:"sensitive"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// ParseTerraformOutputJson returns the values of `terraform output -json` by output name. Strings are
+// returned as is, other values as JSON, which is also how terraform parses them from TF_VAR_ env vars.
+// Sensitive outputs are left out.
+func ParseTerraformOutputJson(outputJson string) (map[string]string, error) {
+	var outputs map[string]terraformOutput
+	if err := json.Unmarshal([]byte(outputJson), &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform outputs: %v", err)
+	}
+	values := make(map[string]string)
+	for name, output := range outputs {
+		if output.Sensitive {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(output.Value, &s); err == nil {
+			values[name] = s
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, output.Value); err != nil {
+			return nil, fmt.Errorf("failed to parse terraform output %v: %v", name, err)
+		}
+		values[name] = compact.String()
+	}
+	return values, nil
+}
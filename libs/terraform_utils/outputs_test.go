@@ -0,0 +1,24 @@
+package terraform_utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTerraformOutputJson(t *testing.T) {
+	outputJson := `{
+  "vpc_id": {"sensitive": false, "type": "string", "value": "vpc-123"},
+  "subnet_ids": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-1", "subnet-2"]},
+  "password": {"sensitive": true, "type": "string", "value": "secret"}
+}`
+	outputs, err := ParseTerraformOutputJson(outputJson)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"vpc_id":     "vpc-123",
+		"subnet_ids": `["subnet-1","subnet-2"]`,
+	}, outputs)
+
+	_, err = ParseTerraformOutputJson("not json")
+	assert.Error(t, err)
+}
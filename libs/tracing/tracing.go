@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/diggerhq/digger"
+
+// shutdown flushes the spans of the tracer provider set up by Init, it does nothing when tracing is disabled
+var shutdown = func(ctx context.Context) error { return nil }
+
+func init() {
+	// the trace context is propagated even when spans are not exported, so that a process exporting them
+	// can join the trace of its caller
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Enabled returns true if an OTLP endpoint is configured through the standard OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init exports the spans of the process to the configured OTLP endpoint over HTTP. Without an endpoint tracing
+// is a no-op. The exporter, sampler and resource are configured by the standard OTEL_* environment variables,
+// OTEL_SERVICE_NAME overrides serviceName.
+func Init(ctx context.Context, serviceName string) error {
+	if !Enabled() {
+		return nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	shutdown = provider.Shutdown
+	return nil
+}
+
+// Shutdown exports the spans not exported yet, it has to be called before the process exits
+func Shutdown(ctx context.Context) error {
+	return shutdown(ctx)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start starts a span named name as a child of the span of ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// End ends span, recording err when it isn't nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the trace context of ctx, to be passed to another process. It is empty when ctx has no span.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract returns ctx with the trace context of carrier, as returned by Inject, so that spans started from it
+// join the trace
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// InjectHeaders sets the headers propagating the trace context, as returned by Inject, on an outgoing request
+func InjectHeaders(carrier map[string]string, header http.Header) {
+	for key, value := range carrier {
+		header.Set(key, value)
+	}
+}
@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestInjectExtract(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	ctx, span := provider.Tracer("test").Start(context.Background(), "webhook")
+	defer span.End()
+
+	carrier := Inject(ctx)
+	assert.Contains(t, carrier, "traceparent")
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), carrier))
+	assert.True(t, extracted.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), extracted.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), extracted.SpanID())
+
+	header := http.Header{}
+	InjectHeaders(carrier, header)
+	assert.Equal(t, carrier["traceparent"], header.Get("traceparent"))
+}
+
+func TestInjectWithoutSpan(t *testing.T) {
+	carrier := Inject(context.Background())
+	assert.Empty(t, carrier)
+	assert.Equal(t, context.Background(), Extract(context.Background(), carrier))
+}
@@ -29,7 +29,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"reflect"
 	"strconv"
 	"strings"
@@ -541,13 +540,18 @@ func getDiggerConfigForBranch(gh next_utils.GithubClientProvider, installationId
 		return "", nil, nil, nil, fmt.Errorf("error getting changed files")
 	}
 	err = backend_utils.CloneGitRepoAndDoAction(cloneUrl, branch, *token, func(dir string) error {
-		diggerYmlBytes, err := os.ReadFile(path.Join(dir, "digger.yml"))
-		diggerYmlStr = string(diggerYmlBytes)
+		var err error
 		config, _, dependencyGraph, err = dg_configuration.LoadDiggerConfig(dir, true, changedFiles)
 		if err != nil {
 			log.Printf("Error loading digger config: %v", err)
 			return err
 		}
+		// the stored config is loaded later without the repository, so includes and project files are merged in
+		diggerYmlStr, err = dg_configuration.LoadMergedDiggerConfigString(dir)
+		if err != nil {
+			log.Printf("Error merging digger config: %v", err)
+			return err
+		}
 		return nil
 	})
 	if err != nil {